@@ -2,6 +2,7 @@
 package arc
 
 import (
+	"context"
 	"time"
 
 	"github.com/shaj13/libcache"
@@ -14,68 +15,300 @@ func init() {
 }
 
 // New returns a new non-thread safe cache.
+//
+// The sublists events relayed into the arc emitter, so the arc emitter
+// is the only handlers registry, and guarded by the same lock as the arc operations.
 func New(cap int) libcache.Cache {
-	return &arc{
-		p:  0,
-		t1: lru.New(cap).(*internal.Cache),
-		b1: lru.New(cap).(*internal.Cache),
-		t2: lru.New(cap).(*internal.Cache),
-		b2: lru.New(cap).(*internal.Cache),
+	a := &arc{
+		p:    0,
+		subs: make(map[<-chan libcache.Event]func()),
+		t1:   internal.Unwrap(lru.New(cap)),
+		b1:   internal.Unwrap(lru.New(cap)),
+		t2:   internal.Unwrap(lru.New(cap)),
+		b2:   internal.Unwrap(lru.New(cap)),
 	}
+
+	a.t1.Relay(a.relay)
+	a.t2.Relay(a.relay)
+	return a
 }
 
 type arc struct {
-	p  int
-	t1 *internal.Cache
-	t2 *internal.Cache
-	b1 *internal.Cache
-	b2 *internal.Cache
+	// counters kept first to be 64-bit aligned for atomic operations,
+	// and counts lookups only, as t1 and t2 count evictions and expirations.
+	counters internal.Counters
+	p        int
+	jitter   time.Duration
+	emitter  internal.Emitter
+	subs     map[<-chan libcache.Event]func()
+	closed   bool
+	// silent reports whether write events are suppressed.
+	silent bool
+	// maxCost is the entries total cost ceiling, zero means no ceiling.
+	maxCost int64
+	// maxKeyBytes is the entries total key bytes budget, zero means no budget.
+	maxKeyBytes int64
+	// paused reports whether capacity and cost eviction deferred.
+	paused bool
+	// purgeRemoves reports whether purge relays t1 and t2 Remove events,
+	// instead of firing a single Purge event.
+	purgeRemoves bool
+	// keyFunc normalizes the keys of LoadMany result.
+	keyFunc libcache.KeyFunc
+	// admit reports whether new keys stored, nil means all of them,
+	// it is not set on the sublists, as moves between them are not stores.
+	admit libcache.AdmissionFunc
+	// recording reports whether the first t1 and t2 eviction recorded into evicted.
+	recording bool
+	evicted   *libcache.Event
+	t1        *internal.Cache
+	t2        *internal.Cache
+	b1        *internal.Cache
+	b2        *internal.Cache
+}
+
+// relay surfaces t1 and t2 evictions and expirations as arc events.
+// Other sublists events are either internal moves between the sublists,
+// or emitted by arc itself once per logical operation.
+func (a *arc) relay(e libcache.Event) {
+	if e.Op == libcache.Remove && a.recording && a.evicted == nil {
+		a.evicted = &e
+	}
+
+	if e.Op == libcache.Remove || e.Op == libcache.Expire {
+		a.emitter.Emit(e)
+	}
+}
+
+func (a *arc) emit(op libcache.Op, key, val interface{}, ok bool) {
+	exp := a.expiryOf(key)
+	a.emitter.Emit(libcache.Event{
+		Op:     op,
+		Key:    key,
+		Value:  val,
+		Expiry: exp,
+		Cost:   a.costOf(key),
+		Ok:     ok,
+	})
+}
+
+// expiryOf returns the key value expiry from t1 or t2, without running their GC.
+func (a *arc) expiryOf(key interface{}) time.Time {
+	if exp, ok := a.t1.ExpiryOf(key); ok {
+		return exp
+	}
+	exp, _ := a.t2.ExpiryOf(key)
+	return exp
+}
+
+// costOf returns the key value cost from t1 or t2.
+func (a *arc) costOf(key interface{}) int64 {
+	if c, ok := a.t1.CostOf(key); ok {
+		return c
+	}
+	c, _ := a.t2.CostOf(key)
+	return c
+}
+
+func (a *arc) emitWrite(key, old interface{}, hadOld bool) {
+	if a.silent {
+		return
+	}
+
+	val, _ := a.peek(key)
+	exp := a.expiryOf(key)
+	a.emitter.Emit(libcache.Event{
+		Op:     libcache.Write,
+		Key:    key,
+		Value:  val,
+		Expiry: exp,
+		Old:    old,
+		HadOld: hadOld,
+		Cost:   a.costOf(key),
+	})
 }
 
 func (a *arc) Load(key interface{}) (value interface{}, ok bool) {
-	if val, ok := a.t1.Peek(key); ok {
-		exp, _ := a.t1.Expiry(key)
-		a.t1.DelSilently(key)
-		a.t2.StoreWithTTL(key, val, time.Until(exp))
-		return val, ok
+	value, ok = a.load(key)
+	a.counters.Lookup(ok)
+	a.emit(libcache.Read, key, value, ok)
+	return value, ok
+}
+
+func (a *arc) LoadWithExpiry(key interface{}) (interface{}, time.Time, bool) {
+	value, ok := a.Load(key)
+	if !ok {
+		return nil, time.Time{}, false
+	}
+
+	exp := a.expiryOf(key)
+	return value, exp, true
+}
+
+func (a *arc) load(key interface{}) (value interface{}, ok bool) {
+	// A single t1 lookup, so the t1 inline GC sweeps at most one batch.
+	if info, ok := a.t1.GetEntry(key); ok {
+		if internal.IsNegative(info.Value) {
+			return nil, false
+		}
+
+		// Promotion is a read, the entry keeps its absolute expiry and age.
+		a.promote(key, info.Value, info.Expiry)
+		a.t2.SetCreated(key, info.Created)
+		a.t2.SetDelta(key, info.Delta)
+		return info.Value, ok
 	}
 
 	return a.t2.Load(key)
 }
 
+// promote moves the key from t1 to t2, with its pin and tags.
+//
+// promote must run as a single step relative to other arc operations,
+// so a key is never observed in both or neither of t1 and t2,
+// and Keys, Len and Contains agree on it.
+// The thread safe cache guarantees that by holding its write lock
+// for the whole arc operation, Load included, as a load may promote.
+// The key holds a t1 slot, so t2 has room for it and the move never evicts.
+func (a *arc) promote(key, val interface{}, exp time.Time) {
+	pinned := a.t1.Unpin(key)
+	tags := a.t1.Tags(key)
+	a.t1.DelSilently(key)
+	a.t2.StoreWithDeadline(key, val, exp)
+	a.t2.Tag(key, tags...)
+	if pinned {
+		a.t2.Pin(key)
+	}
+}
+
+func (a *arc) Test(key interface{}, pred func(value interface{}) bool) (bool, bool) {
+	v, ok := a.Load(key)
+	if !ok {
+		return false, ok
+	}
+	return pred(v), ok
+}
+
 func (a *arc) Store(key, val interface{}) {
 	a.StoreWithTTL(key, val, a.TTL())
 }
 
 func (a *arc) StoreWithTTL(key, val interface{}, ttl time.Duration) {
+	a.StoreWithTTLJitter(key, val, ttl, a.jitter)
+}
+
+// admits reports whether the key value stored, as the key exists or admitted.
+func (a *arc) admits(key, val interface{}) bool {
+	return a.admit == nil || a.list(key) != nil || internal.Admits(a.admit, key, val)
+}
+
+func (a *arc) StoreWithTTLJitter(key, val interface{}, ttl, jitter time.Duration) {
+	if !a.admits(key, val) {
+		return
+	}
+
+	old, hadOld := a.peek(key)
+	a.store(key, val, ttl, jitter, nil)
+
+	a.evict(key)
+	a.emitWrite(key, old, hadOld)
+}
+
+func (a *arc) StoreEvicting(key, val interface{}) (interface{}, interface{}, bool) {
+	return a.StoreWithTTLEvicting(key, val, a.TTL())
+}
+
+// StoreWithTTLEvicting records the first t1 and t2 removal while storing,
+// as the sublists remove entries during a store only to evict them.
+func (a *arc) StoreWithTTLEvicting(key, val interface{}, ttl time.Duration) (interface{}, interface{}, bool) {
+	a.recording, a.evicted = true, nil
+	a.StoreWithTTL(key, val, ttl)
+	e := a.evicted
+	a.recording, a.evicted = false, nil
+
+	if e == nil {
+		return nil, nil, false
+	}
+	return e.Key, e.Value, true
+}
+
+func (a *arc) StoreWithDeadline(key, val interface{}, deadline time.Time) {
+	if !a.admits(key, val) {
+		return
+	}
+
+	old, hadOld := a.peek(key)
+	a.store(key, val, 0, 0, nil)
+
+	// store decides which sublist holds the key,
+	// then the key value stored again with the deadline in place.
+	if l := a.list(key); l != nil {
+		l.StoreWithDeadline(key, val, deadline)
+	}
+
+	a.evict(key)
+	a.emitWrite(key, old, hadOld)
+}
+
+// StoreWithDeadlineEvicting records the first t1 and t2 removal while storing,
+// like StoreWithTTLEvicting.
+func (a *arc) StoreWithDeadlineEvicting(key, val interface{}, deadline time.Time) (interface{}, interface{}, bool) {
+	a.recording, a.evicted = true, nil
+	a.StoreWithDeadline(key, val, deadline)
+	e := a.evicted
+	a.recording, a.evicted = false, nil
+
+	if e == nil {
+		return nil, nil, false
+	}
+	return e.Key, e.Value, true
+}
+
+func (a *arc) StoreWithTags(key, val interface{}, tags ...string) {
+	if !a.admits(key, val) {
+		return
+	}
+
+	old, hadOld := a.peek(key)
+	a.store(key, val, a.TTL(), a.jitter, tags)
+
+	a.evict(key)
+	a.emitWrite(key, old, hadOld)
+}
+
+func (a *arc) InvalidateTag(tag string) int {
+	return a.t1.InvalidateTag(tag) + a.t2.InvalidateTag(tag)
+}
+
+// store sets the key value and replaces its tags.
+func (a *arc) store(key, val interface{}, ttl, jitter time.Duration, tags []string) {
 	defer func() {
-		if a.Cap() != 0 && a.t1.Len()+a.t2.Len() > a.Cap() {
-			a.replace(key)
+		if t := a.list(key); t != nil {
+			t.Tag(key, tags...)
 		}
 	}()
 
-	if a.t1.Contains(key) {
-		a.t1.DelSilently(key)
-		a.t2.StoreWithTTL(key, val, ttl)
+	if a.t1.Has(key) {
+		a.promote(key, val, a.t2.ExpiresAt(ttl, jitter))
 		return
 	}
 
-	if a.t2.Contains(key) {
-		a.t2.StoreWithTTL(key, val, ttl)
+	if a.t2.Has(key) {
+		a.t2.StoreWithTTLJitter(key, val, ttl, jitter)
 		return
 	}
 
 	if a.b1.Contains(key) {
 		a.p = min(a.Cap(), a.p+max(a.b2.Len()/a.b1.Len(), 1))
 		a.b1.Delete(key)
-		a.t2.StoreWithTTL(key, val, ttl)
+		a.t2.StoreWithTTLJitter(key, val, ttl, jitter)
 		return
 	}
 
 	if a.b2.Contains(key) {
 		a.p = max(0, a.p-max(a.b1.Len()/a.b2.Len(), 1))
 		a.b2.Delete(key)
-		a.t2.StoreWithTTL(key, val, ttl)
+		a.t2.StoreWithTTLJitter(key, val, ttl, jitter)
 		return
 	}
 
@@ -87,18 +320,92 @@ func (a *arc) StoreWithTTL(key, val interface{}, ttl time.Duration) {
 		a.b2.Discard()
 	}
 
-	a.t1.StoreWithTTL(key, val, ttl)
+	a.t1.StoreWithTTLJitter(key, val, ttl, jitter)
+}
+
+func (a *arc) GetOrCompute(key interface{}, loader libcache.Loader) (interface{}, error) {
+	if v, ok := a.Load(key); ok {
+		return v, nil
+	}
+
+	if e, ok := a.GetEntry(key); ok && internal.IsNegative(e.Value) {
+		return nil, libcache.ErrNotFound
+	}
+
+	v, err := loader(key)
+	return internal.StoreLoaded(a, key, v, err)
+}
+
+func (a *arc) Merge(other libcache.EntrySource, onConflict func(existing, incoming interface{}) interface{}) {
+	internal.MergeEntries(a, internal.LiveEntries(other), onConflict)
+}
+
+func (a *arc) LoadCtx(ctx context.Context, key interface{}, loader func(context.Context) (interface{}, error)) (interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return a.GetOrCompute(key, internal.ContextLoader(ctx, loader))
+}
+
+func (a *arc) StoreNegative(key interface{}, ttl time.Duration) {
+	a.StoreWithTTL(key, libcache.Negative{}, ttl)
+}
+
+func (a *arc) StoreMany(items map[interface{}]interface{}) {
+	for k, v := range items {
+		a.Store(k, v)
+	}
+}
+
+func (a *arc) Warm(items map[interface{}]interface{}) {
+	a.WarmWithTTL(items, a.TTL())
+}
+
+func (a *arc) WarmWithTTL(items map[interface{}]interface{}, ttl time.Duration) {
+	a.silent = true
+	defer func() {
+		a.silent = false
+	}()
+
+	for k, v := range items {
+		a.StoreWithTTL(k, v, ttl)
+	}
+}
+
+func (a *arc) LoadMany(keys ...interface{}) map[interface{}]interface{} {
+	items := make(map[interface{}]interface{})
+	for _, k := range keys {
+		if v, ok := a.Load(k); ok {
+			items[a.keyFunc.Key(k)] = v
+		}
+	}
+	return items
 }
 
 func (a *arc) replace(key interface{}) {
+	// Protect the stored key from being replaced by itself,
+	// when all other entries are pinned.
+	if !a.t1.Pinned(key) && !a.t2.Pinned(key) && a.Pin(key) {
+		defer a.Unpin(key)
+	}
+
 	if (a.t1.Len() > 0 && a.b2.Contains(key) && a.t1.Len() == a.p) || (a.t1.Len() > a.p) {
-		k, _ := a.t1.Discard()
-		a.b1.Store(k, nil)
+		_ = discard(a.t1, a.b1) || discard(a.t2, a.b2)
 		return
 	}
 
-	k, _ := a.t2.Discard()
-	a.b2.Store(k, nil)
+	_ = discard(a.t2, a.b2) || discard(a.t1, a.b1)
+}
+
+// discard evicts the oldest entry from t and records its key in the ghost list b.
+// discard reports whether an entry evicted, as pinned entries are never evicted.
+func discard(t, b *internal.Cache) bool {
+	n := t.Len()
+	if k, _ := t.Discard(); t.Len() < n {
+		b.Store(k, nil)
+		return true
+	}
+	return false
 }
 
 func (a *arc) Delete(key interface{}) {
@@ -108,38 +415,365 @@ func (a *arc) Delete(key interface{}) {
 	a.b2.Delete(key)
 }
 
+func (a *arc) GetAndDelete(key interface{}) (interface{}, bool) {
+	if v, ok := a.t1.GetAndDelete(key); ok {
+		return v, ok
+	}
+	return a.t2.GetAndDelete(key)
+}
+
+func (a *arc) DeleteMany(keys ...interface{}) {
+	for _, k := range keys {
+		a.Delete(k)
+	}
+}
+
+// Update updates the key value in place, in the list that holds it,
+// keys known only by the ghost lists b1 and b2 have no value to update.
 func (a *arc) Update(key, value interface{}) {
-	if a.t1.Contains(key) {
-		a.t1.Update(key, value)
+	t := a.list(key)
+	if t == nil {
+		return
+	}
+
+	// Negative entries treated as absent.
+	old, ok := t.Peek(key)
+	if !ok {
+		return
+	}
+
+	t.Update(key, value)
+	a.emitWrite(key, old, true)
+}
+
+// list returns the list that holds the key value, t1 or t2,
+// or nil if none of them holds it.
+func (a *arc) list(key interface{}) *internal.Cache {
+	if a.t1.Has(key) {
+		return a.t1
+	}
+
+	if a.t2.Has(key) {
+		return a.t2
 	}
-	a.t2.Update(key, value)
+
+	return nil
+}
+
+// Frequency returns the key access frequency as tracked by the t1 and t2 membership,
+// 1 for keys held by t1, accessed once, and 2 for keys held by t2, accessed at least twice,
+// as ARC does not count the accesses beyond that.
+func (a *arc) Frequency(key interface{}) (int, bool) {
+	l := a.list(key)
+	if l == nil {
+		return 0, false
+	}
+	return a.frequency(l), true
+}
+
+// frequency returns the access frequency of the keys held by the given list.
+func (a *arc) frequency(l *internal.Cache) int {
+	if l == a.t2 {
+		return 2
+	}
+	return 1
+}
+
+func (a *arc) CompareAndSwap(key, old, new interface{}) (ok bool) {
+	if a.t1.Has(key) {
+		ok = a.t1.CompareAndSwap(key, old, new)
+	} else {
+		ok = a.t2.CompareAndSwap(key, old, new)
+	}
+
+	if ok {
+		a.emitWrite(key, old, true)
+	}
+
+	return ok
+}
+
+func (a *arc) CompareAndDelete(key, old interface{}) bool {
+	if a.t1.Has(key) {
+		return a.t1.CompareAndDelete(key, old)
+	}
+	return a.t2.CompareAndDelete(key, old)
+}
+
+func (a *arc) Increment(key interface{}, delta int64) (int64, error) {
+	t := a.t2
+	if a.t1.Has(key) {
+		t = a.t1
+	}
+
+	old, ok := t.Peek(key)
+	if !ok {
+		a.Store(key, delta)
+		if !a.t1.Has(key) && !a.t2.Has(key) {
+			return 0, libcache.ErrNotStored
+		}
+		return delta, nil
+	}
+
+	n, err := t.Increment(key, delta)
+	if err == nil {
+		a.emitWrite(key, old, true)
+	}
+
+	return n, err
+}
+
+func (a *arc) Decrement(key interface{}, delta int64) (int64, error) {
+	return a.Increment(key, -delta)
 }
 
 func (a *arc) Peek(key interface{}) (value interface{}, ok bool) {
+	value, ok = a.peek(key)
+	a.counters.Lookup(ok)
+	a.emit(libcache.Read, key, value, ok)
+	return value, ok
+}
+
+func (a *arc) peek(key interface{}) (value interface{}, ok bool) {
 	if val, ok := a.t1.Peek(key); ok {
 		return val, ok
 	}
 	return a.t2.Peek(key)
 }
 
+func (a *arc) GetEntry(key interface{}) (info libcache.EntryInfo, ok bool) {
+	if l := a.list(key); l != nil {
+		info, ok = l.GetEntry(key)
+		info.Frequency = a.frequency(l)
+	}
+	a.emit(libcache.Read, key, info.Value, ok && !internal.IsNegative(info.Value))
+	return info, ok
+}
+
 func (a *arc) Expiry(key interface{}) (time.Time, bool) {
-	if a.t1.Contains(key) {
+	if a.t1.Has(key) {
 		return a.t1.Expiry(key)
 	}
 	return a.t2.Expiry(key)
 }
 
+func (a *arc) SetExpiry(key interface{}, t time.Time) (ok bool) {
+	if l := a.list(key); l != nil {
+		ok = l.SetExpiry(key, t)
+	}
+
+	if ok {
+		val, _ := a.peek(key)
+		a.emitWrite(key, val, true)
+	}
+
+	return ok
+}
+
+func (a *arc) RefreshAllTTL(ttl time.Duration) {
+	a.t1.RefreshAllTTL(ttl)
+	a.t2.RefreshAllTTL(ttl)
+	a.emitWrites()
+}
+
+func (a *arc) ClearTTL() {
+	a.t1.ClearTTL()
+	a.t2.ClearTTL()
+	a.emitWrites()
+}
+
+// emitWrites emits a Write event per non-negative entry.
+func (a *arc) emitWrites() {
+	for _, k := range a.Keys() {
+		if v, ok := a.peek(k); ok && !internal.IsNegative(v) {
+			a.emitWrite(k, v, true)
+		}
+	}
+}
+
+func (a *arc) Touch(key interface{}, ttl time.Duration) (ok bool) {
+	if a.t1.Has(key) {
+		ok = a.t1.Touch(key, ttl)
+	} else {
+		ok = a.t2.Touch(key, ttl)
+	}
+
+	if ok {
+		val, _ := a.peek(key)
+		a.emitWrite(key, val, true)
+	}
+
+	return ok
+}
+
+func (a *arc) RemainingTTL(key interface{}) (time.Duration, bool) {
+	if a.t1.Has(key) {
+		return a.t1.RemainingTTL(key)
+	}
+	return a.t2.RemainingTTL(key)
+}
+
 func (a *arc) Purge() {
 	a.t1.Purge()
 	a.t2.Purge()
 	a.b1.Purge()
 	a.b2.Purge()
+
+	if !a.purgeRemoves {
+		a.emitter.Emit(libcache.Event{Op: libcache.Purge})
+	}
+}
+
+func (a *arc) Reset() {
+	a.p = 0
+	a.jitter = 0
+	a.maxCost = 0
+	a.maxKeyBytes = 0
+	a.paused = false
+	a.purgeRemoves = false
+	a.keyFunc = nil
+	a.admit = nil
+	a.emitter.SetKeyFunc(nil)
+	a.unsubscribe()
+	a.emitter.Clear()
+	a.counters.Reset()
+	a.t1.Reset()
+	a.t2.Reset()
+	a.b1.Reset()
+	a.b2.Reset()
 }
 
+// Resize replaces entries until t1 and t2 together fit the new size,
+// then trims the ghost lists, which the replacements fed.
 func (a *arc) Resize(size int) int {
+	if size < 0 {
+		size = 0
+	}
+
+	n := a.Len()
+	for size > 0 && a.Len() > size {
+		l := a.Len()
+		a.replace(nil)
+		// All other entries are pinned.
+		if a.Len() == l {
+			break
+		}
+	}
+
+	a.p = min(a.p, size)
+	a.t1.Resize(size)
+	a.t2.Resize(size)
 	a.b1.Resize(size)
 	a.b2.Resize(size)
-	return a.t1.Resize(size) + a.t2.Resize(size)
+	return n - a.Len()
+}
+
+func (a *arc) ResizeCost(maxCost int64) int64 {
+	a.maxCost = maxCost
+	cost := a.Cost()
+	if maxCost > 0 && cost > maxCost {
+		a.evictOversized(maxCost, 0)
+	}
+	a.fitCost(nil)
+	return cost - a.Cost()
+}
+
+// evictOversized evicts the entries whose cost or key bytes alone exceed the limit,
+// ahead of the replacement, as they never fit, and records their keys in the ghost lists.
+func (a *arc) evictOversized(maxCost, maxKeyBytes int64) {
+	for _, k := range a.t1.EvictOversized(maxCost, maxKeyBytes) {
+		a.b1.Store(k, nil)
+	}
+	for _, k := range a.t2.EvictOversized(maxCost, maxKeyBytes) {
+		a.b2.Store(k, nil)
+	}
+}
+
+// evict replaces entries until the cache fits its capacity, cost ceiling and key bytes budget,
+// unless eviction paused. The given key is never replaced.
+func (a *arc) evict(key interface{}) {
+	if a.paused {
+		return
+	}
+
+	if a.Cap() != 0 && a.t1.Len()+a.t2.Len() > a.Cap() {
+		a.replace(key)
+	}
+
+	a.fitCost(key)
+	a.fitKeyBytes(key)
+}
+
+func (a *arc) PauseEviction() {
+	a.paused = true
+	a.t1.PauseEviction()
+	a.t2.PauseEviction()
+}
+
+func (a *arc) ResumeEviction() {
+	a.paused = false
+
+	for a.Cap() != 0 && a.Len() > a.Cap() {
+		n := a.Len()
+		a.replace(nil)
+		// All other entries are pinned.
+		if a.Len() == n {
+			break
+		}
+	}
+
+	if a.maxCost > 0 && a.Cost() > a.maxCost {
+		a.evictOversized(a.maxCost, 0)
+	}
+	a.fitCost(nil)
+	if a.maxKeyBytes > 0 && a.t1.KeyBytes()+a.t2.KeyBytes() > a.maxKeyBytes {
+		a.evictOversized(0, a.maxKeyBytes)
+	}
+	a.fitKeyBytes(nil)
+	a.t1.ResumeEviction()
+	a.t2.ResumeEviction()
+}
+
+// fitCost replaces entries until the total cost fits the cost ceiling,
+// and returns the cost evicted, following the internal cache fitCost rule,
+// the oversized entries evicted beforehand by the callers.
+// The given key is never replaced.
+func (a *arc) fitCost(key interface{}) int64 {
+	cost := a.Cost()
+	for a.maxCost > 0 && a.Cost() > a.maxCost {
+		n := a.Len()
+		a.replace(key)
+		// All other entries are pinned.
+		if a.Len() == n {
+			break
+		}
+	}
+	return cost - a.Cost()
+}
+
+func (a *arc) SetMaxKeyBytes(n int64) {
+	if n < 0 {
+		n = 0
+	}
+
+	a.maxKeyBytes = n
+	if n > 0 && a.t1.KeyBytes()+a.t2.KeyBytes() > n {
+		a.evictOversized(0, n)
+	}
+	a.fitKeyBytes(nil)
+}
+
+// fitKeyBytes replaces entries until the total key bytes fits the key bytes budget,
+// by the fitCost rule. The given key is never replaced.
+func (a *arc) fitKeyBytes(key interface{}) {
+	for a.maxKeyBytes > 0 && a.t1.KeyBytes()+a.t2.KeyBytes() > a.maxKeyBytes {
+		n := a.Len()
+		a.replace(key)
+		// All other entries are pinned.
+		if a.Len() == n {
+			break
+		}
+	}
 }
 
 func (a *arc) SetTTL(ttl time.Duration) {
@@ -147,6 +781,66 @@ func (a *arc) SetTTL(ttl time.Duration) {
 	a.t2.SetTTL(ttl)
 }
 
+func (a *arc) SetUpdateRefreshesTTL(refresh bool) {
+	a.t1.SetUpdateRefreshesTTL(refresh)
+	a.t2.SetUpdateRefreshesTTL(refresh)
+}
+
+func (a *arc) SetClock(clock libcache.Clock) {
+	a.t1.SetClock(clock)
+	a.t2.SetClock(clock)
+	a.b1.SetClock(clock)
+	a.b2.SetClock(clock)
+}
+
+func (a *arc) SetMaxIdle(d time.Duration) {
+	a.t1.SetMaxIdle(d)
+	a.t2.SetMaxIdle(d)
+}
+
+// SetDelta sets the duration of the loader call that computed the key value.
+func (a *arc) SetDelta(key interface{}, d time.Duration) bool {
+	return a.t1.SetDelta(key, d) || a.t2.SetDelta(key, d)
+}
+
+// Delta returns the duration of the loader call that computed the key value,
+// and the key expiry.
+func (a *arc) Delta(key interface{}) (time.Duration, time.Time, bool) {
+	if d, exp, ok := a.t1.Delta(key); ok {
+		return d, exp, ok
+	}
+	return a.t2.Delta(key)
+}
+
+func (a *arc) SetBloomFilter(expectedN int, fpRate float64) {
+	a.t1.SetBloomFilter(expectedN, fpRate)
+	a.t2.SetBloomFilter(expectedN, fpRate)
+	a.b1.SetBloomFilter(expectedN, fpRate)
+	a.b2.SetBloomFilter(expectedN, fpRate)
+}
+
+func (a *arc) SetGCBatchSize(n int) {
+	a.t1.SetGCBatchSize(n)
+	a.t2.SetGCBatchSize(n)
+}
+
+func (a *arc) SetPurgeRemoveEvents(enabled bool) {
+	a.purgeRemoves = enabled
+	a.t1.SetPurgeRemoveEvents(enabled)
+	a.t2.SetPurgeRemoveEvents(enabled)
+}
+
+func (a *arc) SetDeterministic(enabled bool) {
+	a.t1.SetDeterministic(enabled)
+	a.t2.SetDeterministic(enabled)
+	a.b1.SetDeterministic(enabled)
+	a.b2.SetDeterministic(enabled)
+}
+
+func (a *arc) SetJitter(jitter time.Duration) {
+	a.jitter = jitter
+}
+
 func (a *arc) TTL() time.Duration {
 	// Both T1 and T2 LRU have the same ttl.
 	return a.t1.TTL()
@@ -156,17 +850,163 @@ func (a *arc) Len() int {
 	return a.t1.Len() + a.t2.Len()
 }
 
+func (a *arc) KeysWithPrefix(prefix string) []interface{} {
+	return append(a.t1.KeysWithPrefix(prefix), a.t2.KeysWithPrefix(prefix)...)
+}
+
+func (a *arc) DeleteWithPrefix(prefix string) int {
+	return a.t1.DeleteWithPrefix(prefix) + a.t2.DeleteWithPrefix(prefix)
+}
+
+func (a *arc) DeleteFunc(pred func(key, value interface{}) bool) int {
+	return a.t1.DeleteFunc(pred) + a.t2.DeleteFunc(pred)
+}
+
+func (a *arc) Snapshot() map[interface{}]interface{} {
+	m := a.t1.Snapshot()
+	for k, v := range a.t2.Snapshot() {
+		m[k] = v
+	}
+	return m
+}
+
+func (a *arc) Iterator() *libcache.Iterator {
+	return internal.Chain(a.t1.Iterator(), a.t2.Iterator())
+}
+
 func (a *arc) Keys() []interface{} {
 	return append(a.t1.Keys(), a.t2.Keys()...)
 }
 
+func (a *arc) KeysByExpiry() []interface{} {
+	return internal.SortByExpiry(append(a.t1.ExpiryPairs(), a.t2.ExpiryPairs()...))
+}
+
+func (a *arc) ExpiringWithin(d time.Duration) []interface{} {
+	return internal.SortByExpiry(append(a.t1.ExpiryPairsWithin(d), a.t2.ExpiryPairsWithin(d)...))
+}
+
+// lists returns t1 and t2 ordered by which replace discards from first.
+func (a *arc) lists() (*internal.Cache, *internal.Cache) {
+	if a.t1.Len() > a.p {
+		return a.t1, a.t2
+	}
+	return a.t2, a.t1
+}
+
+func (a *arc) PeekOldest() (key, value interface{}, ok bool) {
+	x, y := a.lists()
+	if key, value, ok = x.PeekOldest(); ok {
+		return
+	}
+	return y.PeekOldest()
+}
+
+func (a *arc) PeekNewest() (key, value interface{}, ok bool) {
+	x, y := a.lists()
+	if key, value, ok = y.PeekNewest(); ok {
+		return
+	}
+	return x.PeekNewest()
+}
+
+// OrderedKeys returns the keys of the list replace discards from first,
+// followed by the keys of the other list.
+func (a *arc) OrderedKeys() []interface{} {
+	x, y := a.lists()
+	return append(x.OrderedKeys(), y.OrderedKeys()...)
+}
+
+func (a *arc) ColdestN(n int) []libcache.EntryInfo {
+	x, y := a.lists()
+	infos := x.ColdestN(n)
+	return append(infos, y.ColdestN(n-len(infos))...)
+}
+
+func (a *arc) HottestN(n int) []libcache.EntryInfo {
+	x, y := a.lists()
+	infos := y.HottestN(n)
+	return append(infos, x.HottestN(n-len(infos))...)
+}
+
+func (a *arc) Metrics() libcache.Metrics {
+	m := a.counters.Metrics()
+	m1, m2 := a.t1.Metrics(), a.t2.Metrics()
+	m.Evictions = m1.Evictions + m2.Evictions
+	m.Expirations = m1.Expirations + m2.Expirations
+	m.RecentEvictions = m1.RecentEvictions + m2.RecentEvictions
+	m.LastGCEvicted = m1.LastGCEvicted + m2.LastGCEvicted
+	m.LastGCDuration = m1.LastGCDuration + m2.LastGCDuration
+	m.Len = a.Len()
+	m.Cap = a.Cap()
+	m.Cost = a.Cost()
+	return m
+}
+
+func (a *arc) Utilization() float64 {
+	if a.maxCost > 0 {
+		return float64(a.Cost()) / float64(a.maxCost)
+	}
+	if a.Cap() == 0 {
+		return 0
+	}
+	return float64(a.Len()) / float64(a.Cap())
+}
+
+func (a *arc) Cost() int64 {
+	return a.t1.Cost() + a.t2.Cost()
+}
+
+func (a *arc) SetWeigher(w libcache.Weigher) {
+	a.t1.SetWeigher(w)
+	a.t2.SetWeigher(w)
+}
+
+func (a *arc) SetAdmissionFunc(fn libcache.AdmissionFunc) {
+	a.admit = fn
+}
+
+func (a *arc) SetKeyFunc(fn libcache.KeyFunc) {
+	a.keyFunc = fn
+	a.emitter.SetKeyFunc(fn)
+	a.t1.SetKeyFunc(fn)
+	a.t2.SetKeyFunc(fn)
+	a.b1.SetKeyFunc(fn)
+	a.b2.SetKeyFunc(fn)
+}
+
+func (a *arc) Policy() libcache.ReplacementPolicy {
+	return libcache.ARC
+}
+
 func (a *arc) Cap() int {
 	// ALL sub LRU have the same capacity.
 	return a.t1.Cap()
 }
 
+func (a *arc) Pin(key interface{}) bool {
+	return a.t1.Pin(key) || a.t2.Pin(key)
+}
+
+func (a *arc) Unpin(key interface{}) bool {
+	return a.t1.Unpin(key) || a.t2.Unpin(key)
+}
+
 func (a *arc) Contains(key interface{}) bool {
-	return a.t1.Contains(key) || a.t2.Contains(key)
+	info, ok := a.GetEntry(key)
+	return ok && !internal.IsNegative(info.Value)
+}
+
+func (a *arc) ContainsNoGC(key interface{}) bool {
+	return a.t1.ContainsNoGC(key) || a.t2.ContainsNoGC(key)
+}
+
+func (a *arc) ContainsMany(keys []interface{}) []bool {
+	flags := make([]bool, len(keys))
+	for i, k := range keys {
+		flags[i] = a.Contains(k)
+	}
+	return flags
 }
 
 func (a *arc) RegisterOnEvicted(f func(key, value interface{})) {
@@ -180,13 +1020,73 @@ func (a *arc) RegisterOnExpired(f func(key, value interface{})) {
 }
 
 func (a *arc) Notify(ch chan<- libcache.Event, ops ...libcache.Op) {
-	a.t1.Notify(ch, ops...)
-	a.t2.Notify(ch, ops...)
+	a.emitter.Notify(ch, ops...)
+}
+
+func (a *arc) NotifyFunc(fn func(libcache.Event), ops ...libcache.Op) {
+	a.emitter.NotifyFunc(fn, ops...)
+}
+
+func (a *arc) NotifyBlocking(ch chan<- libcache.Event, ops ...libcache.Op) {
+	a.emitter.NotifyBlocking(ch, ops...)
+}
+
+func (a *arc) NotifyWithReplay(ch chan<- libcache.Event, ops ...libcache.Op) {
+	a.t1.Replay(ch, ops...)
+	a.t2.Replay(ch, ops...)
+	a.emitter.Notify(ch, ops...)
+}
+
+func (a *arc) Subscribe(ops ...libcache.Op) (<-chan libcache.Event, func()) {
+	if a.closed {
+		return a.t1.Subscribe(ops...)
+	}
+
+	ch, cancel := internal.Subscribe(a, ops...)
+	a.subs[ch] = cancel
+
+	return ch, func() {
+		delete(a.subs, ch)
+		cancel()
+	}
+}
+
+func (a *arc) Watch(key interface{}) (<-chan libcache.Event, func()) {
+	if a.closed {
+		return a.t1.Watch(key)
+	}
+
+	ch, cancel := a.emitter.Watch(key)
+	a.subs[ch] = cancel
+
+	return ch, func() {
+		delete(a.subs, ch)
+		cancel()
+	}
+}
+
+func (a *arc) Close() error {
+	a.unsubscribe()
+	a.emitter.Clear()
+	a.t1.Close()
+	a.t2.Close()
+	a.b1.Close()
+	a.b2.Close()
+	a.closed = true
+	return nil
+}
+
+// unsubscribe closes the channels allocated by Subscribe and Watch.
+func (a *arc) unsubscribe() {
+	for _, cancel := range a.subs {
+		cancel()
+	}
+
+	a.subs = make(map[<-chan libcache.Event]func())
 }
 
 func (a *arc) Ignore(ch chan<- libcache.Event, ops ...libcache.Op) {
-	a.t1.Ignore(ch, ops...)
-	a.t2.Ignore(ch, ops...)
+	a.emitter.Ignore(ch, ops...)
 }
 
 func (a *arc) GC() time.Duration {
@@ -1,9 +1,14 @@
 package arc
 
 import (
+	"math/rand"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/shaj13/libcache"
 )
 
 func TestARCc(t *testing.T) {
@@ -58,3 +63,165 @@ func TestARCc(t *testing.T) {
 
 	a.Delete(1)
 }
+
+func TestARCLoadExpired(t *testing.T) {
+	a := New(2).(*arc)
+
+	a.StoreWithTTL(1, 1, time.Millisecond)
+	assert.Equal(t, 1, a.t1.Len())
+
+	time.Sleep(time.Millisecond * 2)
+
+	v, ok := a.Load(1)
+	assert.Nil(t, v)
+	assert.False(t, ok)
+	assert.Equal(t, 0, a.t1.Len())
+	assert.Equal(t, 0, a.t2.Len())
+}
+
+func TestARCKeysConsistency(t *testing.T) {
+	const size = 50
+
+	wg := sync.WaitGroup{}
+	cache := libcache.ARC.New(size * 2)
+
+	for i := 0; i < size; i++ {
+		cache.Store(i, i)
+	}
+
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				cache.Load(rand.Intn(size))
+			}
+		}()
+	}
+
+	for i := 0; i < 100; i++ {
+		keys := cache.Keys()
+		seen := make(map[interface{}]struct{})
+		for _, k := range keys {
+			seen[k] = struct{}{}
+		}
+
+		assert.Len(t, keys, size)
+		assert.Len(t, seen, size)
+		assert.Equal(t, size, cache.Len())
+		assert.Len(t, cache.Snapshot(), size)
+
+		k := rand.Intn(size)
+		assert.True(t, cache.Contains(k))
+		assert.True(t, cache.ContainsNoGC(k))
+	}
+
+	wg.Wait()
+}
+
+func TestARCConcurrentSubscriber(t *testing.T) {
+	const size = 50
+
+	wg := sync.WaitGroup{}
+	cache := libcache.ARC.New(size)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			ch, cancel := cache.Subscribe()
+			select {
+			case <-ch:
+			case <-time.After(time.Millisecond):
+			}
+			cancel()
+		}
+	}()
+
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				k := rand.Intn(size * 2)
+				cache.Store(k, k)
+				cache.Load(k)
+			}
+		}()
+	}
+
+	wg.Wait()
+	<-done
+	assert.Equal(t, size, cache.Len())
+}
+
+func TestARCNotifyPromotion(t *testing.T) {
+	c := make(chan libcache.Event, 10)
+	a := New(2).(*arc)
+	a.Notify(c)
+
+	a.Store(1, 1)
+	a.Load(1)
+	a.Store(1, 2)
+	close(c)
+
+	ops := []libcache.Op{}
+	for e := range c {
+		assert.Equal(t, 1, e.Key)
+		ops = append(ops, e.Op)
+	}
+
+	assert.Equal(t, []libcache.Op{libcache.Write, libcache.Read, libcache.Write}, ops)
+	assert.Equal(t, 1, a.t2.Len())
+}
+
+func TestARCUpdate(t *testing.T) {
+	a := New(2).(*arc)
+
+	a.Store(1, 1)
+	a.Load(1)
+	a.Store(2, 2)
+	a.Update(1, 10)
+	a.Update(2, 20)
+
+	v, ok := a.Peek(1)
+	assert.True(t, ok)
+	assert.Equal(t, 10, v)
+	assert.True(t, a.t2.Contains(1))
+
+	v, _ = a.Peek(2)
+	assert.Equal(t, 20, v)
+	assert.True(t, a.t1.Contains(2))
+
+	// ghost keys have no value to update.
+	a.Store(3, 3)
+	a.Store(4, 4)
+	assert.True(t, a.b1.Contains(2))
+
+	a.Update(2, 200)
+	assert.False(t, a.Contains(2))
+	assert.Equal(t, 2, a.Len())
+}
+
+func TestARCFrequency(t *testing.T) {
+	a := New(0).(*arc)
+	a.Store(1, 1)
+	a.Store(2, 2)
+	a.Load(2)
+	a.Load(2)
+
+	n, ok := a.Frequency(1)
+	assert.True(t, ok)
+	assert.Equal(t, 1, n)
+
+	n, ok = a.Frequency(2)
+	assert.True(t, ok)
+	assert.Equal(t, 2, n)
+
+	info, _ := a.GetEntry(2)
+	assert.Equal(t, 2, info.Frequency)
+
+	n, ok = a.Frequency(3)
+	assert.False(t, ok)
+	assert.Equal(t, 0, n)
+}
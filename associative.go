@@ -0,0 +1,691 @@
+package libcache
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/shaj13/libcache/internal"
+)
+
+// NewAssociative returns a new thread safe N-way set-associative cache,
+// composed of sets LRU caches holding up to ways entries each,
+// the keys routed to the sets by their hash.
+//
+// Once a set full, storing a new key routed to it evicts the set least recently used entry,
+// even if other sets have room, so the cache total capacity is sets*ways,
+// and each set entries index preallocated for ways entries.
+//
+// Keys, Len, Purge and the other scans aggregate the sets, so Purge fires a Purge event per set,
+// OrderedKeys, ColdestN and HottestN order the entries by their last access.
+// Resize, ResizeCost, SetMaxKeyBytes and SetBloomFilter spread their size evenly across the sets.
+//
+// Sets less than 1 clamped to 1, and zero ways means unbounded sets.
+// NewAssociative panics if the LRU cache replacement policy function is not linked into the binary.
+func NewAssociative(sets, ways int) Cache {
+	cache := new(cache)
+	cache.mu = sync.RWMutex{}
+	cache.done = make(chan struct{})
+	cache.unsafe = newAssociative(sets, ways)
+	cache.policy = LRU
+	cache.renew = func() Cache {
+		return NewAssociative(sets, ways)
+	}
+	return cache
+}
+
+func newAssociative(sets, ways int) *associative {
+	if sets < 1 {
+		sets = 1
+	}
+
+	a := &associative{
+		sets: make([]Cache, sets),
+		subs: make(map[<-chan Event]func()),
+	}
+
+	for i := range a.sets {
+		a.sets[i] = LRU.NewUnsafe(ways)
+		if c := internal.Unwrap(a.sets[i]); c != nil {
+			c.Reserve(ways)
+		}
+	}
+
+	return a
+}
+
+// associative is a non-thread safe N-way set-associative cache.
+type associative struct {
+	sets []Cache
+	// keyFunc normalizes the keys before hashing them.
+	keyFunc KeyFunc
+	// subs holds the channels allocated by Subscribe and their cancel funcs.
+	subs   map[<-chan Event]func()
+	closed bool
+}
+
+// set returns the set the key routed to,
+// hashing the normalized key like the map equality, so equal keys share a set.
+func (a *associative) set(key interface{}) Cache {
+	return a.sets[internal.Hash(a.keyFunc.Key(key))%uint64(len(a.sets))]
+}
+
+// spread returns n divided evenly across the sets rounded up,
+// non-positive n returned as is.
+func (a *associative) spread(n int) int {
+	if n <= 0 {
+		return n
+	}
+	return (n + len(a.sets) - 1) / len(a.sets)
+}
+
+// byAccess returns up to n of the sets entries ordered by last access,
+// the least recently used first unless reverse.
+func (a *associative) byAccess(n int, reverse bool) []EntryInfo {
+	if n <= 0 {
+		return nil
+	}
+
+	infos := []EntryInfo{}
+	for _, s := range a.sets {
+		if reverse {
+			infos = append(infos, s.HottestN(n)...)
+			continue
+		}
+		infos = append(infos, s.ColdestN(n)...)
+	}
+
+	sort.SliceStable(infos, func(i, j int) bool {
+		if reverse {
+			return infos[i].Accessed.After(infos[j].Accessed)
+		}
+		return infos[i].Accessed.Before(infos[j].Accessed)
+	})
+
+	if len(infos) > n {
+		infos = infos[:n]
+	}
+	return infos
+}
+
+func (a *associative) Load(key interface{}) (interface{}, bool) {
+	return a.set(key).Load(key)
+}
+
+func (a *associative) LoadWithExpiry(key interface{}) (interface{}, time.Time, bool) {
+	return a.set(key).LoadWithExpiry(key)
+}
+
+func (a *associative) Peek(key interface{}) (interface{}, bool) {
+	return a.set(key).Peek(key)
+}
+
+func (a *associative) Test(key interface{}, pred func(value interface{}) bool) (bool, bool) {
+	return a.set(key).Test(key, pred)
+}
+
+func (a *associative) Update(key interface{}, value interface{}) {
+	a.set(key).Update(key, value)
+}
+
+func (a *associative) CompareAndSwap(key, old, new interface{}) bool {
+	return a.set(key).CompareAndSwap(key, old, new)
+}
+
+func (a *associative) CompareAndDelete(key, old interface{}) bool {
+	return a.set(key).CompareAndDelete(key, old)
+}
+
+func (a *associative) Increment(key interface{}, delta int64) (int64, error) {
+	return a.set(key).Increment(key, delta)
+}
+
+func (a *associative) Decrement(key interface{}, delta int64) (int64, error) {
+	return a.set(key).Decrement(key, delta)
+}
+
+func (a *associative) Store(key interface{}, value interface{}) {
+	a.set(key).Store(key, value)
+}
+
+func (a *associative) StoreWithTTL(key interface{}, value interface{}, ttl time.Duration) {
+	a.set(key).StoreWithTTL(key, value, ttl)
+}
+
+func (a *associative) StoreEvicting(key, value interface{}) (interface{}, interface{}, bool) {
+	return a.set(key).StoreEvicting(key, value)
+}
+
+func (a *associative) StoreWithTTLEvicting(key, value interface{}, ttl time.Duration) (interface{}, interface{}, bool) {
+	return a.set(key).StoreWithTTLEvicting(key, value, ttl)
+}
+
+func (a *associative) StoreWithDeadline(key interface{}, value interface{}, deadline time.Time) {
+	a.set(key).StoreWithDeadline(key, value, deadline)
+}
+
+func (a *associative) StoreWithDeadlineEvicting(key, value interface{}, deadline time.Time) (interface{}, interface{}, bool) {
+	return a.set(key).StoreWithDeadlineEvicting(key, value, deadline)
+}
+
+func (a *associative) StoreWithTTLJitter(key interface{}, value interface{}, ttl, jitter time.Duration) {
+	a.set(key).StoreWithTTLJitter(key, value, ttl, jitter)
+}
+
+func (a *associative) StoreNegative(key interface{}, ttl time.Duration) {
+	a.set(key).StoreNegative(key, ttl)
+}
+
+func (a *associative) StoreWithTags(key, value interface{}, tags ...string) {
+	a.set(key).StoreWithTags(key, value, tags...)
+}
+
+func (a *associative) StoreMany(items map[interface{}]interface{}) {
+	for k, v := range items {
+		a.Store(k, v)
+	}
+}
+
+func (a *associative) Warm(items map[interface{}]interface{}) {
+	a.WarmWithTTL(items, a.TTL())
+}
+
+func (a *associative) WarmWithTTL(items map[interface{}]interface{}, ttl time.Duration) {
+	for k, v := range items {
+		a.set(k).WarmWithTTL(map[interface{}]interface{}{k: v}, ttl)
+	}
+}
+
+func (a *associative) LoadMany(keys ...interface{}) map[interface{}]interface{} {
+	items := make(map[interface{}]interface{})
+	for _, k := range keys {
+		for id, v := range a.set(k).LoadMany(k) {
+			items[id] = v
+		}
+	}
+	return items
+}
+
+func (a *associative) Delete(key interface{}) {
+	a.set(key).Delete(key)
+}
+
+func (a *associative) GetAndDelete(key interface{}) (interface{}, bool) {
+	return a.set(key).GetAndDelete(key)
+}
+
+func (a *associative) DeleteMany(keys ...interface{}) {
+	for _, k := range keys {
+		a.Delete(k)
+	}
+}
+
+func (a *associative) GetEntry(key interface{}) (EntryInfo, bool) {
+	return a.set(key).GetEntry(key)
+}
+
+func (a *associative) Expiry(key interface{}) (time.Time, bool) {
+	return a.set(key).Expiry(key)
+}
+
+func (a *associative) Touch(key interface{}, ttl time.Duration) bool {
+	return a.set(key).Touch(key, ttl)
+}
+
+func (a *associative) SetExpiry(key interface{}, exp time.Time) bool {
+	return a.set(key).SetExpiry(key, exp)
+}
+
+func (a *associative) RemainingTTL(key interface{}) (time.Duration, bool) {
+	return a.set(key).RemainingTTL(key)
+}
+
+func (a *associative) RefreshAllTTL(ttl time.Duration) {
+	for _, s := range a.sets {
+		s.RefreshAllTTL(ttl)
+	}
+}
+
+func (a *associative) ClearTTL() {
+	for _, s := range a.sets {
+		s.ClearTTL()
+	}
+}
+
+func (a *associative) Keys() []interface{} {
+	keys := []interface{}{}
+	for _, s := range a.sets {
+		keys = append(keys, s.Keys()...)
+	}
+	return keys
+}
+
+func (a *associative) KeysByExpiry() []interface{} {
+	return internal.SortByExpiry(a.ExpiryPairs())
+}
+
+func (a *associative) ExpiringWithin(d time.Duration) []interface{} {
+	return internal.SortByExpiry(a.ExpiryPairsWithin(d))
+}
+
+// ExpiryPairs returns all the keys paired with their expiry, in unspecified order.
+func (a *associative) ExpiryPairs() []internal.KeyExpiry {
+	pairs := []internal.KeyExpiry{}
+	for _, s := range a.sets {
+		pairs = append(pairs, expiryPairs(s)...)
+	}
+	return pairs
+}
+
+// ExpiryPairsWithin returns the keys expiring within d from now paired with their expiry,
+// in unspecified order.
+func (a *associative) ExpiryPairsWithin(d time.Duration) []internal.KeyExpiry {
+	pairs := []internal.KeyExpiry{}
+	for _, s := range a.sets {
+		pairs = append(pairs, expiryPairsWithin(s, d)...)
+	}
+	return pairs
+}
+
+func (a *associative) KeysWithPrefix(prefix string) []interface{} {
+	keys := []interface{}{}
+	for _, s := range a.sets {
+		keys = append(keys, s.KeysWithPrefix(prefix)...)
+	}
+	return keys
+}
+
+func (a *associative) DeleteWithPrefix(prefix string) (n int) {
+	for _, s := range a.sets {
+		n += s.DeleteWithPrefix(prefix)
+	}
+	return
+}
+
+func (a *associative) DeleteFunc(pred func(key, value interface{}) bool) (n int) {
+	for _, s := range a.sets {
+		n += s.DeleteFunc(pred)
+	}
+	return
+}
+
+func (a *associative) InvalidateTag(tag string) (n int) {
+	for _, s := range a.sets {
+		n += s.InvalidateTag(tag)
+	}
+	return
+}
+
+func (a *associative) GetOrCompute(key interface{}, loader Loader) (interface{}, error) {
+	return a.set(key).GetOrCompute(key, loader)
+}
+
+func (a *associative) LoadCtx(ctx context.Context, key interface{}, loader func(context.Context) (interface{}, error)) (interface{}, error) {
+	return a.set(key).LoadCtx(ctx, key, loader)
+}
+
+// Merge snapshots the other cache entries before merging them,
+// so the other cache may be the associative cache itself.
+func (a *associative) Merge(other EntrySource, onConflict func(existing, incoming interface{}) interface{}) {
+	internal.MergeEntries(a, internal.LiveEntries(other), onConflict)
+}
+
+func (a *associative) Snapshot() map[interface{}]interface{} {
+	m := make(map[interface{}]interface{})
+	for _, s := range a.sets {
+		for k, v := range s.Snapshot() {
+			m[k] = v
+		}
+	}
+	return m
+}
+
+func (a *associative) Iterator() *Iterator {
+	it := a.sets[0].Iterator()
+	for _, s := range a.sets[1:] {
+		it = internal.Chain(it, s.Iterator())
+	}
+	return it
+}
+
+func (a *associative) Pin(key interface{}) bool {
+	return a.set(key).Pin(key)
+}
+
+func (a *associative) Unpin(key interface{}) bool {
+	return a.set(key).Unpin(key)
+}
+
+func (a *associative) Contains(key interface{}) bool {
+	return a.set(key).Contains(key)
+}
+
+func (a *associative) ContainsNoGC(key interface{}) bool {
+	return a.set(key).ContainsNoGC(key)
+}
+
+func (a *associative) ContainsMany(keys []interface{}) []bool {
+	flags := make([]bool, len(keys))
+	for i, k := range keys {
+		flags[i] = a.Contains(k)
+	}
+	return flags
+}
+
+func (a *associative) Purge() {
+	for _, s := range a.sets {
+		s.Purge()
+	}
+}
+
+func (a *associative) Reset() {
+	a.unsubscribe()
+	for _, s := range a.sets {
+		s.Reset()
+	}
+	a.keyFunc = nil
+}
+
+func (a *associative) PauseEviction() {
+	for _, s := range a.sets {
+		s.PauseEviction()
+	}
+}
+
+func (a *associative) ResumeEviction() {
+	for _, s := range a.sets {
+		s.ResumeEviction()
+	}
+}
+
+// Resize sets each set capacity to size divided evenly across the sets rounded up.
+func (a *associative) Resize(size int) (n int) {
+	ways := a.spread(size)
+	for _, s := range a.sets {
+		n += s.Resize(ways)
+	}
+	return
+}
+
+// ResizeCost sets each set cost ceiling to maxCost divided evenly across the sets rounded up.
+func (a *associative) ResizeCost(maxCost int64) (n int64) {
+	if maxCost > 0 {
+		maxCost = (maxCost + int64(len(a.sets)) - 1) / int64(len(a.sets))
+	}
+
+	for _, s := range a.sets {
+		n += s.ResizeCost(maxCost)
+	}
+	return
+}
+
+// SetMaxKeyBytes sets each set key bytes budget to n divided evenly across the sets rounded up.
+func (a *associative) SetMaxKeyBytes(n int64) {
+	if n > 0 {
+		n = (n + int64(len(a.sets)) - 1) / int64(len(a.sets))
+	}
+
+	for _, s := range a.sets {
+		s.SetMaxKeyBytes(n)
+	}
+}
+
+func (a *associative) Len() (n int) {
+	for _, s := range a.sets {
+		n += s.Len()
+	}
+	return
+}
+
+func (a *associative) Cap() (n int) {
+	for _, s := range a.sets {
+		n += s.Cap()
+	}
+	return
+}
+
+// Policy returns LRU, the replacement policy of the sets.
+func (a *associative) Policy() ReplacementPolicy {
+	return LRU
+}
+
+func (a *associative) Cost() (n int64) {
+	for _, s := range a.sets {
+		n += s.Cost()
+	}
+	return
+}
+
+// PeekOldest returns the least recently used entry across the sets.
+func (a *associative) PeekOldest() (key, value interface{}, ok bool) {
+	if infos := a.byAccess(1, false); len(infos) > 0 {
+		return infos[0].Key, infos[0].Value, true
+	}
+	return
+}
+
+// PeekNewest returns the most recently used entry across the sets.
+func (a *associative) PeekNewest() (key, value interface{}, ok bool) {
+	if infos := a.byAccess(1, true); len(infos) > 0 {
+		return infos[0].Key, infos[0].Value, true
+	}
+	return
+}
+
+// OrderedKeys returns the keys ordered by last access, the least recently used first,
+// as each set evicts its least recently used entry.
+func (a *associative) OrderedKeys() []interface{} {
+	infos := a.byAccess(a.Len(), false)
+	keys := make([]interface{}, 0, len(infos))
+	for _, e := range infos {
+		keys = append(keys, e.Key)
+	}
+	return keys
+}
+
+func (a *associative) ColdestN(n int) []EntryInfo {
+	return a.byAccess(n, false)
+}
+
+func (a *associative) HottestN(n int) []EntryInfo {
+	return a.byAccess(n, true)
+}
+
+func (a *associative) Metrics() Metrics {
+	m := Metrics{}
+	for _, s := range a.sets {
+		sm := s.Metrics()
+		m.Hits += sm.Hits
+		m.Misses += sm.Misses
+		m.Evictions += sm.Evictions
+		m.Expirations += sm.Expirations
+		m.RecentEvictions += sm.RecentEvictions
+		m.LastGCEvicted += sm.LastGCEvicted
+		m.LastGCDuration += sm.LastGCDuration
+		m.Len += sm.Len
+		m.Cap += sm.Cap
+		m.Cost += sm.Cost
+	}
+	return m
+}
+
+// Utilization returns the sets average utilization.
+func (a *associative) Utilization() float64 {
+	u := float64(0)
+	for _, s := range a.sets {
+		u += s.Utilization()
+	}
+	return u / float64(len(a.sets))
+}
+
+func (a *associative) TTL() time.Duration {
+	return a.sets[0].TTL()
+}
+
+func (a *associative) SetTTL(ttl time.Duration) {
+	for _, s := range a.sets {
+		s.SetTTL(ttl)
+	}
+}
+
+func (a *associative) SetClock(clock Clock) {
+	for _, s := range a.sets {
+		s.SetClock(clock)
+	}
+}
+
+func (a *associative) SetJitter(jitter time.Duration) {
+	for _, s := range a.sets {
+		s.SetJitter(jitter)
+	}
+}
+
+func (a *associative) SetWeigher(w Weigher) {
+	for _, s := range a.sets {
+		s.SetWeigher(w)
+	}
+}
+
+func (a *associative) SetAdmissionFunc(fn AdmissionFunc) {
+	for _, s := range a.sets {
+		s.SetAdmissionFunc(fn)
+	}
+}
+
+// SetKeyFunc purges the cache entries, as the keys routed to the sets by their normalized form.
+func (a *associative) SetKeyFunc(fn KeyFunc) {
+	for _, s := range a.sets {
+		s.SetKeyFunc(fn)
+	}
+	a.keyFunc = fn
+}
+
+func (a *associative) SetMaxIdle(d time.Duration) {
+	for _, s := range a.sets {
+		s.SetMaxIdle(d)
+	}
+}
+
+// SetBloomFilter sizes each set filter for expectedN divided evenly across the sets rounded up.
+func (a *associative) SetBloomFilter(expectedN int, fpRate float64) {
+	expectedN = a.spread(expectedN)
+	for _, s := range a.sets {
+		s.SetBloomFilter(expectedN, fpRate)
+	}
+}
+
+func (a *associative) SetPurgeRemoveEvents(enabled bool) {
+	for _, s := range a.sets {
+		s.SetPurgeRemoveEvents(enabled)
+	}
+}
+
+func (a *associative) SetDeterministic(enabled bool) {
+	for _, s := range a.sets {
+		s.SetDeterministic(enabled)
+	}
+}
+
+func (a *associative) SetGCBatchSize(n int) {
+	for _, s := range a.sets {
+		s.SetGCBatchSize(n)
+	}
+}
+
+func (a *associative) SetUpdateRefreshesTTL(refresh bool) {
+	for _, s := range a.sets {
+		s.SetUpdateRefreshesTTL(refresh)
+	}
+}
+
+func (a *associative) RegisterOnEvicted(f func(key, value interface{})) {
+	for _, s := range a.sets {
+		s.RegisterOnEvicted(f)
+	}
+}
+
+func (a *associative) RegisterOnExpired(f func(key, value interface{})) {
+	for _, s := range a.sets {
+		s.RegisterOnExpired(f)
+	}
+}
+
+func (a *associative) Notify(ch chan<- Event, ops ...Op) {
+	for _, s := range a.sets {
+		s.Notify(ch, ops...)
+	}
+}
+
+func (a *associative) NotifyFunc(fn func(Event), ops ...Op) {
+	for _, s := range a.sets {
+		if n, ok := s.(funcNotifier); ok {
+			n.NotifyFunc(fn, ops...)
+		}
+	}
+}
+
+func (a *associative) NotifyBlocking(ch chan<- Event, ops ...Op) {
+	for _, s := range a.sets {
+		s.NotifyBlocking(ch, ops...)
+	}
+}
+
+func (a *associative) NotifyWithReplay(ch chan<- Event, ops ...Op) {
+	for _, s := range a.sets {
+		s.NotifyWithReplay(ch, ops...)
+	}
+}
+
+func (a *associative) Subscribe(ops ...Op) (<-chan Event, func()) {
+	if a.closed {
+		return a.sets[0].Subscribe(ops...)
+	}
+
+	ch, cancel := internal.Subscribe(a, ops...)
+	a.subs[ch] = cancel
+
+	return ch, func() {
+		delete(a.subs, ch)
+		cancel()
+	}
+}
+
+// unsubscribe closes the channels allocated by Subscribe.
+func (a *associative) unsubscribe() {
+	for _, cancel := range a.subs {
+		cancel()
+	}
+
+	a.subs = make(map[<-chan Event]func())
+}
+
+func (a *associative) Watch(key interface{}) (<-chan Event, func()) {
+	return a.set(key).Watch(key)
+}
+
+func (a *associative) Ignore(ch chan<- Event, ops ...Op) {
+	for _, s := range a.sets {
+		s.Ignore(ch, ops...)
+	}
+}
+
+func (a *associative) GC() time.Duration {
+	next := time.Duration(0)
+	for _, s := range a.sets {
+		// return the next nearer gc cycle.
+		if d := s.GC(); d != 0 && (next == 0 || d < next) {
+			next = d
+		}
+	}
+	return next
+}
+
+func (a *associative) Close() error {
+	a.unsubscribe()
+	for _, s := range a.sets {
+		s.Close()
+	}
+	a.closed = true
+	return nil
+}
@@ -3,6 +3,9 @@ package libcache
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"math/rand"
 	"sync"
 	"time"
 
@@ -14,46 +17,461 @@ const (
 	Read   = internal.Read
 	Write  = internal.Write
 	Remove = internal.Remove
+	Expire = internal.Expire
+	Purge  = internal.Purge
 )
 
+// ErrNotInt64 is returned by Increment and Decrement,
+// when the existing key value is not an int64.
+var ErrNotInt64 = internal.ErrNotInt64
+
+// ErrNotStored is returned by Increment and Decrement,
+// when the missing key rejected, e.g. by the admission function or the key bytes budget.
+var ErrNotStored = internal.ErrNotStored
+
+// ErrNotFound is returned by GetOrCompute,
+// when the key known to be absent from the underlying data source.
+var ErrNotFound = internal.ErrNotFound
+
+// Negative is the value of a negative entry stored by StoreNegative,
+// that marks a key as known to be absent from the underlying data source.
+// Load, GetOrCompute, Contains and Expiry report its key absent,
+// and Keys, Snapshot, Iterator and Dump never list it,
+// but it carried by the cache events, GetEntry and ColdestN, and counted by Len.
+type Negative = internal.Negative
+
+// NotFound returns an error for a Loader to report the key absent
+// from the underlying data source, GetOrCompute then caches the key
+// as negative entry for ttl and returns ErrNotFound.
+func NotFound(ttl time.Duration) error {
+	return internal.NotFound(ttl)
+}
+
 // Op describes a set of cache operations.
 type Op = internal.Op
 
 // Event represents a single cache entry change.
 type Event = internal.Event
 
+// Clock provides the current time to the cache,
+// to compute and check entries expiry.
+type Clock = internal.Clock
+
+// Cloner is an optional interface implemented by caches,
+// that can clone themselves, like the thread safe caches returned by ReplacementPolicy.New.
+type Cloner interface {
+	// Clone returns a new independent cache of the same replacement policy,
+	// capacity, default TTL, jitter and clock, pre-populated with the cache live entries,
+	// that keeps the entries absolute expiry. The negative entries not cloned.
+	Clone() Cache
+	// Filter is like Clone, but the returned cache holds only the live entries
+	// satisfying pred, the source cache left unchanged.
+	Filter(pred func(key, value interface{}) bool) Cache
+}
+
+// Freezer is an optional interface implemented by caches,
+// that can freeze into a read-only cache, like the thread safe caches returned by ReplacementPolicy.New.
+type Freezer interface {
+	// Freeze returns a read-only cache, populated from a snapshot of the cache live entries,
+	// that keeps the entries absolute expiry. Its reads never lock,
+	// as it never mutated, and never update the underlying "recent-ness".
+	// Its write operations, such as Store, Update, Delete, Resize and Purge, are no-ops,
+	// and GetOrCompute and LoadCtx return the loader result without storing it.
+	Freeze() Cache
+}
+
+// Iterator iterates over a snapshot of cache entries,
+// it skips the entries expired by the time they reached.
+//
+//	it := cache.Iterator()
+//	for it.Next() {
+//		fmt.Println(it.Key(), it.Value())
+//	}
+type Iterator = internal.Iterator
+
+// EntryInfo is a copy of a cache entry metadata.
+//
+// EntryInfo implements encoding.BinaryMarshaler, encoding.BinaryUnmarshaler,
+// json.Marshaler and json.Unmarshaler, serializing the entry key, value and absolute expiry,
+// to persist entries one at a time. The binary encoding uses encoding/gob,
+// therefore the caller must register the keys and values concrete types using gob.Register,
+// while the json encoding round trips the json-able values only, see JSONCodec.
+type EntryInfo = internal.EntryInfo
+
+// Metrics is a point in time snapshot of the cache counters and size,
+// to export them to a metrics system such as Prometheus.
+type Metrics = internal.Metrics
+
+// EntrySource is the subset of the Cache methods used by Merge
+// to read the entries of the other cache, all caches implement it.
+type EntrySource = internal.EntrySource
+
+// Weigher returns the cost of a key value, like its size in bytes.
+type Weigher = internal.Weigher
+
+// AdmissionFunc reports whether a new key value worth caching,
+// like rejecting scan traffic values unlikely to be reused.
+type AdmissionFunc = internal.AdmissionFunc
+
+// KeyFunc normalizes a key into a comparable form, used to index the cache entries,
+// to support keys that are not comparable such as []byte.
+//
+//	cache.SetKeyFunc(func(key interface{}) interface{} {
+//		return string(key.([]byte))
+//	})
+type KeyFunc = internal.KeyFunc
+
+// Keyer is implemented by keys having their own identity, like domain objects,
+// the cache indexes the entries by CacheKey result, and retains the original keys
+// for Keys and the events. Keyer ignored when a KeyFunc set, and for nil pointers.
+//
+//	func (u *User) CacheKey() interface{} {
+//		return u.ID
+//	}
+type Keyer = internal.Keyer
+
+// Frequencyer is an interface implemented by the caches of all the replacement policies,
+// that can report how many times a key accessed.
+//
+// Policies that do not track access frequency, like LRU,
+// report zero frequency for existing keys,
+// and ARC reports a lower bound, 1 or 2, as it only tells the keys accessed once
+// from the keys accessed at least twice.
+type Frequencyer interface {
+	// Frequency returns the key access frequency,
+	// It returns 0, false if the key does not exist.
+	Frequency(key interface{}) (int, bool)
+}
+
 // Cache stores data so that future requests for that data can be served faster.
 type Cache interface {
 	// Load returns key value.
 	Load(key interface{}) (interface{}, bool)
+	// LoadWithExpiry is like Load, but also returns the key value absolute expiry,
+	// zero time for a key that never expires, to decide on a background refresh
+	// without a second Expiry call that may observe another value.
+	LoadWithExpiry(key interface{}) (value interface{}, expiry time.Time, ok bool)
 	// Peek returns key value without updating the underlying "recent-ness".
+	// Peek still collects the key entry if expired, so it serializes with the writers.
 	Peek(key interface{}) (interface{}, bool)
+	// Test reports whether key value satisfies pred, without returning the value.
+	// Test updates the underlying "recent-ness" like Load.
+	Test(key interface{}, pred func(value interface{}) bool) (bool, bool)
 	// Update the key value without updating the underlying "recent-ness".
 	Update(key interface{}, value interface{})
+	// CompareAndSwap swaps the key value if the current value equal to old,
+	// without updating the underlying "recent-ness".
+	// Values compared using ==, and non comparable values are never equal.
+	// The swapped result reports whether the value was swapped.
+	CompareAndSwap(key, old, new interface{}) (swapped bool)
+	// CompareAndDelete deletes the key value if the current value equal to old.
+	// Values compared using ==, and non comparable values are never equal.
+	// The deleted result reports whether the key value was deleted.
+	CompareAndDelete(key, old interface{}) (deleted bool)
+	// Increment atomically adds delta to the key int64 value and returns the new value,
+	// without updating the underlying "recent-ness" or the key expiry.
+	// If the key does not exist, Increment sets its value to delta,
+	// or returns ErrNotStored if the new key rejected.
+	// Increment returns ErrNotInt64 if the existing key value is not an int64.
+	Increment(key interface{}, delta int64) (int64, error)
+	// Decrement atomically subtracts delta from the key int64 value and returns the new value,
+	// without updating the underlying "recent-ness" or the key expiry.
+	// If the key does not exist, Decrement sets its value to -delta.
+	// Decrement returns ErrNotInt64 if the existing key value is not an int64.
+	Decrement(key interface{}, delta int64) (int64, error)
 	// Store sets the key value.
 	Store(key interface{}, value interface{})
 	// StoreWithTTL sets the key value with TTL overrides the default.
 	StoreWithTTL(key interface{}, value interface{}, ttl time.Duration)
+	// StoreEvicting sets the key value like Store, and returns the entry evicted
+	// to make room for it, if any, so the caller maintains its external bookkeeping.
+	// If the store evicted several entries to fit the cost ceiling, the first one returned.
+	StoreEvicting(key, value interface{}) (evictedKey, evictedValue interface{}, evicted bool)
+	// StoreWithTTLEvicting sets the key value like StoreWithTTL,
+	// and returns the entry evicted to make room for it like StoreEvicting.
+	StoreWithTTLEvicting(key, value interface{}, ttl time.Duration) (evictedKey, evictedValue interface{}, evicted bool)
+	// StoreWithDeadline sets the key value with an absolute expiry time,
+	// zero deadline means the key value never expires.
+	// A deadline in the past stores the key value,
+	// which then collected as expired by the next cache operation.
+	StoreWithDeadline(key interface{}, value interface{}, deadline time.Time)
+	// StoreWithDeadlineEvicting sets the key value like StoreWithDeadline,
+	// and returns the entry evicted to make room for it like StoreEvicting.
+	StoreWithDeadlineEvicting(key, value interface{}, deadline time.Time) (evictedKey, evictedValue interface{}, evicted bool)
+	// StoreNegative marks the key as known to be absent for ttl,
+	// Load and GetOrCompute report the key missing until the ttl elapses.
+	// The writes treat the negative entry as absent too, so Update, CompareAndSwap,
+	// CompareAndDelete, Touch and SetExpiry are no-ops, and Increment stores delta over it.
+	StoreNegative(key interface{}, ttl time.Duration)
+	// StoreWithTTLJitter sets the key value with TTL overrides the default,
+	// and applies a random jitter in range [-jitter, +jitter] to the TTL,
+	// The jitter overrides the default jitter and never makes the TTL non-positive.
+	StoreWithTTLJitter(key interface{}, value interface{}, ttl, jitter time.Duration)
+	// StoreMany sets the items key value.
+	// Items stored in map iteration order, which is unspecified,
+	// thus when the items exceeds the cache capacity,
+	// the evicted items are unspecified as well.
+	StoreMany(items map[interface{}]interface{})
+	// Warm populates the cache with items, using the default TTL.
+	// Warm is intended for the initial population of the cache,
+	// it stores the items silently without firing Write events,
+	// while evictions still fire as usual.
+	// Like StoreMany, evicted items are unspecified when items exceeds the cache capacity.
+	Warm(items map[interface{}]interface{})
+	// WarmWithTTL populates the cache with items like Warm, using the given TTL.
+	WarmWithTTL(items map[interface{}]interface{}, ttl time.Duration)
+	// LoadMany returns the found keys value.
+	LoadMany(keys ...interface{}) map[interface{}]interface{}
 	// Delete deletes the key value.
 	Delete(key interface{})
+	// GetAndDelete deletes the key value and returns its value if any.
+	// The loaded result reports whether the key was present.
+	GetAndDelete(key interface{}) (value interface{}, loaded bool)
+	// DeleteMany deletes the keys value.
+	DeleteMany(keys ...interface{})
+	// GetEntry returns a copy of the key entry metadata,
+	// without updating the underlying "recent-ness".
+	GetEntry(key interface{}) (EntryInfo, bool)
 	// Expiry returns key value expiry time.
 	Expiry(key interface{}) (time.Time, bool)
+	// Touch extends the key value expiry to now plus the given ttl,
+	// without updating the value or the underlying "recent-ness".
+	// Zero or negative ttl makes the key value never expires.
+	// Touch emits a Write event, so the GC function reschedules
+	// the next garbage collection cycle if the new expiry is nearer.
+	// The touched result reports whether the key exist.
+	Touch(key interface{}, ttl time.Duration) (touched bool)
+	// SetExpiry sets the key value expiry to the given absolute time,
+	// without updating the value or the underlying "recent-ness".
+	// Zero time makes the key value never expires.
+	// Like Touch, SetExpiry emits a Write event.
+	// The ok result reports whether the key exist and not yet expired.
+	SetExpiry(key interface{}, t time.Time) (ok bool)
+	// RefreshAllTTL sets the expiry of all the cache entries to now plus the given ttl,
+	// unlike SetTTL that affects only the subsequent stores.
+	// Zero or negative ttl makes the entries never expire, and negative entries keep their expiry.
+	// Like Touch, RefreshAllTTL emits a Write event per entry.
+	RefreshAllTTL(ttl time.Duration)
+	// ClearTTL makes all the cache entries never expire, negative entries keep their expiry.
+	// Like Touch, ClearTTL emits a Write event per entry.
+	ClearTTL()
+	// RemainingTTL returns the remaining duration until key value expires,
+	// zero duration returned for a key that never expires.
+	RemainingTTL(key interface{}) (time.Duration, bool)
 	// Keys return cache records keys.
 	Keys() []interface{}
-	// Contains Checks if a key exists in cache.
+	// KeysByExpiry returns the keys ordered by ascending expiry,
+	// followed by the keys that never expire, e.g. to refresh them proactively.
+	// KeysByExpiry scans all the cache entries, therefore it runs in O(n log n).
+	KeysByExpiry() []interface{}
+	// ExpiringWithin returns the keys expiring within d from now, ordered by ascending expiry.
+	// ExpiringWithin scans all the cache entries, therefore it runs in O(n log n).
+	ExpiringWithin(d time.Duration) []interface{}
+	// GetOrCompute returns the key value if exist, Otherwise,
+	// it loads the key value using loader and stores it.
+	// Loader errors are returned and not cached,
+	// except NotFound which caches the key as negative entry,
+	// so GetOrCompute returns ErrNotFound without calling loader until the entry expires.
+	//
+	// The thread safe cache runs loader without holding its lock,
+	// and concurrent calls for the same missing key wait for a single loader call
+	// and share its result. See WithXFetch for the early recomputation of hot keys.
+	GetOrCompute(key interface{}, loader Loader) (interface{}, error)
+	// Merge stores the other cache live entries, negative entries excluded,
+	// keeping their absolute expiry and evicting entries as the capacity requires.
+	// The value of the keys already in the cache resolved using onConflict,
+	// with the incoming entry expiry. Nil onConflict means the incoming value wins.
+	// The thread safe cache snapshots other entries, and then merges them under its lock.
+	Merge(other EntrySource, onConflict func(existing, incoming interface{}) interface{})
+	// LoadCtx is like GetOrCompute, but loader receives a context,
+	// and LoadCtx returns ctx error without caching the loaded value
+	// if ctx done before loader returned.
+	//
+	// The thread safe cache runs loader in its own goroutine, so a caller
+	// stops waiting once its ctx done, and concurrent calls for the same missing key
+	// share a single loader call, that canceled only once all its waiters canceled.
+	// The loader context carries the values of the first caller ctx,
+	// and a loader panic returned to all the waiters as an error.
+	LoadCtx(ctx context.Context, key interface{}, loader func(context.Context) (interface{}, error)) (interface{}, error)
+	// StoreWithTags sets the key value and tags it with the given tags,
+	// replacing the tags of its previous value if any.
+	StoreWithTags(key, value interface{}, tags ...string)
+	// InvalidateTag deletes all keys bearing the given tag,
+	// and returns the number of deleted keys.
+	InvalidateTag(tag string) int
+	// KeysWithPrefix returns the string keys starting with prefix, other keys types ignored.
+	// KeysWithPrefix scans all the cache entries, therefore it runs in O(n).
+	KeysWithPrefix(prefix string) []interface{}
+	// DeleteWithPrefix deletes the string keys starting with prefix,
+	// and returns the number of deleted keys.
+	// DeleteWithPrefix scans all the cache entries, therefore it runs in O(n).
+	DeleteWithPrefix(prefix string) int
+	// DeleteFunc deletes the live entries satisfying pred,
+	// and returns the number of deleted keys.
+	// DeleteFunc scans all the cache entries, therefore it runs in O(n).
+	DeleteFunc(pred func(key, value interface{}) bool) int
+	// Snapshot returns a shallow copy of the cache live entries keys and values.
+	Snapshot() map[interface{}]interface{}
+	// Iterator returns an iterator over a snapshot of the cache entries,
+	// without materializing the keys into a slice first.
+	Iterator() *Iterator
+	// Pin protects the key value from being evicted by the cache replacement policy,
+	// until the key unpinned. If all unpinned entries evicted and the capacity
+	// still exceeded, new entries stored anyway, growing the cache beyond its capacity.
+	// Pinned entries still expire when their TTL elapsed.
+	// Pin reports whether the key exists.
+	Pin(key interface{}) bool
+	// Unpin returns a pinned key back to the cache replacement policy.
+	// Unpin reports whether the key was pinned.
+	Unpin(key interface{}) bool
+	// Contains Checks if a key exists in cache, negative entries excluded.
 	Contains(key interface{}) bool
-	// Purge Clears all cache entries.
+	// ContainsNoGC Checks if a key exists in cache, negative entries excluded,
+	// like Contains but without collecting the expired entries,
+	// it only checks the key entry expiry, so it's cheap when many entries expired at once.
+	// The tradeoff is that expired entries stay resident (and counted by Len)
+	// until the next GC or writer operation sweeps them.
+	ContainsNoGC(key interface{}) bool
+	// ContainsMany Checks if the keys exists in cache,
+	// and returns presence flags in the same order of the given keys.
+	ContainsMany(keys []interface{}) []bool
+	// Purge Clears all cache entries, and fires a single Purge event with nil key.
 	Purge()
-	// Resize cache, returning number evicted
+	// Reset is a more aggressive Purge, it Clears all cache entries,
+	// removes all Notify channels and restores the cache configuration it constructed with,
+	// i.e. the capacity passed to New and the defaults, e.g. TTL and weigher,
+	// then re-applies the options passed to NewWithOptions, such as WithTTL and WithClock.
+	// The configuration set later by the setters, e.g. SetTTL, is dropped,
+	// while the hooks and the panic handler kept.
+	// Reset closes the channels allocated by Subscribe and Watch, so their consumers stop,
+	// while the GC loop and EnableAutoRefresh subscribe again,
+	// and the WithOnEvicted and Hooks.OnEvict listeners registered again.
+	Reset()
+	// Resize cache, returning number evicted,
+	// a Remove event fired for each. Zero size means unbounded, and never evicts,
+	// and negative size clamped to zero.
 	Resize(int) int
+	// PauseEviction defers the capacity and cost eviction, e.g. during a bulk import,
+	// so stores exceed the cache capacity until ResumeEviction called.
+	// Expired entries still collected while paused.
+	PauseEviction()
+	// ResumeEviction evicts entries down to the cache capacity, cost ceiling
+	// and key bytes budget in one pass, the latter two as ResizeCost does,
+	// and re-enables the eviction on store.
+	ResumeEviction()
+	// ResizeCost sets the entries total cost ceiling, zero means no ceiling,
+	// and evicts entries until the total cost fits, returning the summed cost evicted.
+	// The entries whose cost alone exceeds the ceiling evicted first, as they never fit,
+	// then the entries in eviction order down to the ceiling, the last entry included.
+	// Pinned entries are never evicted, and remain even above the ceiling.
+	// The ceiling enforced on writes along with the capacity set by Resize,
+	// a written entry is never evicted by itself even if its cost exceeds the ceiling.
+	ResizeCost(maxCost int64) int64
+	// SetMaxKeyBytes sets the entries total key bytes budget, zero or negative means no budget,
+	// and evicts entries until the total key bytes fits, as ResizeCost does for the cost.
+	// String keys count their length, and other keys count a fixed overhead of 16 bytes.
+	// The budget enforced on writes along with the capacity set by Resize,
+	// whichever limit exceeded first evicts, and a written entry is never evicted by itself.
+	SetMaxKeyBytes(n int64)
+	// PeekOldest returns the key and value of the eviction candidate,
+	// without collecting expired entries or updating the underlying "recent-ness".
+	PeekOldest() (key, value interface{}, ok bool)
+	// PeekNewest returns the key and value of the last entry in the eviction order,
+	// i.e. the most recent entry for LRU and FIFO, and the least recent for MRU and LIFO,
+	// without collecting expired entries or updating the underlying "recent-ness".
+	PeekNewest() (key, value interface{}, ok bool)
+	// OrderedKeys returns the keys in eviction order, from the eviction candidate
+	// to the most protected entry, followed by the pinned keys,
+	// without collecting expired entries or updating the underlying "recent-ness".
+	// The order is policy-specific, e.g. LRU returns the oldest to the newest key,
+	// and LFU the least to the most frequent key.
+	OrderedKeys() []interface{}
+	// ColdestN returns up to n entries in eviction order, starting from the eviction candidate,
+	// without collecting expired entries or updating the underlying "recent-ness",
+	// e.g. to proactively demote them to a lower tier.
+	// It returns all the entries if n exceeds the cache length, and nil if n is not positive.
+	// ColdestN walks all the cache entries, therefore it runs in O(n).
+	ColdestN(n int) []EntryInfo
+	// HottestN is like ColdestN, but starts from the other end of the eviction order.
+	HottestN(n int) []EntryInfo
 	// Len Returns the number of items in the cache.
 	Len() int
+	// Cost returns the total cost of the cache entries,
+	// the entries cost computed at write time by the weigher.
+	Cost() int64
+	// Metrics returns a snapshot of the cache hits, misses, evictions,
+	// expirations, length, capacity, total cost and the last GC sweep, cheap enough for hot paths.
+	// Load, Peek and Test count as lookups, and Reset zeroes the counters.
+	Metrics() Metrics
+	// Utilization returns how full the cache is, as the ratio of the total cost
+	// to the cost ceiling if any, otherwise the ratio of the length to the capacity.
+	// Utilization returns 0 for unbounded caches.
+	Utilization() float64
+	// SetWeigher sets the function used to compute the cost of entries written afterwards,
+	// a nil weigher means each entry costs 1.
+	SetWeigher(Weigher)
+	// SetAdmissionFunc sets the function deciding whether the stores of new keys
+	// insert them, a rejected store is a no-op that neither inserts nor evicts.
+	// Stores of existing keys, Update and negative entries are always admitted,
+	// so a rejection never leaves a stale value. Default nil admits all.
+	SetAdmissionFunc(AdmissionFunc)
+	// SetKeyFunc sets the function used to normalize keys into a comparable form,
+	// a nil function means keys used as is. It purges the cache entries,
+	// therefore it should be set right after construction, see WithKeyFunc.
+	//
+	// Keys, Iterator and events carry the original keys,
+	// while the maps returned by LoadMany and Snapshot keyed by the normalized keys.
+	SetKeyFunc(KeyFunc)
 	// Cap Returns the cache capacity.
 	Cap() int
+	// Policy returns the replacement policy the cache constructed with,
+	// so the policy specific features chosen safely.
+	//
+	//	if cache.Policy() == libcache.ARC {
+	//		// ARC specific features.
+	//	}
+	//
+	// The tiered caches report their first tier policy, and the set-associative caches LRU.
+	Policy() ReplacementPolicy
 	// TTL returns entries default TTL.
 	TTL() time.Duration
 	// SetTTL sets entries default TTL.
 	SetTTL(time.Duration)
+	// SetClock sets the clock used to compute and check entries expiry.
+	SetClock(Clock)
+	// SetJitter sets entries default TTL jitter,
+	// to prevent synchronized expiration of entries stored with the same TTL.
+	SetJitter(time.Duration)
+	// SetMaxIdle sets the max duration an entry lives without being loaded,
+	// regardless of how recently it stored. The entry expires at the earliest of
+	// its TTL expiry and its last access plus the max idle duration.
+	// Peek does not count as an access. Zero duration disables it, Default zero.
+	SetMaxIdle(time.Duration)
+	// SetBloomFilter fronts the cache lookups with a counting bloom filter
+	// sized for expectedN keys at the given false positive rate,
+	// so Load, Peek and Contains of absent keys return early.
+	// Zero or negative expectedN disables it, Default disabled.
+	SetBloomFilter(expectedN int, fpRate float64)
+	// SetDeterministic sets whether the cache is reproducible, for golden tests.
+	// Once enabled, Keys, Iterator and the other scans visit the entries in store order,
+	// and the cache random sources not set explicitly seeded with a fixed seed.
+	// The store order index costs a list node per entry, Default disabled.
+	SetDeterministic(bool)
+	// SetGCBatchSize sets the max number of expired entries a GC call sweeps,
+	// to bound the time GC holds the cache lock when many entries expire at once.
+	// GC returns a short duration once the batch leaves expired entries,
+	// so the GC function comes back promptly. Zero means no limit, Default zero.
+	// The operations on a single key, such as Load and Store, sweep inline up to the same batch,
+	// and the key itself if expired, leaving the remainder to GC,
+	// while the scans, such as Snapshot and Iterator, sweep all the expired entries.
+	SetGCBatchSize(n int)
+	// SetPurgeRemoveEvents sets whether Purge fires a Remove event for each purged entry,
+	// instead of a single Purge event, e.g. for consumers tracking the keys,
+	// at the cost of flooding the subscribers on large purges. Default false.
+	SetPurgeRemoveEvents(bool)
+	// SetUpdateRefreshesTTL sets whether Update resets the entry expiry
+	// to now plus the default TTL. Default false.
+	SetUpdateRefreshesTTL(bool)
 	// RegisterOnEvicted registers a function,
 	// to call it when an entry is purged from the cache.
 	//
@@ -67,7 +485,45 @@ type Cache interface {
 	// Notify causes cache to relay events to ch.
 	// If no operations are provided, all incoming operations will be relayed to ch.
 	// Otherwise, just the provided operations will.
+	//
+	// Each channel receives its events in the order the operations occurred on the cache,
+	// the events dropped while ch is full excepted.
 	Notify(ch chan<- Event, ops ...Op)
+	// NotifyBlocking causes cache to relay events to ch like Notify,
+	// but instead of dropping events when ch is full,
+	// the cache operation blocks until ch receiver is ready.
+	//
+	// The thread safe caches returned by ReplacementPolicy.New deliver the events
+	// to ch from a dedicated goroutine, queuing them in order while the receiver is slow,
+	// so the receiver never stalls the cache operations, and may call the cache.
+	// Ignore with no operations stops the delivery and drops the queued events.
+	// Once they closed, NotifyBlocking does nothing.
+	//
+	// Other caches hold their lock while delivering events,
+	// therefore a slow receiver stalls all cache operations,
+	// and a receiver calls the cache causes a deadlock.
+	NotifyBlocking(ch chan<- Event, ops ...Op)
+	// NotifyWithReplay causes cache to send a synthetic Write event for each live entry to ch,
+	// if the provided operations include Write, then to relay events to ch like Notify.
+	// The thread safe cache replays and registers ch while holding its lock,
+	// so no event is missed or duplicated in between.
+	//
+	// The replay waits for ch receiver while holding the cache lock,
+	// therefore ch must be buffered to hold all the entries,
+	// or drained by another goroutine.
+	NotifyWithReplay(ch chan<- Event, ops ...Op)
+	// Subscribe allocates a buffered channel and causes cache to relay events to it,
+	// in the same manner as Notify. It returns the channel along with
+	// an unsubscribe function that undoes the effect of Subscribe and closes the channel.
+	// Calling the unsubscribe function more than once is safe.
+	Subscribe(ops ...Op) (<-chan Event, func())
+	// Watch allocates a buffered channel and causes cache to relay
+	// the Write, Remove and Expire events of the given key, and the Purge events to it,
+	// events dropped while the channel is full. It returns the channel along with
+	// a cancel function that unregisters and closes the channel.
+	// Each Watch call allocates its own channel,
+	// and calling the cancel function more than once is safe.
+	Watch(key interface{}) (<-chan Event, func())
 	// Ignore causes the provided operations to be ignored. Ignore undoes the effect
 	// of any prior calls to Notify for the provided operations.
 	// If no operations are provided, ch removed.
@@ -76,10 +532,15 @@ type Cache interface {
 	// all expired items from the cache evicted.
 	//
 	// GC returns the remaining time duration for the next gc cycle
-	// if there any, Otherwise, it return 0.
+	// if there any, Otherwise, it return 0. See SetGCBatchSize.
 	//
 	// Calling GC without waits for the duration to elapsed considered a no-op.
 	GC() time.Duration
+	// Close purges the cache entries, stops its background resources
+	// and closes the channels allocated by Subscribe and Watch,
+	// which in turn stops the GC function.
+	// After Close, the cache stores nothing and every lookup returns not-found.
+	Close() error
 }
 
 // GC runs a garbage collection to evict expired items from the cache on time.
@@ -88,24 +549,35 @@ type Cache interface {
 // cache write events and capture the result of calling the GC method on cache
 // to trigger the garbage collection loop at the right point in time.
 //
-// GC is a long running function, it returns when ctx done, therefore the
-// caller must start it in its own goroutine.
+// GC is a long running function, it returns when ctx done or the cache closed,
+// therefore the caller must start it in its own goroutine.
 //
 // Experimental
 //
 // Notice: This func is EXPERIMENTAL and may be changed or removed in a
 // later release.
 func GC(ctx context.Context, cache Cache) {
+	gc(ctx, cache, 0)
+}
+
+// gc runs the GC loop, and if interval greater than zero,
+// it runs a garbage collection every interval as well.
+func gc(ctx context.Context, cache Cache, interval time.Duration) {
 	remaining := time.Duration(0)
 
 	t := time.NewTimer(remaining)
 	defer t.Stop()
 
-	c := make(chan Event, 1)
-	cache.Notify(c, Write)
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	c, cancel := cache.Subscribe(Write)
 	defer func() {
-		cache.Ignore(c)
-		close(c)
+		cancel()
 	}()
 
 	gc := func() {
@@ -118,7 +590,16 @@ func GC(ctx context.Context, cache Cache) {
 
 	for {
 		select {
-		case e := <-c:
+		case e, ok := <-c:
+			// cache closed, or reset.
+			if !ok {
+				if c, cancel, ok = resubscribe(cache, Write); !ok {
+					return
+				}
+				gc()
+				continue
+			}
+
 			if e.Expiry.IsZero() {
 				continue
 			}
@@ -128,50 +609,254 @@ func GC(ctx context.Context, cache Cache) {
 			}
 		case <-t.C:
 			gc()
+		case <-tick:
+			gc()
 		case <-ctx.Done():
 			return
 		}
 	}
 }
 
+// resubscribe subscribes to the cache ops again, once Reset closed the previous subscription,
+// and reports false if the cache closed instead, as closed caches return closed channels.
+// An event delivered meanwhile may be dropped, so the caller catches up on its own.
+func resubscribe(cache Cache, ops ...Op) (<-chan Event, func(), bool) {
+	ch, cancel := cache.Subscribe(ops...)
+	select {
+	case _, ok := <-ch:
+		if !ok {
+			return ch, cancel, false
+		}
+	default:
+	}
+	return ch, cancel, true
+}
+
 type cache struct {
 	// mu guards unsafe cache.
 	// Calls to mu.Unlock are currently not deferred,
 	// because defer adds ~200 ns (as of go1.)
-	mu     sync.Mutex
+	//
+	// Only operations that never mutate the unsafe cache hold the read lock.
+	// Peek, Contains and Expiry hold the write lock, unlike Len, Cap, Keys and TTL,
+	// as they collect the expired entries inline, record the lookup metrics
+	// and emit Read events, none of which is safe under a shared lock.
+	// ContainsNoGC is their read-locked alternative.
+	mu     sync.RWMutex
 	unsafe Cache
+	// policy is the replacement policy the cache constructed with.
+	policy ReplacementPolicy
+	// renew returns a new empty cache constructed like this one,
+	// nil means a new cache of the same policy and capacity.
+	renew func() Cache
+	// calls holds the in-flight GetOrCompute loader calls,
+	// keyed by the normalized keys.
+	calls map[interface{}]*call
+	// keyFunc normalizes the keys of calls.
+	keyFunc KeyFunc
+	// beta scales the XFetch early recomputation, zero means disabled.
+	beta float64
+	// rand is the XFetch random source, nil means the global source.
+	rand *rand.Rand
+	// clock is the clock set on the unsafe cache, nil means the real clock.
+	clock Clock
+	// jitter is the default TTL jitter set on the unsafe cache.
+	jitter time.Duration
+	// forwarders holds the forwarders of the NotifyBlocking channels.
+	forwarders map[chan<- Event]*forwarder
+	// opts are the options the cache constructed with, re-applied by Reset.
+	opts []Option
+	// hooks are set at construction, and invoked without holding mu.
+	hooks Hooks
+	// panicHandler handles the panics of the user callbacks, nil means they propagate.
+	panicHandler PanicHandler
+	// listeners holds the ops of the NotifyBlocking channels registered by the options listeners,
+	// which Reset registers again.
+	listeners map[chan<- Event][]Op
+	// done closed once the cache closed, so the options listeners exit.
+	done chan struct{}
+}
+
+// call is an in-flight or completed GetOrCompute or LoadCtx loader call.
+type call struct {
+	// done closed once the call completed.
+	done chan struct{}
+	val  interface{}
+	err  error
+	// waiters is the number of callers waiting for the call.
+	waiters int
+	// cancel cancels the LoadCtx loader context, nil for GetOrCompute calls.
+	cancel context.CancelFunc
+}
+
+// newCall registers a new in-flight call for the key id,
+// newCall must be called while holding the cache lock.
+func (c *cache) newCall(id interface{}) *call {
+	if c.calls == nil {
+		c.calls = make(map[interface{}]*call)
+	}
+
+	cl := &call{done: make(chan struct{}), waiters: 1}
+	c.calls[id] = cl
+	return cl
 }
 
 func (c *cache) Load(key interface{}) (interface{}, bool) {
+	c.beforeLoad(key)
 	c.mu.Lock()
 	v, ok := c.unsafe.Load(key)
 	c.mu.Unlock()
+	c.afterLoad(key, ok)
 	return v, ok
 }
 
+func (c *cache) LoadWithExpiry(key interface{}) (interface{}, time.Time, bool) {
+	c.beforeLoad(key)
+	c.mu.Lock()
+	v, exp, ok := c.unsafe.LoadWithExpiry(key)
+	c.mu.Unlock()
+	c.afterLoad(key, ok)
+	return v, exp, ok
+}
+
 func (c *cache) Peek(key interface{}) (interface{}, bool) {
+	c.beforeLoad(key)
 	c.mu.Lock()
 	v, ok := c.unsafe.Peek(key)
 	c.mu.Unlock()
+	c.afterLoad(key, ok)
 	return v, ok
 }
 
+func (c *cache) Test(key interface{}, pred func(value interface{}) bool) (bool, bool) {
+	c.mu.Lock()
+	ok, found := c.unsafe.Test(key, pred)
+	c.mu.Unlock()
+	return ok, found
+}
+
 func (c *cache) Update(key interface{}, value interface{}) {
 	c.mu.Lock()
 	c.unsafe.Update(key, value)
 	c.mu.Unlock()
 }
 
+func (c *cache) CompareAndSwap(key, old, new interface{}) bool {
+	c.mu.Lock()
+	ok := c.unsafe.CompareAndSwap(key, old, new)
+	c.mu.Unlock()
+	return ok
+}
+
+func (c *cache) CompareAndDelete(key, old interface{}) bool {
+	c.mu.Lock()
+	ok := c.unsafe.CompareAndDelete(key, old)
+	c.mu.Unlock()
+	return ok
+}
+
+func (c *cache) Increment(key interface{}, delta int64) (int64, error) {
+	c.mu.Lock()
+	n, err := c.unsafe.Increment(key, delta)
+	c.mu.Unlock()
+	return n, err
+}
+
+func (c *cache) Decrement(key interface{}, delta int64) (int64, error) {
+	c.mu.Lock()
+	n, err := c.unsafe.Decrement(key, delta)
+	c.mu.Unlock()
+	return n, err
+}
+
 func (c *cache) Store(key interface{}, value interface{}) {
+	c.beforeStore(key)
 	c.mu.Lock()
 	c.unsafe.Store(key, value)
 	c.mu.Unlock()
+	c.afterStore(key)
 }
 
 func (c *cache) StoreWithTTL(key interface{}, value interface{}, ttl time.Duration) {
+	c.beforeStore(key)
 	c.mu.Lock()
 	c.unsafe.StoreWithTTL(key, value, ttl)
 	c.mu.Unlock()
+	c.afterStore(key)
+}
+
+func (c *cache) StoreEvicting(key, value interface{}) (interface{}, interface{}, bool) {
+	c.beforeStore(key)
+	c.mu.Lock()
+	k, v, ok := c.unsafe.StoreEvicting(key, value)
+	c.mu.Unlock()
+	c.afterStore(key)
+	return k, v, ok
+}
+
+func (c *cache) StoreWithTTLEvicting(key, value interface{}, ttl time.Duration) (interface{}, interface{}, bool) {
+	c.beforeStore(key)
+	c.mu.Lock()
+	k, v, ok := c.unsafe.StoreWithTTLEvicting(key, value, ttl)
+	c.mu.Unlock()
+	c.afterStore(key)
+	return k, v, ok
+}
+
+func (c *cache) StoreWithDeadline(key interface{}, value interface{}, deadline time.Time) {
+	c.beforeStore(key)
+	c.mu.Lock()
+	c.unsafe.StoreWithDeadline(key, value, deadline)
+	c.mu.Unlock()
+	c.afterStore(key)
+}
+
+func (c *cache) StoreWithDeadlineEvicting(key, value interface{}, deadline time.Time) (interface{}, interface{}, bool) {
+	c.beforeStore(key)
+	c.mu.Lock()
+	k, v, ok := c.unsafe.StoreWithDeadlineEvicting(key, value, deadline)
+	c.mu.Unlock()
+	c.afterStore(key)
+	return k, v, ok
+}
+
+func (c *cache) StoreNegative(key interface{}, ttl time.Duration) {
+	c.mu.Lock()
+	c.unsafe.StoreNegative(key, ttl)
+	c.mu.Unlock()
+}
+
+func (c *cache) StoreMany(items map[interface{}]interface{}) {
+	c.mu.Lock()
+	c.unsafe.StoreMany(items)
+	c.mu.Unlock()
+}
+
+func (c *cache) Warm(items map[interface{}]interface{}) {
+	c.mu.Lock()
+	c.unsafe.Warm(items)
+	c.mu.Unlock()
+}
+
+func (c *cache) WarmWithTTL(items map[interface{}]interface{}, ttl time.Duration) {
+	c.mu.Lock()
+	c.unsafe.WarmWithTTL(items, ttl)
+	c.mu.Unlock()
+}
+
+func (c *cache) LoadMany(keys ...interface{}) map[interface{}]interface{} {
+	c.mu.Lock()
+	items := c.unsafe.LoadMany(keys...)
+	c.mu.Unlock()
+	return items
+}
+
+func (c *cache) StoreWithTTLJitter(key interface{}, value interface{}, ttl, jitter time.Duration) {
+	c.beforeStore(key)
+	c.mu.Lock()
+	c.unsafe.StoreWithTTLJitter(key, value, ttl, jitter)
+	c.mu.Unlock()
+	c.afterStore(key)
 }
 
 func (c *cache) Delete(key interface{}) {
@@ -180,13 +865,315 @@ func (c *cache) Delete(key interface{}) {
 	c.mu.Unlock()
 }
 
-func (c *cache) Keys() []interface{} {
+func (c *cache) GetAndDelete(key interface{}) (interface{}, bool) {
+	c.mu.Lock()
+	v, ok := c.unsafe.GetAndDelete(key)
+	c.mu.Unlock()
+	return v, ok
+}
+
+func (c *cache) DeleteMany(keys ...interface{}) {
+	c.mu.Lock()
+	c.unsafe.DeleteMany(keys...)
+	c.mu.Unlock()
+}
+
+func (c *cache) GetOrCompute(key interface{}, loader Loader) (interface{}, error) {
+	c.mu.Lock()
+	if v, ok := c.unsafe.Load(key); ok {
+		if c.beta > 0 {
+			c.refreshEarly(key, loader)
+		}
+		c.mu.Unlock()
+		return v, nil
+	}
+
+	if e, ok := c.unsafe.GetEntry(key); ok && internal.IsNegative(e.Value) {
+		c.mu.Unlock()
+		return nil, ErrNotFound
+	}
+
+	id := c.keyFunc.Key(key)
+	// Join the in-flight call, unless all its LoadCtx waiters canceled,
+	// and it is about to return their context error.
+	// GetOrCompute waiters never cancel, so the joined call loader never canceled.
+	if cl, ok := c.calls[id]; ok && cl.waiters > 0 {
+		cl.waiters++
+		c.mu.Unlock()
+		<-cl.done
+		return cl.val, cl.err
+	}
+
+	cl := c.newCall(id)
+	start := c.now()
+	c.mu.Unlock()
+
+	c.load(key, id, start, cl, loader)
+	return cl.val, cl.err
+}
+
+func (c *cache) Merge(other EntrySource, onConflict func(existing, incoming interface{}) interface{}) {
+	// Snapshot other without holding the lock,
+	// as other may be the cache itself.
+	entries := internal.LiveEntries(other)
+
+	c.mu.Lock()
+	internal.MergeEntries(c.unsafe, entries, onConflict)
+	c.mu.Unlock()
+}
+
+func (c *cache) LoadCtx(ctx context.Context, key interface{}, loader func(context.Context) (interface{}, error)) (interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	if v, ok := c.unsafe.Load(key); ok {
+		c.mu.Unlock()
+		return v, nil
+	}
+
+	if e, ok := c.unsafe.GetEntry(key); ok && internal.IsNegative(e.Value) {
+		c.mu.Unlock()
+		return nil, ErrNotFound
+	}
+
+	id := c.keyFunc.Key(key)
+	cl, ok := c.calls[id]
+
+	// Join the in-flight call, unless all its waiters canceled,
+	// and it is about to return their context error.
+	if ok && cl.waiters > 0 {
+		cl.waiters++
+	} else {
+		// The loader outlives the caller context, as other waiters may join,
+		// and canceled once all waiters canceled.
+		lctx, cancel := context.WithCancel(detached{ctx})
+		cl = c.newCall(id)
+		cl.cancel = cancel
+		start := c.now()
+
+		go c.load(key, id, start, cl, recoverLoader(internal.ContextLoader(lctx, loader)))
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-cl.done:
+		return cl.val, cl.err
+	case <-ctx.Done():
+		c.mu.Lock()
+		cl.waiters--
+		if cl.waiters == 0 && cl.cancel != nil {
+			cl.cancel()
+		}
+		c.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+// recoverLoader returns a loader that reports the loader panic as an error,
+// as no caller goroutine to propagate it to.
+func recoverLoader(loader Loader) Loader {
+	return func(key interface{}) (v interface{}, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				v, err = nil, fmt.Errorf("libcache: loader panicked: %v", r)
+			}
+		}()
+		return loader(key)
+	}
+}
+
+// detached is a context that carries the values of its parent,
+// but never canceled with it.
+type detached struct {
+	context.Context
+}
+
+func (detached) Deadline() (time.Time, bool) { return time.Time{}, false }
+
+func (detached) Done() <-chan struct{} { return nil }
+
+func (detached) Err() error { return nil }
+
+// load runs loader for the in-flight call, stores the loaded value,
+// and releases the call waiters even if loader panics.
+func (c *cache) load(key, id interface{}, start time.Time, cl *call, loader Loader) {
+	defer func() {
+		c.mu.Lock()
+		cl.val, cl.err = internal.StoreLoaded(c.unsafe, key, cl.val, cl.err)
+		if d, ok := c.unsafe.(deltaer); ok && cl.err == nil {
+			d.SetDelta(key, c.now().Sub(start))
+		}
+
+		// A canceled call may be replaced by a new one.
+		if c.calls[id] == cl {
+			delete(c.calls, id)
+		}
+
+		c.mu.Unlock()
+		close(cl.done)
+
+		if cl.cancel != nil {
+			cl.cancel()
+		}
+	}()
+
+	cl.err = errors.New("libcache: loader panicked")
+	cl.val, cl.err = loader(key)
+}
+
+func (c *cache) StoreWithTags(key, value interface{}, tags ...string) {
+	c.beforeStore(key)
+	c.mu.Lock()
+	c.unsafe.StoreWithTags(key, value, tags...)
+	c.mu.Unlock()
+	c.afterStore(key)
+}
+
+func (c *cache) InvalidateTag(tag string) int {
+	c.mu.Lock()
+	n := c.unsafe.InvalidateTag(tag)
+	c.mu.Unlock()
+	return n
+}
+
+func (c *cache) KeysWithPrefix(prefix string) []interface{} {
+	c.mu.Lock()
+	keys := c.unsafe.KeysWithPrefix(prefix)
+	c.mu.Unlock()
+	return keys
+}
+
+func (c *cache) DeleteWithPrefix(prefix string) int {
+	c.mu.Lock()
+	n := c.unsafe.DeleteWithPrefix(prefix)
+	c.mu.Unlock()
+	return n
+}
+
+func (c *cache) DeleteFunc(pred func(key, value interface{}) bool) int {
+	c.mu.Lock()
+	n := c.unsafe.DeleteFunc(pred)
+	c.mu.Unlock()
+	return n
+}
+
+func (c *cache) Snapshot() map[interface{}]interface{} {
+	c.mu.Lock()
+	m := c.unsafe.Snapshot()
+	c.mu.Unlock()
+	return m
+}
+
+func (c *cache) Policy() ReplacementPolicy {
+	return c.policy
+}
+
+func (c *cache) Clone() Cache {
+	return c.clone(nil)
+}
+
+func (c *cache) Filter(pred func(key, value interface{}) bool) Cache {
+	return c.clone(pred)
+}
+
+// clone returns a new cache of the same policy, capacity, default TTL, jitter and clock,
+// holding the live entries satisfying pred, nil pred matches all the entries.
+// The negative entries excluded, like the other enumerations.
+func (c *cache) clone(pred func(key, value interface{}) bool) Cache {
 	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var clone Cache
+	if c.renew != nil {
+		clone = c.renew()
+	} else {
+		clone = c.policy.New(c.unsafe.Cap())
+	}
+
+	clone.SetTTL(c.unsafe.TTL())
+	clone.SetJitter(c.jitter)
+	clone.SetKeyFunc(c.keyFunc)
+	if c.clock != nil {
+		clone.SetClock(c.clock)
+	}
+
+	// The absolute expiry kept as is, the jitter applied already when the entry stored.
+	now := c.now()
+	for it := c.unsafe.Iterator(); it.Next(); {
+		exp := it.Expiry()
+		if !exp.IsZero() && !now.Before(exp) {
+			continue
+		}
+		if internal.IsNegative(it.Value()) || (pred != nil && !pred(it.Key(), it.Value())) {
+			continue
+		}
+		clone.StoreWithDeadline(it.Key(), it.Value(), exp)
+	}
+
+	return clone
+}
+
+func (c *cache) Iterator() *Iterator {
+	c.mu.Lock()
+	it := c.unsafe.Iterator()
+	c.mu.Unlock()
+	return it
+}
+
+func (c *cache) Keys() []interface{} {
+	c.mu.RLock()
 	keys := c.unsafe.Keys()
+	c.mu.RUnlock()
+	return keys
+}
+
+func (c *cache) KeysByExpiry() []interface{} {
+	c.mu.Lock()
+	keys := c.unsafe.KeysByExpiry()
 	c.mu.Unlock()
 	return keys
 }
 
+func (c *cache) ExpiringWithin(d time.Duration) []interface{} {
+	c.mu.Lock()
+	keys := c.unsafe.ExpiringWithin(d)
+	c.mu.Unlock()
+	return keys
+}
+
+// ExpiryPairs returns all the keys paired with their expiry, in unspecified order.
+func (c *cache) ExpiryPairs() []internal.KeyExpiry {
+	c.mu.Lock()
+	pairs := expiryPairs(c.unsafe)
+	c.mu.Unlock()
+	return pairs
+}
+
+// ExpiryPairsWithin returns the keys expiring within d from now paired with their expiry,
+// in unspecified order.
+func (c *cache) ExpiryPairsWithin(d time.Duration) []internal.KeyExpiry {
+	c.mu.Lock()
+	pairs := expiryPairsWithin(c.unsafe, d)
+	c.mu.Unlock()
+	return pairs
+}
+
+func (c *cache) Pin(key interface{}) bool {
+	c.mu.Lock()
+	ok := c.unsafe.Pin(key)
+	c.mu.Unlock()
+	return ok
+}
+
+func (c *cache) Unpin(key interface{}) bool {
+	c.mu.Lock()
+	ok := c.unsafe.Unpin(key)
+	c.mu.Unlock()
+	return ok
+}
+
 func (c *cache) Contains(key interface{}) bool {
 	c.mu.Lock()
 	ok := c.unsafe.Contains(key)
@@ -194,12 +1181,67 @@ func (c *cache) Contains(key interface{}) bool {
 	return ok
 }
 
+func (c *cache) ContainsNoGC(key interface{}) bool {
+	c.mu.RLock()
+	ok := c.unsafe.ContainsNoGC(key)
+	c.mu.RUnlock()
+	return ok
+}
+
+func (c *cache) ContainsMany(keys []interface{}) []bool {
+	c.mu.Lock()
+	flags := c.unsafe.ContainsMany(keys)
+	c.mu.Unlock()
+	return flags
+}
+
 func (c *cache) Purge() {
 	c.mu.Lock()
 	c.unsafe.Purge()
 	c.mu.Unlock()
 }
 
+func (c *cache) Reset() {
+	c.mu.Lock()
+	c.stopForwarders()
+	c.unsafe.Reset()
+	c.keyFunc = nil
+	c.clock = nil
+	c.jitter = 0
+	for _, opt := range c.opts {
+		opt(reconfigurer{Cache: c.unsafe, c: c})
+	}
+	for ch, ops := range c.listeners {
+		c.forward(ch, ops...)
+	}
+	c.mu.Unlock()
+}
+
+func (c *cache) ResizeCost(maxCost int64) int64 {
+	c.mu.Lock()
+	n := c.unsafe.ResizeCost(maxCost)
+	c.mu.Unlock()
+	return n
+}
+
+func (c *cache) SetMaxKeyBytes(n int64) {
+	c.mu.Lock()
+	c.unsafe.SetMaxKeyBytes(n)
+	c.mu.Unlock()
+}
+
+func (c *cache) PauseEviction() {
+	c.mu.Lock()
+	c.unsafe.PauseEviction()
+	c.mu.Unlock()
+}
+
+func (c *cache) ResumeEviction() {
+	c.mu.Lock()
+	c.unsafe.ResumeEviction()
+	c.mu.Unlock()
+}
+
 func (c *cache) Resize(s int) int {
 	c.mu.Lock()
 	n := c.unsafe.Resize(s)
@@ -208,23 +1250,98 @@ func (c *cache) Resize(s int) int {
 }
 
 func (c *cache) Len() int {
-	c.mu.Lock()
+	c.mu.RLock()
 	n := c.unsafe.Len()
-	c.mu.Unlock()
+	c.mu.RUnlock()
 	return n
 }
 
 func (c *cache) Cap() int {
-	c.mu.Lock()
+	c.mu.RLock()
 	n := c.unsafe.Cap()
-	c.mu.Unlock()
+	c.mu.RUnlock()
 	return n
 }
 
-func (c *cache) TTL() time.Duration {
+func (c *cache) PeekOldest() (interface{}, interface{}, bool) {
+	c.mu.RLock()
+	k, v, ok := c.unsafe.PeekOldest()
+	c.mu.RUnlock()
+	return k, v, ok
+}
+
+func (c *cache) PeekNewest() (interface{}, interface{}, bool) {
+	c.mu.RLock()
+	k, v, ok := c.unsafe.PeekNewest()
+	c.mu.RUnlock()
+	return k, v, ok
+}
+
+func (c *cache) OrderedKeys() []interface{} {
+	c.mu.RLock()
+	keys := c.unsafe.OrderedKeys()
+	c.mu.RUnlock()
+	return keys
+}
+
+func (c *cache) ColdestN(n int) []EntryInfo {
+	c.mu.RLock()
+	infos := c.unsafe.ColdestN(n)
+	c.mu.RUnlock()
+	return infos
+}
+
+func (c *cache) HottestN(n int) []EntryInfo {
+	c.mu.RLock()
+	infos := c.unsafe.HottestN(n)
+	c.mu.RUnlock()
+	return infos
+}
+
+func (c *cache) Cost() int64 {
+	c.mu.RLock()
+	n := c.unsafe.Cost()
+	c.mu.RUnlock()
+	return n
+}
+
+func (c *cache) Metrics() Metrics {
+	c.mu.RLock()
+	m := c.unsafe.Metrics()
+	c.mu.RUnlock()
+	return m
+}
+
+func (c *cache) Utilization() float64 {
+	c.mu.RLock()
+	u := c.unsafe.Utilization()
+	c.mu.RUnlock()
+	return u
+}
+
+func (c *cache) SetWeigher(w Weigher) {
 	c.mu.Lock()
-	ttl := c.unsafe.TTL()
+	c.unsafe.SetWeigher(w)
 	c.mu.Unlock()
+}
+
+func (c *cache) SetAdmissionFunc(fn AdmissionFunc) {
+	c.mu.Lock()
+	c.unsafe.SetAdmissionFunc(fn)
+	c.mu.Unlock()
+}
+
+func (c *cache) SetKeyFunc(fn KeyFunc) {
+	c.mu.Lock()
+	c.unsafe.SetKeyFunc(fn)
+	c.keyFunc = fn
+	c.mu.Unlock()
+}
+
+func (c *cache) TTL() time.Duration {
+	c.mu.RLock()
+	ttl := c.unsafe.TTL()
+	c.mu.RUnlock()
 	return ttl
 }
 
@@ -234,6 +1351,59 @@ func (c *cache) SetTTL(ttl time.Duration) {
 	c.mu.Unlock()
 }
 
+func (c *cache) SetClock(clock Clock) {
+	c.mu.Lock()
+	c.unsafe.SetClock(clock)
+	c.clock = clock
+	c.mu.Unlock()
+}
+
+func (c *cache) SetJitter(jitter time.Duration) {
+	c.mu.Lock()
+	c.unsafe.SetJitter(jitter)
+	c.jitter = jitter
+	c.mu.Unlock()
+}
+
+func (c *cache) SetMaxIdle(d time.Duration) {
+	c.mu.Lock()
+	c.unsafe.SetMaxIdle(d)
+	c.mu.Unlock()
+}
+
+func (c *cache) SetBloomFilter(expectedN int, fpRate float64) {
+	c.mu.Lock()
+	c.unsafe.SetBloomFilter(expectedN, fpRate)
+	c.mu.Unlock()
+}
+
+func (c *cache) SetGCBatchSize(n int) {
+	c.mu.Lock()
+	c.unsafe.SetGCBatchSize(n)
+	c.mu.Unlock()
+}
+
+func (c *cache) SetPurgeRemoveEvents(enabled bool) {
+	c.mu.Lock()
+	c.unsafe.SetPurgeRemoveEvents(enabled)
+	c.mu.Unlock()
+}
+
+func (c *cache) SetDeterministic(enabled bool) {
+	c.mu.Lock()
+	c.unsafe.SetDeterministic(enabled)
+	if enabled && c.rand == nil {
+		c.rand = rand.New(rand.NewSource(internal.DeterministicSeed)) //nolint:gosec
+	}
+	c.mu.Unlock()
+}
+
+func (c *cache) SetUpdateRefreshesTTL(refresh bool) {
+	c.mu.Lock()
+	c.unsafe.SetUpdateRefreshesTTL(refresh)
+	c.mu.Unlock()
+}
+
 func (c *cache) RegisterOnEvicted(f func(key, value interface{})) {
 	c.mu.Lock()
 	c.unsafe.RegisterOnEvicted(f)
@@ -248,14 +1418,77 @@ func (c *cache) RegisterOnExpired(f func(key, value interface{})) {
 
 func (c *cache) Notify(ch chan<- Event, ops ...Op) {
 	c.mu.Lock()
+	c.unforward(ch)
 	c.unsafe.Notify(ch, ops...)
 	c.mu.Unlock()
 }
 
+// NotifyFunc causes cache to call fn synchronously for each event of the provided ops,
+// while holding the cache lock, so fn must not call the cache.
+// It has no effect if the unsafe cache does not support it.
+func (c *cache) NotifyFunc(fn func(Event), ops ...Op) {
+	c.mu.Lock()
+	if n, ok := c.unsafe.(funcNotifier); ok {
+		n.NotifyFunc(fn, ops...)
+	}
+	c.mu.Unlock()
+}
+
+func (c *cache) NotifyBlocking(ch chan<- Event, ops ...Op) {
+	c.mu.Lock()
+	c.forward(ch, ops...)
+	c.mu.Unlock()
+}
+
+func (c *cache) NotifyWithReplay(ch chan<- Event, ops ...Op) {
+	c.mu.Lock()
+	c.unsafe.NotifyWithReplay(ch, ops...)
+	c.mu.Unlock()
+}
+
+func (c *cache) Subscribe(ops ...Op) (<-chan Event, func()) {
+	c.mu.Lock()
+	ch, cancel := c.unsafe.Subscribe(ops...)
+	c.mu.Unlock()
+
+	return ch, func() {
+		c.mu.Lock()
+		cancel()
+		c.mu.Unlock()
+	}
+}
+
+func (c *cache) Watch(key interface{}) (<-chan Event, func()) {
+	c.mu.Lock()
+	ch, cancel := c.unsafe.Watch(key)
+	c.mu.Unlock()
+
+	return ch, func() {
+		c.mu.Lock()
+		cancel()
+		c.mu.Unlock()
+	}
+}
+
 func (c *cache) Ignore(ch chan<- Event, ops ...Op) {
 	c.mu.Lock()
-	c.unsafe.Ignore(ch, ops...)
+	switch f, ok := c.forwarders[ch]; {
+	case ok && len(ops) == 0:
+		delete(c.listeners, ch)
+		c.unforward(ch)
+	case ok:
+		c.unsafe.Ignore(f.in, ops...)
+	default:
+		c.unsafe.Ignore(ch, ops...)
+	}
+	c.mu.Unlock()
+}
+
+func (c *cache) GetEntry(key interface{}) (EntryInfo, bool) {
+	c.mu.Lock()
+	info, ok := c.unsafe.GetEntry(key)
 	c.mu.Unlock()
+	return info, ok
 }
 
 func (c *cache) Expiry(key interface{}) (time.Time, bool) {
@@ -265,9 +1498,67 @@ func (c *cache) Expiry(key interface{}) (time.Time, bool) {
 	return exp, ok
 }
 
+func (c *cache) Touch(key interface{}, ttl time.Duration) bool {
+	c.mu.Lock()
+	ok := c.unsafe.Touch(key, ttl)
+	c.mu.Unlock()
+	return ok
+}
+
+func (c *cache) RefreshAllTTL(ttl time.Duration) {
+	c.mu.Lock()
+	c.unsafe.RefreshAllTTL(ttl)
+	c.mu.Unlock()
+}
+
+func (c *cache) ClearTTL() {
+	c.mu.Lock()
+	c.unsafe.ClearTTL()
+	c.mu.Unlock()
+}
+
+func (c *cache) SetExpiry(key interface{}, t time.Time) bool {
+	c.mu.Lock()
+	ok := c.unsafe.SetExpiry(key, t)
+	c.mu.Unlock()
+	return ok
+}
+
+func (c *cache) RemainingTTL(key interface{}) (time.Duration, bool) {
+	c.mu.Lock()
+	ttl, ok := c.unsafe.RemainingTTL(key)
+	c.mu.Unlock()
+	return ttl, ok
+}
+
 func (c *cache) GC() time.Duration {
 	c.mu.Lock()
 	dur := c.unsafe.GC()
 	c.mu.Unlock()
 	return dur
 }
+
+func (c *cache) Close() error {
+	c.mu.Lock()
+	if !c.closed() {
+		close(c.done)
+	}
+	c.stopForwarders()
+	err := c.unsafe.Close()
+	c.mu.Unlock()
+	return err
+}
+
+// Frequency returns the key access frequency,
+// if the underlying cache implements Frequencyer.
+// Otherwise, it reports zero frequency for existing keys, like LRU.
+func (c *cache) Frequency(key interface{}) (n int, ok bool) {
+	c.mu.Lock()
+	if f, isFreq := c.unsafe.(Frequencyer); isFreq {
+		n, ok = f.Frequency(key)
+	} else {
+		ok = c.unsafe.Contains(key)
+	}
+	c.mu.Unlock()
+	return n, ok
+}
@@ -1,9 +1,17 @@
 package libcache_test
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"math"
 	"math/rand"
+	"runtime"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -11,13 +19,38 @@ import (
 
 	"github.com/shaj13/libcache"
 	_ "github.com/shaj13/libcache/arc"
-	_ "github.com/shaj13/libcache/fifo"
+	"github.com/shaj13/libcache/fifo"
+	_ "github.com/shaj13/libcache/gdsf"
+	"github.com/shaj13/libcache/internal"
 	_ "github.com/shaj13/libcache/lfu"
 	_ "github.com/shaj13/libcache/lifo"
 	_ "github.com/shaj13/libcache/lru"
+	_ "github.com/shaj13/libcache/lruk"
 	_ "github.com/shaj13/libcache/mru"
 )
 
+// fakeClock is a manually advanced clock.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Now()}
+}
+
+func (f *fakeClock) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *fakeClock) Advance(d time.Duration) {
+	f.mu.Lock()
+	f.now = f.now.Add(d)
+	f.mu.Unlock()
+}
+
 var cacheTests = []struct {
 	cont          libcache.ReplacementPolicy
 	evictedKey    interface{}
@@ -26,7 +59,7 @@ var cacheTests = []struct {
 	{
 		cont:          libcache.LFU,
 		evictedKey:    1,
-		onEvictedKeys: []interface{}{0, 19},
+		onEvictedKeys: []interface{}{0, 1},
 	},
 	{
 		cont:          libcache.LRU,
@@ -53,6 +86,28 @@ var cacheTests = []struct {
 		evictedKey:    1,
 		onEvictedKeys: []interface{}{0, 1},
 	},
+	{
+		cont:          libcache.LRUK,
+		evictedKey:    1,
+		onEvictedKeys: []interface{}{0, 1},
+	},
+	{
+		cont:          libcache.GDSF,
+		evictedKey:    1,
+		onEvictedKeys: []interface{}{0, 1},
+	},
+}
+
+func TestBuiltinIDLE(t *testing.T) {
+	// The idle package is not imported by the tests,
+	// IDLE registered by libcache itself.
+	assert.True(t, libcache.IDLE.Available())
+
+	cache := libcache.IDLE.New(0)
+	cache.Store(1, 1)
+	_, ok := cache.Load(1)
+	assert.False(t, ok)
+	assert.Equal(t, 0, cache.Len())
 }
 
 func TestCacheStore(t *testing.T) {
@@ -78,6 +133,158 @@ func TestCacheStoreWithTTL(t *testing.T) {
 	}
 }
 
+func TestCacheStoreWithDeadline(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheStoreWithDeadline", func(t *testing.T) {
+			clock := newFakeClock()
+			cache := tt.cont.New(0)
+			cache.SetClock(clock)
+
+			deadline := clock.Now().Add(time.Millisecond * 100)
+			cache.StoreWithDeadline(1, 1, deadline)
+
+			exp, ok := cache.Expiry(1)
+			assert.True(t, ok)
+			assert.Equal(t, deadline.UTC(), exp)
+
+			clock.Advance(time.Millisecond * 99)
+			assert.True(t, cache.Contains(1))
+
+			clock.Advance(time.Millisecond)
+			assert.False(t, cache.Contains(1))
+
+			// deadline in the past collected by the next operation.
+			cache.StoreWithDeadline(2, 2, clock.Now().Add(-time.Second))
+			assert.False(t, cache.Contains(2))
+		})
+	}
+}
+
+func TestCacheStoreWithTTLJitter(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheStoreWithTTLJitter", func(t *testing.T) {
+			clock := newFakeClock()
+			newCache := func() libcache.Cache {
+				cache := tt.cont.New(0)
+				cache.SetClock(clock)
+				cache.SetDeterministic(true)
+				cache.SetJitter(time.Minute)
+				cache.StoreWithTTL(1, 1, time.Hour)
+				cache.StoreWithTTLJitter(2, 2, time.Millisecond, time.Hour)
+				return cache
+			}
+
+			cache := newCache()
+			now := clock.Now()
+
+			got, ok := cache.Expiry(1)
+			assert.True(t, ok)
+			assert.False(t, got.Before(now.Add(time.Hour-time.Minute)))
+			assert.False(t, got.After(now.Add(time.Hour+time.Minute)))
+
+			// The jitter clamped below the ttl, so the key expires within (0, 2*ttl).
+			got, ok = cache.Expiry(2)
+			assert.True(t, ok)
+			assert.True(t, got.After(now))
+			assert.True(t, got.Before(now.Add(time.Millisecond*2)))
+
+			// The deterministic cache seeds its random source, so the jitter reproducible.
+			other, _ := newCache().Expiry(2)
+			assert.Equal(t, got, other)
+
+			clock.Advance(time.Millisecond * 2)
+			assert.False(t, cache.Contains(2))
+		})
+	}
+}
+
+func TestCacheTouch(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheTouch", func(t *testing.T) {
+			cache := tt.cont.New(3)
+			cache.StoreWithTTL(1, 1, time.Millisecond*20)
+			cache.Store(2, 2)
+			cache.Store(3, 3)
+
+			assert.True(t, cache.Touch(1, time.Hour))
+			assert.False(t, cache.Touch(4, time.Hour))
+
+			time.Sleep(time.Millisecond * 30)
+
+			exp, ok := cache.Expiry(1)
+			assert.True(t, ok)
+			assert.WithinDuration(t, time.Now().Add(time.Hour), exp, time.Second)
+
+			cache.Store(4, 4)
+			assert.False(t, cache.Contains(tt.evictedKey), "Touch should not move element")
+		})
+	}
+}
+
+func TestCacheSetExpiry(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheSetExpiry", func(t *testing.T) {
+			clock := newFakeClock()
+			cache := tt.cont.New(0)
+			cache.SetClock(clock)
+			cache.StoreWithTTL(1, 1, time.Minute)
+			cache.StoreWithTTL(2, 2, time.Minute)
+			cache.Store(3, 3)
+
+			// move earlier.
+			assert.True(t, cache.SetExpiry(1, clock.Now().Add(time.Second)))
+			// move later.
+			assert.True(t, cache.SetExpiry(2, clock.Now().Add(time.Hour)))
+			// add to and remove from the expiring heap.
+			assert.True(t, cache.SetExpiry(3, clock.Now().Add(time.Second)))
+			assert.True(t, cache.SetExpiry(3, time.Time{}))
+			assert.False(t, cache.SetExpiry(4, clock.Now()))
+
+			clock.Advance(time.Second)
+			cache.GC()
+			assert.False(t, cache.Contains(1))
+			assert.False(t, cache.SetExpiry(1, clock.Now().Add(time.Hour)))
+
+			clock.Advance(time.Minute)
+			cache.GC()
+			assert.True(t, cache.Contains(2))
+			assert.True(t, cache.Contains(3))
+
+			clock.Advance(time.Hour)
+			cache.GC()
+			assert.False(t, cache.Contains(2))
+			assert.True(t, cache.Contains(3))
+		})
+	}
+}
+
+func TestCacheRemainingTTL(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheRemainingTTL", func(t *testing.T) {
+			cache := tt.cont.New(0)
+			cache.Store(1, 1)
+			cache.StoreWithTTL(2, 2, time.Hour)
+			cache.StoreWithTTL(3, 3, time.Nanosecond)
+
+			ttl, ok := cache.RemainingTTL(1)
+			assert.True(t, ok)
+			assert.Zero(t, ttl)
+
+			ttl, ok = cache.RemainingTTL(2)
+			assert.True(t, ok)
+			assert.InDelta(t, int64(time.Hour), int64(ttl), float64(time.Second))
+
+			ttl, ok = cache.RemainingTTL(3)
+			assert.False(t, ok)
+			assert.Zero(t, ttl)
+
+			ttl, ok = cache.RemainingTTL(4)
+			assert.False(t, ok)
+			assert.Zero(t, ttl)
+		})
+	}
+}
+
 func TestCacheLoad(t *testing.T) {
 	for _, tt := range cacheTests {
 		t.Run("Test"+tt.cont.String()+"CacheLoad", func(t *testing.T) {
@@ -101,6 +308,46 @@ func TestCacheDelete(t *testing.T) {
 	}
 }
 
+func TestCacheMany(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheMany", func(t *testing.T) {
+			cache := tt.cont.New(0)
+			cache.StoreMany(map[interface{}]interface{}{1: 1, 2: 2, 3: 3})
+			assert.Equal(t, 3, cache.Len())
+
+			got := cache.LoadMany(1, 2, 4)
+			assert.Equal(t, map[interface{}]interface{}{1: 1, 2: 2}, got)
+
+			cache.DeleteMany(1, 2)
+			assert.ElementsMatch(t, []interface{}{3}, cache.Keys())
+		})
+	}
+}
+
+func TestCacheGetAndDelete(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheGetAndDelete", func(t *testing.T) {
+			cache := tt.cont.New(0)
+			cache.Store(1, 1)
+
+			loaded := make(chan bool, 2)
+			for i := 0; i < 2; i++ {
+				go func() {
+					_, ok := cache.GetAndDelete(1)
+					loaded <- ok
+				}()
+			}
+
+			assert.True(t, <-loaded != <-loaded, "Expected exactly one GetAndDelete success")
+			assert.False(t, cache.Contains(1))
+
+			v, ok := cache.GetAndDelete(1)
+			assert.Nil(t, v)
+			assert.False(t, ok)
+		})
+	}
+}
+
 func TestCachePeek(t *testing.T) {
 	for _, tt := range cacheTests {
 		t.Run("Test"+tt.cont.String()+"CachePeek", func(t *testing.T) {
@@ -134,6 +381,43 @@ func TestCacheContains(t *testing.T) {
 	}
 }
 
+func TestCacheTest(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheTest", func(t *testing.T) {
+			cache := tt.cont.New(0)
+			cache.Store(1, 1)
+			pred := func(v interface{}) bool { return v.(int) > 0 }
+
+			ok, found := cache.Test(1, pred)
+			assert.True(t, ok)
+			assert.True(t, found)
+
+			cache.Update(1, 0)
+			ok, found = cache.Test(1, pred)
+			assert.False(t, ok)
+			assert.True(t, found)
+
+			ok, found = cache.Test(2, pred)
+			assert.False(t, ok)
+			assert.False(t, found)
+		})
+	}
+}
+
+func TestCacheContainsMany(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheContainsMany", func(t *testing.T) {
+			cache := tt.cont.New(0)
+			cache.Store(1, 0)
+			cache.Store(3, 0)
+			cache.StoreWithTTL(4, 0, time.Nanosecond)
+
+			got := cache.ContainsMany([]interface{}{1, 2, 3, 4})
+			assert.Equal(t, []bool{true, false, true, false}, got)
+		})
+	}
+}
+
 func TestCacheUpdate(t *testing.T) {
 	for _, tt := range cacheTests {
 		t.Run("Test"+tt.cont.String()+"CacheUpdate", func(t *testing.T) {
@@ -152,212 +436,3894 @@ func TestCacheUpdate(t *testing.T) {
 	}
 }
 
-func TestCachePurge(t *testing.T) {
+func TestCacheCompareAndSwap(t *testing.T) {
 	for _, tt := range cacheTests {
-		t.Run("Test"+tt.cont.String()+"CachePurge", func(t *testing.T) {
-			cache := tt.cont.New(0)
+		t.Run("Test"+tt.cont.String()+"CacheCompareAndSwap", func(t *testing.T) {
+			cache := tt.cont.New(3)
 			cache.Store(1, 0)
 			cache.Store(2, 0)
-			cache.Store(3, 0)
-			cache.Purge()
+			cache.Store(3, []int{})
 
-			assert.Equal(t, 0, cache.Len())
+			assert.False(t, cache.CompareAndSwap(1, 1, 2))
+			assert.True(t, cache.CompareAndSwap(1, 0, 1))
+			assert.False(t, cache.CompareAndSwap(3, []int{}, 1))
+			assert.False(t, cache.CompareAndSwap(4, nil, 1))
+
+			v, _ := cache.Peek(1)
+			cache.Store(4, 0)
+			found := cache.Contains(tt.evictedKey)
+			assert.Equal(t, 1, v)
+			assert.False(t, found, "CompareAndSwap should not move element")
 		})
 	}
 }
 
-func TestCacheResize(t *testing.T) {
+func TestCacheCompareAndDelete(t *testing.T) {
 	for _, tt := range cacheTests {
-		t.Run("Test"+tt.cont.String()+"CacheResize", func(t *testing.T) {
+		t.Run("Test"+tt.cont.String()+"CacheCompareAndDelete", func(t *testing.T) {
 			cache := tt.cont.New(0)
 			cache.Store(1, 0)
-			cache.Store(2, 0)
-			cache.Store(3, 0)
-			cache.Resize(2)
-			assert.Equal(t, 2, cache.Len())
+			cache.Store(2, []int{})
+
+			assert.False(t, cache.CompareAndDelete(1, 1))
+			assert.True(t, cache.Contains(1))
+			assert.True(t, cache.CompareAndDelete(1, 0))
+			assert.False(t, cache.Contains(1))
+			assert.False(t, cache.CompareAndDelete(2, []int{}))
+			assert.True(t, cache.Contains(2))
 		})
 	}
 }
 
-func TestCacheKeys(t *testing.T) {
+func TestCacheIncrement(t *testing.T) {
 	for _, tt := range cacheTests {
-		t.Run("Test"+tt.cont.String()+"CacheKeys", func(t *testing.T) {
+		t.Run("Test"+tt.cont.String()+"CacheIncrement", func(t *testing.T) {
 			cache := tt.cont.New(0)
+			cache.StoreWithTTL(2, int64(1), time.Hour)
+			cache.Store(3, 1)
+			exp, _ := cache.Expiry(2)
+
+			n, err := cache.Increment(1, 5)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(5), n)
+
+			n, err = cache.Decrement(1, 2)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(3), n)
+
+			n, err = cache.Increment(2, 1)
+			got, _ := cache.Expiry(2)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(2), n)
+			assert.Equal(t, exp, got)
+
+			_, err = cache.Increment(3, 1)
+			v, _ := cache.Peek(3)
+			assert.Equal(t, libcache.ErrNotInt64, err)
+			assert.Equal(t, 1, v)
+		})
+	}
+}
+
+func TestCachePin(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CachePin", func(t *testing.T) {
+			cache := tt.cont.New(3)
 			cache.Store(1, 0)
 			cache.Store(2, 0)
 			cache.Store(3, 0)
-			assert.ElementsMatch(t, []interface{}{1, 2, 3}, cache.Keys())
+
+			assert.True(t, cache.Pin(tt.evictedKey))
+			assert.False(t, cache.Pin(4))
+
+			cache.Store(4, 0)
+			cache.Store(tt.evictedKey, 1)
+			assert.True(t, cache.Contains(tt.evictedKey))
+			assert.Equal(t, 3, cache.Len())
+
+			for _, k := range cache.Keys() {
+				cache.Pin(k)
+			}
+
+			cache.Store(5, 0)
+			assert.Equal(t, 4, cache.Len())
+
+			for _, k := range cache.Keys() {
+				cache.Unpin(k)
+			}
+
+			assert.False(t, cache.Unpin(tt.evictedKey))
 		})
 	}
 }
 
-func TestCacheCap(t *testing.T) {
+func TestCachePinKeepsFrequency(t *testing.T) {
+	cache := libcache.LFU.New(2)
+	cache.Store(1, 1)
+	cache.Store(2, 2)
+	for i := 0; i < 3; i++ {
+		cache.Load(1)
+	}
+
+	assert.True(t, cache.Pin(1))
+	cache.Load(1)
+	k, _, _ := cache.PeekOldest()
+	assert.Equal(t, 2, k)
+
+	assert.True(t, cache.Unpin(1))
+	n, _ := cache.(libcache.Frequencyer).Frequency(1)
+	assert.Equal(t, 4, n)
+
+	// The unpinned key keeps its frequency, so the new key evicts 2.
+	cache.Store(3, 3)
+	assert.True(t, cache.Contains(1))
+	assert.False(t, cache.Contains(2))
+}
+
+func TestCachePinExpiring(t *testing.T) {
 	for _, tt := range cacheTests {
-		t.Run("Test"+tt.cont.String()+"CacheCap", func(t *testing.T) {
-			cache := tt.cont.New(3)
-			assert.Equal(t, 3, cache.Cap())
+		t.Run("Test"+tt.cont.String()+"CachePinExpiring", func(t *testing.T) {
+			cache := tt.cont.New(0)
+			cache.StoreWithTTL(1, 0, time.Millisecond)
+			cache.Pin(1)
+
+			time.Sleep(time.Millisecond)
+			assert.False(t, cache.Contains(1))
+			assert.False(t, cache.Unpin(1))
 		})
 	}
 }
 
-func TestCacheTTL(t *testing.T) {
+func TestCachePurge(t *testing.T) {
 	for _, tt := range cacheTests {
-		t.Run("Test"+tt.cont.String()+"CacheTTL", func(t *testing.T) {
+		t.Run("Test"+tt.cont.String()+"CachePurge", func(t *testing.T) {
 			cache := tt.cont.New(0)
-			cache.SetTTL(time.Second)
-			assert.Equal(t, time.Second, cache.TTL())
+			cache.Store(1, 0)
+			cache.Store(2, 0)
+			cache.Store(3, 0)
+			cache.Purge()
+
+			assert.Equal(t, 0, cache.Len())
 		})
 	}
 }
 
-func TestOnEvicted(t *testing.T) {
+func TestCachePurgeEvent(t *testing.T) {
 	for _, tt := range cacheTests {
-		t.Run("Test"+tt.cont.String()+"CacheOnEvicted", func(t *testing.T) {
-			cache := tt.cont.New(20)
-			send := make(chan libcache.Event, 10)
-			done := make(chan bool)
-			evictedKeys := make([]interface{}, 0, 2)
-			cache.Notify(send, libcache.Remove)
+		t.Run("Test"+tt.cont.String()+"CachePurgeEvent", func(t *testing.T) {
+			for _, n := range []int{0, 10, 100} {
+				c := make(chan libcache.Event, 200)
+				cache := tt.cont.New(0)
+				cache.Notify(c, libcache.Remove, libcache.Purge)
+				w, _ := cache.Watch(1)
 
-			go func() {
-				for {
-					e := <-send
-					evictedKeys = append(evictedKeys, e.Key)
-					if len(evictedKeys) >= 2 {
-						done <- true
-						return
-					}
+				for i := 0; i < n; i++ {
+					cache.Store(i, i)
 				}
-			}()
+				cache.Purge()
 
-			for i := 0; i < 22; i++ {
-				cache.Store(i, i)
+				assert.Len(t, c, 1)
+				e := <-c
+				assert.Equal(t, libcache.Purge, e.Op)
+				assert.Nil(t, e.Key)
+
+				// the key watchers notified by the Purge event too.
+				for len(w) > 1 {
+					<-w
+				}
+				assert.Equal(t, libcache.Purge, (<-w).Op)
 			}
 
-			select {
-			case <-done:
-			case <-time.After(time.Second * 2):
-				t.Fatal("TestOnEvicted timeout exceeded, expected to receive evicted keys")
+			// per-key events opt in.
+			c := make(chan libcache.Event, 200)
+			cache := tt.cont.NewWithOptions(0, libcache.WithPurgeRemoveEvents(true))
+			cache.Notify(c, libcache.Remove, libcache.Purge)
+			for i := 0; i < 100; i++ {
+				cache.Store(i, i)
 			}
+			cache.Purge()
 
-			assert.ElementsMatch(t, tt.onEvictedKeys, evictedKeys)
+			assert.Len(t, c, 100)
+			for len(c) > 0 {
+				assert.Equal(t, libcache.Remove, (<-c).Op)
+			}
 		})
 	}
 }
 
-func TestExpiring(t *testing.T) {
+func TestCacheReset(t *testing.T) {
 	for _, tt := range cacheTests {
-		t.Run("Test"+tt.cont.String()+"CacheExpiring", func(t *testing.T) {
-			cache := tt.cont.New(0)
-			keys := make([]interface{}, 10)
-			for i := 0; i < 10; i++ {
-				cache.StoreWithTTL(fmt.Sprintf("%v.100", i), i, time.Millisecond*100)
-				cache.StoreWithTTL(fmt.Sprintf("%v.200", i), i, time.Millisecond*200)
-				keys[i] = fmt.Sprintf("%v.200", i)
+		t.Run("Test"+tt.cont.String()+"CacheReset", func(t *testing.T) {
+			c := make(chan libcache.Event, 10)
+			cache := tt.cont.New(3)
+			cache.SetTTL(time.Hour)
+			cache.Resize(5)
+			cache.Notify(c)
+			cache.Store(1, 1)
+
+			cache.Reset()
+			for len(c) > 0 {
+				<-c
 			}
 
-			time.Sleep(time.Millisecond * 100)
+			cache.Store(2, 2)
 
-			cache.Peek("notfound") // should expire *.100
-			got := cache.Keys()
-			assert.ElementsMatch(t, keys, got)
+			exp, _ := cache.Expiry(2)
+			assert.Equal(t, 1, cache.Len())
+			assert.Equal(t, 3, cache.Cap())
+			assert.Zero(t, cache.TTL())
+			assert.Zero(t, exp)
+			assert.Len(t, c, 0)
+		})
+	}
+}
 
-			time.Sleep(time.Millisecond * 100)
-			cache.Store("notfound", 0) // should expire *.200
-			got = cache.Keys()
-			assert.ElementsMatch(t, []string{"notfound"}, got)
+func TestCacheResetKeepsOptions(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheResetKeepsOptions", func(t *testing.T) {
+			clock := newFakeClock()
+			cache := tt.cont.NewWithOptions(
+				3,
+				libcache.WithTTL(time.Minute),
+				libcache.WithCapacity(5),
+				libcache.WithClock(clock),
+			)
+			cache.SetTTL(time.Hour)
+			cache.Store(1, 1)
 
-			cache.Purge()
+			cache.Reset()
+			cache.Store(2, 2)
 
-			// check remove element will keep other entries in heap.
-			// this has been added to make sure we remove right entry
-			// by its index.
-			cache.StoreWithTTL(1, 1, time.Millisecond*100)
-			cache.StoreWithTTL(2, 2, time.Millisecond*200)
+			// The options re-applied, while the setters configuration dropped.
+			exp, ok := cache.Expiry(2)
+			assert.True(t, ok)
+			assert.Equal(t, clock.Now().UTC().Add(time.Minute), exp)
+			assert.Equal(t, time.Minute, cache.TTL())
+			assert.Equal(t, 5, cache.Cap())
 
-			cache.Delete(2)
-			got = cache.Keys()
+			clock.Advance(time.Minute)
+			assert.False(t, cache.Contains(2))
+		})
+	}
+}
+
+func TestCacheResetClosesSubscriptions(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheResetClosesSubscriptions", func(t *testing.T) {
+			cache := tt.cont.New(1)
+			sub, _ := cache.Subscribe(libcache.Remove)
+			watch, _ := cache.Watch(1)
+
+			cache.Reset()
+			cache.Store(1, 1)
+			cache.Store(2, 2)
+
+			_, ok := <-sub
+			assert.False(t, ok)
+			_, ok = <-watch
+			assert.False(t, ok)
+
+			// Subscribing again after Reset receives the evictions.
+			sub, cancel := cache.Subscribe(libcache.Remove)
+			defer cancel()
+			cache.Store(3, 3)
+			e := <-sub
+			assert.Equal(t, 2, e.Key)
+		})
+	}
+}
+
+func TestNewWithJanitorReset(t *testing.T) {
+	cache := libcache.LRU.NewWithJanitor(0, 0)
+	defer cache.Close()
+
+	cache.Reset()
+	cache.StoreWithTTL(1, 1, time.Millisecond*10)
+
+	// The janitor subscribes again after Reset, and collects the entry on time.
+	assert.Eventually(t, func() bool {
+		return cache.Len() == 0
+	}, time.Second, time.Millisecond*5)
+}
+
+func TestCacheResize(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheResize", func(t *testing.T) {
+			cache := tt.cont.New(0)
+			cache.Store(1, 0)
+			cache.Store(2, 0)
+			cache.Store(3, 0)
+			cache.Resize(2)
+			assert.Equal(t, 2, cache.Len())
+		})
+	}
+}
+
+func TestCacheResizeShrink(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheResizeShrink", func(t *testing.T) {
+			c := make(chan libcache.Event, 10)
+			cache := tt.cont.New(10)
+			cache.Notify(c, libcache.Remove)
+
+			for i := 0; i < 10; i++ {
+				cache.Store(i, i)
+			}
+			for i := 0; i < 5; i++ {
+				cache.Load(i)
+			}
+
+			assert.Equal(t, 6, cache.Resize(4))
+			assert.Equal(t, 4, cache.Len())
+			assert.Equal(t, 4, cache.Cap())
+			assert.Len(t, c, 6)
+
+			// Zero size means unbounded, and never evicts.
+			assert.Equal(t, 0, cache.Resize(0))
+			assert.Equal(t, 4, cache.Len())
+		})
+	}
+}
+
+func TestCacheNegativeWrites(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheNegativeWrites", func(t *testing.T) {
+			cache := tt.cont.New(0)
+			cache.StoreNegative(1, time.Hour)
+
+			cache.Update(1, 1)
+			assert.False(t, cache.Contains(1))
+			assert.False(t, cache.CompareAndSwap(1, nil, 1))
+			assert.False(t, cache.CompareAndDelete(1, nil))
+			assert.False(t, cache.Touch(1, time.Minute))
+			assert.False(t, cache.SetExpiry(1, time.Now().Add(time.Minute)))
+			assert.False(t, cache.Contains(1))
+
+			n, err := cache.Increment(1, 2)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(2), n)
+			v, ok := cache.Load(1)
+			assert.True(t, ok)
+			assert.Equal(t, int64(2), v)
+		})
+	}
+}
+
+func TestCacheKeys(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheKeys", func(t *testing.T) {
+			cache := tt.cont.New(0)
+			cache.Store(1, 0)
+			cache.Store(2, 0)
+			cache.Store(3, 0)
+			assert.ElementsMatch(t, []interface{}{1, 2, 3}, cache.Keys())
+		})
+	}
+}
+
+func TestCacheInvalidateTag(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheInvalidateTag", func(t *testing.T) {
+			c := make(chan libcache.Event, 10)
+			cache := tt.cont.New(0)
+			cache.Notify(c, libcache.Remove)
+
+			cache.StoreWithTags(1, 1, "user:1", "org:1")
+			cache.StoreWithTags(2, 2, "user:2", "org:1")
+			cache.StoreWithTags(3, 3, "user:3", "org:2")
+			cache.StoreWithTags(4, 4, "user:1")
+			cache.Load(1)
+
+			// retagged on re-store.
+			cache.StoreWithTags(4, 4, "user:4")
+
+			assert.Equal(t, 1, cache.InvalidateTag("user:1"))
+			assert.ElementsMatch(t, []interface{}{2, 3, 4}, cache.Keys())
+
+			// deleted keys removed from their tags.
+			cache.Delete(2)
+			assert.Equal(t, 0, cache.InvalidateTag("org:1"))
+			assert.Equal(t, 0, cache.InvalidateTag("user:2"))
+
+			assert.Equal(t, 1, cache.InvalidateTag("org:2"))
+			assert.Equal(t, 1, cache.InvalidateTag("user:4"))
+			assert.Equal(t, 0, cache.Len())
+			assert.Len(t, c, 4)
+		})
+	}
+}
+
+func TestCacheKeysWithPrefix(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheKeysWithPrefix", func(t *testing.T) {
+			c := make(chan libcache.Event, 10)
+			cache := tt.cont.New(0)
+			cache.Store("user:1:profile", 0)
+			cache.Store("user:1:settings", 0)
+			cache.Store("user:2:profile", 0)
+			cache.Store(1, 0)
+			cache.Store(2, 0)
+			cache.Load("user:1:profile")
+			cache.Notify(c, libcache.Remove)
+
+			got := cache.KeysWithPrefix("user:1:")
+			assert.ElementsMatch(t, []interface{}{"user:1:profile", "user:1:settings"}, got)
+			assert.Len(t, cache.KeysWithPrefix(""), 3)
+
+			assert.Equal(t, 2, cache.DeleteWithPrefix("user:1:"))
+			assert.Equal(t, 0, cache.DeleteWithPrefix("user:1:"))
+			assert.ElementsMatch(t, []interface{}{"user:2:profile", 1, 2}, cache.Keys())
+			assert.Len(t, c, 2)
+		})
+	}
+}
+
+func TestCacheIterator(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheIterator", func(t *testing.T) {
+			clock := newFakeClock()
+			cache := tt.cont.NewWithClock(0, clock)
+			cache.Store(1, 1)
+			cache.Load(1)
+			cache.Store(2, 2)
+			cache.StoreWithTTL(3, 3, time.Second)
+
+			it := cache.Iterator()
+
+			// mutations after the snapshot do not affect the iterator.
+			cache.Store(4, 4)
+			cache.Delete(2)
+			clock.Advance(time.Second)
+
+			got := map[interface{}]interface{}{}
+			for it.Next() {
+				got[it.Key()] = it.Value()
+			}
+
+			assert.Equal(t, map[interface{}]interface{}{1: 1, 2: 2}, got)
+			assert.False(t, it.Next())
+		})
+	}
+}
+
+func TestCacheClone(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheClone", func(t *testing.T) {
+			cache := tt.cont.New(3)
+			cache.SetTTL(time.Hour)
+			cache.Store(1, 1)
+			cache.Load(1)
+			cache.Store(2, 2)
+			cache.StoreWithTTL(3, 3, time.Nanosecond)
+			time.Sleep(time.Nanosecond)
+
+			assert.Equal(t, map[interface{}]interface{}{1: 1, 2: 2}, cache.Snapshot())
+
+			clone := cache.(libcache.Cloner).Clone()
+			assert.Equal(t, cache.Snapshot(), clone.Snapshot())
+			assert.Equal(t, 3, clone.Cap())
+			assert.Equal(t, time.Hour, clone.TTL())
+
+			exp, _ := cache.Expiry(1)
+			got, _ := clone.Expiry(1)
+			assert.WithinDuration(t, exp, got, time.Second)
+
+			clone.Store(1, 10)
+			clone.Delete(2)
+			clone.Store(4, 4)
+
+			assert.Equal(t, map[interface{}]interface{}{1: 1, 2: 2}, cache.Snapshot())
+			assert.Equal(t, map[interface{}]interface{}{1: 10, 4: 4}, clone.Snapshot())
+		})
+	}
+}
+
+func TestCacheCloneExpiry(t *testing.T) {
+	clock := newFakeClock()
+	cache := libcache.LRU.NewWithClock(0, clock)
+	cache.StoreWithTTL(1, 1, time.Minute)
+	cache.Store(2, 2)
+	cache.StoreNegative(3, time.Hour)
+
+	for _, clone := range []libcache.Cache{
+		cache.(libcache.Cloner).Clone(),
+		cache.(libcache.Cloner).Filter(func(key, value interface{}) bool {
+			return true
+		}),
+	} {
+		assert.ElementsMatch(t, []interface{}{1, 2}, clone.Keys())
+
+		exp, _ := cache.Expiry(1)
+		got, _ := clone.Expiry(1)
+		assert.Equal(t, exp, got)
+
+		got, _ = clone.Expiry(2)
+		assert.True(t, got.IsZero())
+
+		// The clone follows the cache clock.
+		clone.StoreWithTTL(4, 4, time.Minute)
+		got, _ = clone.Expiry(4)
+		assert.Equal(t, clock.Now().UTC().Add(time.Minute), got)
+	}
+
+	clock.Advance(time.Minute)
+	clone := cache.(libcache.Cloner).Clone()
+	assert.ElementsMatch(t, []interface{}{2}, clone.Keys())
+}
+
+func TestCacheCap(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheCap", func(t *testing.T) {
+			cache := tt.cont.New(3)
+			assert.Equal(t, 3, cache.Cap())
+		})
+	}
+}
+
+func TestCacheTTL(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheTTL", func(t *testing.T) {
+			cache := tt.cont.New(0)
+			cache.SetTTL(time.Second)
+			assert.Equal(t, time.Second, cache.TTL())
+		})
+	}
+}
+
+func TestCacheUpdateRefreshesTTL(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheUpdateRefreshesTTL", func(t *testing.T) {
+			cache := tt.cont.New(0)
+			cache.SetTTL(time.Hour)
+			cache.StoreWithTTL(1, 1, time.Minute)
+			exp, _ := cache.Expiry(1)
+
+			cache.Update(1, 2)
+			got, _ := cache.Expiry(1)
+			assert.Equal(t, exp, got)
+
+			cache.SetUpdateRefreshesTTL(true)
+			cache.Update(1, 3)
+			got, _ = cache.Expiry(1)
+			assert.WithinDuration(t, time.Now().Add(time.Hour), got, time.Second)
+		})
+	}
+}
+
+func TestOnEvicted(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheOnEvicted", func(t *testing.T) {
+			cache := tt.cont.New(20)
+			send := make(chan libcache.Event, 10)
+			done := make(chan bool)
+			evictedKeys := make([]interface{}, 0, 2)
+			cache.Notify(send, libcache.Remove)
+
+			go func() {
+				for {
+					e := <-send
+					evictedKeys = append(evictedKeys, e.Key)
+					if len(evictedKeys) >= 2 {
+						done <- true
+						return
+					}
+				}
+			}()
+
+			for i := 0; i < 22; i++ {
+				cache.Store(i, i)
+			}
+
+			select {
+			case <-done:
+			case <-time.After(time.Second * 2):
+				t.Fatal("TestOnEvicted timeout exceeded, expected to receive evicted keys")
+			}
+
+			assert.ElementsMatch(t, tt.onEvictedKeys, evictedKeys)
+		})
+	}
+}
+
+func TestExpiring(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheExpiring", func(t *testing.T) {
+			clock := newFakeClock()
+			cache := tt.cont.NewWithClock(0, clock)
+			keys := make([]interface{}, 10)
+			for i := 0; i < 10; i++ {
+				cache.StoreWithTTL(fmt.Sprintf("%v.100", i), i, time.Millisecond*100)
+				cache.StoreWithTTL(fmt.Sprintf("%v.200", i), i, time.Millisecond*200)
+				keys[i] = fmt.Sprintf("%v.200", i)
+			}
+
+			clock.Advance(time.Millisecond * 100)
+
+			cache.Peek("notfound") // should expire *.100
+			got := cache.Keys()
+			assert.ElementsMatch(t, keys, got)
+
+			clock.Advance(time.Millisecond * 100)
+			cache.Store("notfound", 0) // should expire *.200
+			got = cache.Keys()
+			assert.ElementsMatch(t, []string{"notfound"}, got)
+
+			cache.Purge()
+
+			// check remove element will keep other entries in heap.
+			// this has been added to make sure we remove right entry
+			// by its index.
+			cache.StoreWithTTL(1, 1, time.Millisecond*100)
+			cache.StoreWithTTL(2, 2, time.Millisecond*200)
+
+			cache.Delete(2)
+			got = cache.Keys()
 			assert.ElementsMatch(t, []int{1}, got)
 
-			time.Sleep(time.Millisecond * 100)
-			cache.Peek("")
-			assert.Equal(t, 0, cache.Len())
+			clock.Advance(time.Millisecond * 100)
+			cache.Peek("")
+			assert.Equal(t, 0, cache.Len())
+
+		})
+	}
+}
+
+func TestNotify(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheNotify", func(t *testing.T) {
+			got := 0
+			olds := []interface{}{}
+			c := make(chan libcache.Event, 20)
+			cache := tt.cont.New(0)
+
+			cache.Notify(c)
+
+			cache.Load(1)
+			cache.StoreWithTTL(1, 0, time.Second)
+			cache.Peek(1)
+			cache.Update(1, 1)
+			cache.Delete(1)
+			close(c)
+
+			for e := range c {
+				t.Logf("Operation %s on Key %v \n", e.Op, e.Key)
+				got += e.Key.(int)
+				if e.HadOld {
+					olds = append(olds, e.Old)
+				}
+			}
+
+			assert.Equal(t, 5, got)
+
+			assert.Equal(t, []interface{}{0}, olds)
+
+			// check it will not try to write on chan after ignore
+			cache.Ignore(c)
+			for i := 0; i < 10; i++ {
+				cache.Store(i, i)
+			}
+		})
+	}
+}
+
+func TestNotifyExpire(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheNotifyExpire", func(t *testing.T) {
+			c := make(chan libcache.Event, 10)
+			cache := tt.cont.New(0)
+			cache.Notify(c, libcache.Remove, libcache.Expire)
+
+			cache.StoreWithTTL(1, 1, time.Nanosecond)
+			cache.Store(2, 2)
+			time.Sleep(time.Nanosecond)
+			cache.GC()
+			cache.Delete(2)
+
+			e := <-c
+			assert.Equal(t, libcache.Expire, e.Op)
+			assert.Equal(t, 1, e.Key)
+			assert.Equal(t, "EXPIRE", e.Op.String())
+
+			e = <-c
+			assert.Equal(t, libcache.Remove, e.Op)
+			assert.Equal(t, 2, e.Key)
+		})
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheSubscribe", func(t *testing.T) {
+			cache := tt.cont.New(0)
+			c, cancel := cache.Subscribe(libcache.Write)
+
+			cache.Store(1, 1)
+			e := <-c
+			assert.Equal(t, 1, e.Key)
+			assert.Equal(t, libcache.Write, e.Op)
+
+			cancel()
+			cancel()
+			cache.Store(2, 2)
+
+			_, ok := <-c
+			assert.False(t, ok)
+		})
+	}
+}
+
+func TestNotifyWithReplay(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheNotifyWithReplay", func(t *testing.T) {
+			c := make(chan libcache.Event, 10)
+			cache := tt.cont.New(0)
+			cache.Store(1, 1)
+			cache.Store(2, 2)
+			cache.Store(3, 3)
+			cache.Load(1)
+
+			cache.NotifyWithReplay(c, libcache.Write)
+			cache.Store(4, 4)
+
+			keys := []interface{}{}
+			for i := 0; i < 3; i++ {
+				e := <-c
+				assert.Equal(t, libcache.Write, e.Op)
+				assert.Equal(t, e.Key, e.Value)
+				keys = append(keys, e.Key)
+			}
+
+			assert.ElementsMatch(t, []interface{}{1, 2, 3}, keys)
+			assert.Equal(t, 4, (<-c).Key)
+		})
+	}
+}
+
+func TestWatch(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheWatch", func(t *testing.T) {
+			cache := tt.cont.New(0)
+			c1, cancel1 := cache.Watch("a")
+			c2, cancel2 := cache.Watch("a")
+
+			cache.Store("a", 1)
+			cache.Store("b", 1)
+			cache.Load("a")
+			cache.Delete("b")
+			cache.Delete("a")
+
+			for _, c := range []<-chan libcache.Event{c1, c2} {
+				ops := []libcache.Op{}
+				for len(c) > 0 {
+					e := <-c
+					assert.Equal(t, "a", e.Key)
+					ops = append(ops, e.Op)
+				}
+				assert.Equal(t, []libcache.Op{libcache.Write, libcache.Remove}, ops)
+			}
+
+			cancel1()
+			cancel1()
+			cache.Store("a", 2)
+
+			_, ok := <-c1
+			assert.False(t, ok)
+			assert.Equal(t, libcache.Write, (<-c2).Op)
+
+			cancel2()
+		})
+	}
+}
+
+func TestNotifyBlocking(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheNotifyBlocking", func(t *testing.T) {
+			c := make(chan libcache.Event)
+			cache := tt.cont.New(0)
+			cache.NotifyBlocking(c, libcache.Write)
+
+			go func() {
+				for i := 0; i < 10; i++ {
+					cache.Store(i, i)
+				}
+			}()
+
+			for i := 0; i < 10; i++ {
+				time.Sleep(time.Millisecond)
+				select {
+				case e := <-c:
+					assert.Equal(t, i, e.Key)
+				case <-time.After(time.Second):
+					t.Fatal("TestNotifyBlocking timeout exceeded, expected to receive all events")
+				}
+			}
+		})
+	}
+}
+
+func TestNotifyBlockingSlowReceiver(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheNotifyBlockingSlowReceiver", func(t *testing.T) {
+			c := make(chan libcache.Event)
+			cache := tt.cont.New(0)
+			cache.NotifyBlocking(c, libcache.Write)
+			defer cache.Close()
+
+			received := make(chan []interface{})
+			go func() {
+				keys := []interface{}{}
+				for i := 0; i < 200; i++ {
+					e := <-c
+					// The receiver is slow, and calls the cache.
+					time.Sleep(time.Microsecond * 100)
+					cache.Peek(e.Key)
+					keys = append(keys, e.Key)
+				}
+				received <- keys
+			}()
+
+			wg := sync.WaitGroup{}
+			for g := 0; g < 4; g++ {
+				wg.Add(1)
+				go func(g int) {
+					defer wg.Done()
+					for i := 0; i < 50; i++ {
+						cache.Store(g*50+i, i)
+					}
+				}(g)
+			}
+
+			stored := make(chan struct{})
+			go func() {
+				wg.Wait()
+				close(stored)
+			}()
+
+			select {
+			case <-stored:
+			case <-time.After(time.Second):
+				t.Fatal("expected stores to progress while the receiver is slow")
+			}
+
+			select {
+			case keys := <-received:
+				assert.Len(t, keys, 200)
+				// Events of each goroutine delivered in order.
+				last := map[int]int{}
+				for _, k := range keys {
+					g := k.(int) / 50
+					if prev, ok := last[g]; ok {
+						assert.True(t, prev < k.(int))
+					}
+					last[g] = k.(int)
+				}
+			case <-time.After(time.Second * 5):
+				t.Fatal("expected to receive all events")
+			}
+		})
+	}
+}
+
+func TestNotifyBlockingClosed(t *testing.T) {
+	goroutines := runtime.NumGoroutine()
+	c := make(chan libcache.Event, 1)
+	cache := libcache.LRU.New(0)
+	cache.Close()
+	cache.NotifyBlocking(c, libcache.Write)
+	cache.Store(1, 1)
+
+	assert.Len(t, c, 0)
+	// Eventually calls the condition from its own goroutine.
+	assert.Eventually(t, func() bool {
+		return runtime.NumGoroutine() <= goroutines+1
+	}, time.Second, time.Millisecond*5)
+}
+
+func TestNotifyEventsOrder(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheNotifyEventsOrder", func(t *testing.T) {
+			const n = 1000
+
+			cache := tt.cont.New(0)
+			defer cache.Close()
+
+			blocking := make(chan libcache.Event)
+			cache.NotifyBlocking(blocking, libcache.Write, libcache.Remove)
+			c, cancel := cache.Subscribe(libcache.Write, libcache.Remove)
+			defer cancel()
+
+			received := make(chan []libcache.Event)
+			go func() {
+				events := []libcache.Event{}
+				for i := 0; i < n*2; i++ {
+					events = append(events, <-blocking)
+				}
+				received <- events
+			}()
+
+			subscribed := []libcache.Event{}
+			for i := 0; i < n; i++ {
+				cache.Store(i, i)
+				cache.Delete(i)
+				for len(c) > 0 {
+					subscribed = append(subscribed, <-c)
+				}
+			}
+
+			var events []libcache.Event
+			select {
+			case events = <-received:
+			case <-time.After(time.Second * 5):
+				t.Fatal("expected to receive all events")
+			}
+
+			for _, events := range [][]libcache.Event{events, subscribed} {
+				assert.Len(t, events, n*2)
+				for i := 0; i < n; i++ {
+					assert.Equal(t, libcache.Write, events[i*2].Op)
+					assert.Equal(t, libcache.Remove, events[i*2+1].Op)
+					assert.Equal(t, i, events[i*2].Key)
+					assert.Equal(t, i, events[i*2+1].Key)
+				}
+			}
+		})
+	}
+}
+
+func TestCacheGC(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheGC", func(t *testing.T) {
+			cache := tt.cont.NewUnsafe(0)
+			cache.StoreWithTTL(0, 0, time.Nanosecond)
+			cache.StoreWithTTL(1, 1, time.Millisecond*100)
+			dur := cache.GC()
+
+			assert.GreaterOrEqual(t, int64(dur), int64(time.Millisecond*99))
+			time.Sleep(dur)
+
+			assert.Zero(t, int(cache.GC()))
+			assert.Zero(t, cache.Len())
+		})
+	}
+}
+
+func TestCacheClose(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheClose", func(t *testing.T) {
+			done := make(chan struct{})
+			cache := tt.cont.New(0)
+			c, _ := cache.Subscribe()
+			cache.Store(1, 1)
+
+			go func() {
+				libcache.GC(context.Background(), cache)
+				close(done)
+			}()
+
+			time.Sleep(time.Millisecond)
+			assert.NoError(t, cache.Close())
+
+			select {
+			case <-done:
+			case <-time.After(time.Second):
+				t.Fatal("TestCacheClose timeout exceeded, expected GC to return")
+			}
+
+			for range c {
+			}
+
+			cache.Store(2, 2)
+			_, ok := cache.Load(1)
+			assert.False(t, ok)
+			assert.Zero(t, cache.Len())
+
+			c, _ = cache.Subscribe()
+			_, ok = <-c
+			assert.False(t, ok)
+		})
+	}
+}
+
+func TestGC(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cache := libcache.LRU.New(0)
+	go libcache.GC(ctx, cache)
+
+	cache.StoreWithTTL(1, 1, time.Millisecond*100)
+	time.Sleep(time.Millisecond * 150)
+	assert.Zero(t, cache.Len())
+
+	cache.StoreWithTTL(1, 1, time.Millisecond*100)
+	cache.StoreWithTTL(2, 2, time.Millisecond*200)
+
+	time.Sleep(time.Millisecond * 150)
+	assert.Equal(t, 1, cache.Len())
+
+	time.Sleep(time.Millisecond * 150)
+	assert.Zero(t, cache.Len())
+}
+
+func TestSafeNew(t *testing.T) {
+	libcache.FIFO.Register(func(cap int) libcache.Cache {
+		panic("bad capacity")
+	})
+	defer libcache.FIFO.Register(fifo.New)
+
+	cache := libcache.SafeNew(libcache.FIFO, 1)
+	cache.Store(1, 1)
+
+	assert.NotNil(t, cache)
+	assert.False(t, cache.Contains(1))
+	assert.Zero(t, cache.Len())
+}
+
+func TestDumpRestore(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"DumpRestore", func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			clock := newFakeClock()
+			cache := tt.cont.New(0)
+			cache.SetClock(clock)
+			cache.Store(1, "1")
+			cache.StoreWithTTL(2, "2", time.Hour)
+			cache.StoreWithTTL(3, "3", time.Millisecond*50)
+			cache.StoreNegative(4, time.Hour)
+
+			err := libcache.Dump(cache, buf)
+			assert.NoError(t, err)
+			assert.Zero(t, cache.Metrics().Hits)
+
+			clock.Advance(time.Millisecond * 50)
+
+			restored := tt.cont.New(0)
+			restored.SetClock(clock)
+			err = libcache.Restore(restored, buf)
+			assert.NoError(t, err)
+
+			exp, _ := cache.Expiry(2)
+			got, _ := restored.Expiry(2)
+			v, _ := restored.Load(1)
+			ttl, _ := restored.RemainingTTL(2)
+
+			assert.ElementsMatch(t, []interface{}{1, 2}, restored.Keys())
+			assert.Equal(t, 2, restored.Len())
+			assert.Equal(t, "1", v)
+			assert.Equal(t, exp, got)
+			assert.Equal(t, time.Hour-time.Millisecond*50, ttl)
+		})
+	}
+}
+
+func TestDumpRestoreWithCodec(t *testing.T) {
+	table := []struct {
+		name  string
+		codec libcache.Codec
+	}{
+		{name: "Gob", codec: libcache.GobCodec{}},
+		{name: "JSON", codec: libcache.JSONCodec{}},
+	}
+
+	for _, tt := range table {
+		t.Run("Test"+tt.name+"Codec", func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			clock := newFakeClock()
+			cache := libcache.LRU.New(0)
+			cache.SetClock(clock)
+			cache.Store("1", "1")
+			cache.StoreWithTTL("2", "2", time.Hour)
+			cache.StoreWithTTL("3", "3", time.Millisecond)
+
+			err := libcache.DumpWithCodec(cache, buf, tt.codec)
+			assert.NoError(t, err)
+
+			clock.Advance(time.Millisecond)
+
+			restored := libcache.LRU.New(0)
+			restored.SetClock(clock)
+			err = libcache.RestoreWithCodec(restored, buf, tt.codec)
+			assert.NoError(t, err)
+
+			exp, _ := cache.Expiry("2")
+			got, _ := restored.Expiry("2")
+			v, _ := restored.Load("1")
+
+			assert.ElementsMatch(t, []interface{}{"1", "2"}, restored.Keys())
+			assert.Equal(t, "1", v)
+			assert.WithinDuration(t, exp, got, time.Millisecond)
+		})
+	}
+}
+
+func TestEntryInfoMarshaling(t *testing.T) {
+	table := []struct {
+		name      string
+		marshal   func(libcache.EntryInfo) ([]byte, error)
+		unmarshal func(*libcache.EntryInfo, []byte) error
+	}{
+		{
+			name:      "Binary",
+			marshal:   libcache.EntryInfo.MarshalBinary,
+			unmarshal: (*libcache.EntryInfo).UnmarshalBinary,
+		},
+		{
+			name:      "JSON",
+			marshal:   libcache.EntryInfo.MarshalJSON,
+			unmarshal: (*libcache.EntryInfo).UnmarshalJSON,
+		},
+	}
+
+	for _, tt := range table {
+		t.Run("Test"+tt.name+"EntryInfoMarshaling", func(t *testing.T) {
+			cache := libcache.LRU.New(0)
+			cache.StoreWithTTL("1", "1", time.Hour)
+			cache.Store("2", "2")
+
+			for _, k := range []interface{}{"1", "2"} {
+				info, _ := cache.GetEntry(k)
+				data, err := tt.marshal(info)
+				assert.NoError(t, err)
+
+				got := libcache.EntryInfo{}
+				err = tt.unmarshal(&got, data)
+				assert.NoError(t, err)
+				assert.Equal(t, info.Key, got.Key)
+				assert.Equal(t, info.Value, got.Value)
+				assert.True(t, info.Expiry.Equal(got.Expiry))
+				assert.True(t, got.Created.IsZero())
+			}
+
+			info, _ := cache.GetEntry("1")
+			assert.False(t, info.Expiry.IsZero())
+		})
+	}
+
+	err := json.Unmarshal([]byte(`{"Key":[1],"Value":1}`), new(libcache.EntryInfo))
+	assert.Error(t, err)
+}
+
+func TestJSONCodecNonComparableKey(t *testing.T) {
+	_, err := libcache.JSONCodec{}.Unmarshal([]byte(`[{"Key":[1],"Value":1}]`))
+	assert.Error(t, err)
+}
+
+// progressCache records the bytes read from the stream
+// by the time the first entry stored.
+type progressCache struct {
+	libcache.Cache
+	read  *int
+	first int
+}
+
+func (p *progressCache) StoreWithDeadline(key, value interface{}, deadline time.Time) {
+	if p.first == 0 {
+		p.first = *p.read
+	}
+	p.Cache.StoreWithDeadline(key, value, deadline)
+}
+
+// countingReader counts the bytes read from r.
+type countingReader struct {
+	r io.Reader
+	n int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += n
+	return n, err
+}
+
+// maxWriter records the largest write.
+type maxWriter struct {
+	bytes.Buffer
+	max int
+}
+
+func (m *maxWriter) Write(p []byte) (int, error) {
+	if len(p) > m.max {
+		m.max = len(p)
+	}
+	return m.Buffer.Write(p)
+}
+
+func TestDumpRestoreStream(t *testing.T) {
+	const n = 100000
+
+	clock := newFakeClock()
+	cache := libcache.LRU.New(0)
+	cache.SetClock(clock)
+	for i := 0; i < n; i++ {
+		cache.Store(i, i)
+	}
+	cache.StoreWithTTL(n, n, time.Millisecond)
+
+	w := new(maxWriter)
+	err := libcache.DumpStream(cache, w, 1000)
+	assert.NoError(t, err)
+
+	clock.Advance(time.Millisecond)
+
+	// Batches written as they filled, and read as they arrived.
+	total := w.Len()
+	assert.Less(t, w.max, total/50)
+
+	r := &countingReader{r: w}
+	restored := &progressCache{Cache: libcache.LRU.New(0), read: &r.n}
+	restored.SetClock(clock)
+	err = libcache.RestoreStream(restored, r)
+	assert.NoError(t, err)
+
+	// The wrapper does not expose its clock, so the expired entry collected once looked up.
+	assert.Less(t, restored.first, total/50)
+	assert.False(t, restored.Contains(n))
+	assert.Equal(t, n, restored.Len())
+
+	v, ok := restored.Load(n - 1)
+	assert.True(t, ok)
+	assert.Equal(t, n-1, v)
+}
+
+func TestRestoreStreamTruncated(t *testing.T) {
+	cache := libcache.LRU.New(0)
+	for i := 0; i < 10; i++ {
+		cache.Store(i, i)
+	}
+
+	buf := new(bytes.Buffer)
+	err := libcache.DumpStream(cache, buf, 5)
+	assert.NoError(t, err)
+
+	// Drop the terminator.
+	buf.Truncate(buf.Len() - 1)
+
+	restored := libcache.LRU.New(0)
+	err = libcache.RestoreStream(restored, buf)
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+	assert.Equal(t, 10, restored.Len())
+}
+
+func TestCacheSetMaxKeyBytes(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheSetMaxKeyBytes", func(t *testing.T) {
+			cache := tt.cont.NewWithOptions(10, libcache.WithMaxKeyBytes(100))
+
+			// long keys exceed the key bytes budget before the count limit.
+			for i := 0; i < 5; i++ {
+				cache.Store(fmt.Sprintf("%030d", i), i)
+			}
+			assert.Equal(t, 3, cache.Len())
+			assert.True(t, cache.Contains(fmt.Sprintf("%030d", 4)))
+
+			// short keys exceed the count limit before the key bytes budget.
+			cache.Purge()
+			for i := 0; i < 20; i++ {
+				cache.Store(fmt.Sprint(i), i)
+			}
+			assert.Equal(t, 10, cache.Len())
+
+			// non string keys count a fixed overhead.
+			cache.Purge()
+			for i := 0; i < 10; i++ {
+				cache.Store(i, i)
+			}
+			assert.Equal(t, 6, cache.Len())
+
+			// lowering the budget below every key evicts them all.
+			cache.SetMaxKeyBytes(1)
+			assert.Equal(t, 0, cache.Len())
+
+			cache.SetMaxKeyBytes(0)
+			for i := 0; i < 10; i++ {
+				cache.Store(fmt.Sprintf("%030d", i), i)
+			}
+			assert.Equal(t, 10, cache.Len())
+		})
+	}
+}
+
+func TestCacheResizeCost(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheResizeCost", func(t *testing.T) {
+			cache := tt.cont.New(0)
+			cache.SetWeigher(func(key, value interface{}) int64 {
+				return int64(len(value.(string)))
+			})
+
+			cache.Store(1, "a")
+			cache.Store(2, "bbbb")
+			cache.Store(3, "cc")
+			cache.Store(4, "ddd")
+			assert.Equal(t, int64(10), cache.Cost())
+
+			evicted := cache.ResizeCost(6)
+			assert.LessOrEqual(t, cache.Cost(), int64(6))
+			assert.Equal(t, int64(10), cache.Cost()+evicted)
+
+			// the ceiling enforced on writes.
+			cache.Store(5, "eeeee")
+			assert.LessOrEqual(t, cache.Cost(), int64(6))
+			assert.True(t, cache.Contains(5))
+
+			// ceiling below the largest entry evicts down to the ceiling.
+			cache.ResizeCost(1)
+			assert.LessOrEqual(t, cache.Cost(), int64(1))
+
+			cache.Store(6, "ffffff")
+			assert.Equal(t, 1, cache.Len())
+			assert.Equal(t, int64(6), cache.Cost())
+		})
+	}
+}
+
+func TestCacheResizeCostOversized(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheResizeCostOversized", func(t *testing.T) {
+			cache := tt.cont.New(0)
+			cache.SetWeigher(func(key, value interface{}) int64 {
+				return int64(len(value.(string)))
+			})
+
+			cache.Store(1, "aaaa")
+			cache.Store(2, "bbbbb")
+			cache.Store(3, "c")
+			cache.Pin(2)
+
+			// The pinned entry never evicted, so it remains above the ceiling.
+			evicted := cache.ResizeCost(3)
+			assert.Equal(t, int64(5), cache.Cost())
+			assert.Equal(t, int64(5), evicted)
+			assert.ElementsMatch(t, []interface{}{2}, cache.Keys())
+		})
+	}
+}
+
+func TestCacheLimitEviction(t *testing.T) {
+	limits := []struct {
+		name  string
+		set   func(c libcache.Cache)
+		total func(c libcache.Cache) int64
+	}{
+		{
+			name:  "Cost",
+			set:   func(c libcache.Cache) { c.ResizeCost(3) },
+			total: func(c libcache.Cache) int64 { return c.Cost() },
+		},
+		{
+			name: "KeyBytes",
+			set:  func(c libcache.Cache) { c.SetMaxKeyBytes(3) },
+			total: func(c libcache.Cache) int64 {
+				n := 0
+				for _, k := range c.Keys() {
+					n += len(k.(string))
+				}
+				return int64(n)
+			},
+		},
+	}
+
+	fixture := func(c libcache.Cache) {
+		for _, k := range []string{"aaaa", "bb", "c", "dd"} {
+			c.Store(k, k)
+		}
+	}
+
+	for _, tt := range cacheTests {
+		for _, limit := range limits {
+			t.Run("Test"+tt.cont.String()+"CacheLimitEviction"+limit.name, func(t *testing.T) {
+				weigher := func(key, value interface{}) int64 {
+					return int64(len(value.(string)))
+				}
+
+				// The limit set over the stored entries.
+				set := tt.cont.New(0)
+				set.SetWeigher(weigher)
+				fixture(set)
+				limit.set(set)
+
+				// The limit enforced once eviction resumed.
+				resumed := tt.cont.New(0)
+				resumed.SetWeigher(weigher)
+				limit.set(resumed)
+				resumed.PauseEviction()
+				fixture(resumed)
+				resumed.ResumeEviction()
+
+				for _, c := range []libcache.Cache{set, resumed} {
+					assert.False(t, c.Contains("aaaa"))
+					assert.LessOrEqual(t, limit.total(c), int64(3))
+				}
+				assert.ElementsMatch(t, set.Keys(), resumed.Keys())
+
+				// The store keeps the written entry even above the limit,
+				// while enforcing the limit evicts it, as the last entry.
+				set.Store("eeee", "eeee")
+				assert.Equal(t, 1, set.Len())
+				limit.set(set)
+				assert.Equal(t, 0, set.Len())
+			})
+		}
+	}
+}
+
+func TestCacheGetEntry(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheGetEntry", func(t *testing.T) {
+			cache := tt.cont.New(0)
+			cache.StoreWithTTL(1, 1, time.Hour)
+			cache.Load(1)
+
+			exp, _ := cache.Expiry(1)
+			info, ok := cache.GetEntry(1)
+			assert.True(t, ok)
+			assert.Equal(t, 1, info.Key)
+			assert.Equal(t, 1, info.Value)
+			assert.Equal(t, exp, info.Expiry)
+			assert.Equal(t, int64(1), info.Cost)
+
+			if tt.cont == libcache.LFU {
+				assert.Equal(t, 1, info.Frequency)
+			}
+
+			_, ok = cache.GetEntry(2)
+			assert.False(t, ok)
+		})
+	}
+}
+
+func TestCacheEntryAge(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheEntryAge", func(t *testing.T) {
+			clock := newFakeClock()
+			cache := tt.cont.New(0)
+			cache.SetClock(clock)
+			cache.Store(1, 1)
+
+			clock.Advance(time.Minute)
+			info, _ := cache.GetEntry(1)
+			assert.Equal(t, time.Minute, info.Age())
+			assert.Equal(t, time.Minute, info.IdleTime())
+
+			// Peek does not bump the access time.
+			cache.Peek(1)
+			clock.Advance(time.Minute)
+			info, _ = cache.GetEntry(1)
+			assert.Equal(t, time.Minute*2, info.Age())
+			assert.Equal(t, time.Minute*2, info.IdleTime())
+
+			cache.Load(1)
+			clock.Advance(time.Second)
+			info, _ = cache.GetEntry(1)
+			assert.Equal(t, time.Minute*2+time.Second, info.Age())
+			assert.Equal(t, time.Second, info.IdleTime())
+		})
+	}
+}
+
+func TestCacheMaxIdle(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheMaxIdle", func(t *testing.T) {
+			clock := newFakeClock()
+			cache := tt.cont.New(0)
+			cache.SetClock(clock)
+			cache.SetMaxIdle(time.Minute)
+			cache.Store(1, 1)
+			cache.Store(2, 2)
+			cache.StoreWithTTL(3, 3, time.Second*90)
+
+			for i := 0; i < 4; i++ {
+				clock.Advance(time.Second * 30)
+				_, ok := cache.Load(2)
+				assert.True(t, ok)
+				cache.Load(3)
+			}
+
+			// the shorter of ttl and max idle wins.
+			assert.False(t, cache.Contains(1))
+			assert.True(t, cache.Contains(2))
+			assert.False(t, cache.Contains(3))
+		})
+	}
+}
+
+func TestCachePeekOldest(t *testing.T) {
+	expect := map[libcache.ReplacementPolicy][2]int{
+		libcache.LFU:  {2, 1},
+		libcache.LRU:  {2, 1},
+		libcache.FIFO: {1, 3},
+		libcache.LIFO: {3, 1},
+		libcache.MRU:  {1, 2},
+		libcache.ARC:  {2, 1},
+		libcache.LRUK: {2, 1},
+		libcache.GDSF: {2, 1},
+	}
+
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CachePeekOldest", func(t *testing.T) {
+			cache := tt.cont.New(3)
+			_, _, ok := cache.PeekOldest()
+			assert.False(t, ok)
+
+			cache.Store(1, 1)
+			cache.Store(2, 2)
+			cache.Store(3, 3)
+			cache.Load(1)
+
+			oldest, v, ok := cache.PeekOldest()
+			assert.True(t, ok)
+			assert.Equal(t, expect[tt.cont][0], oldest)
+			assert.Equal(t, oldest, v)
+
+			newest, _, ok := cache.PeekNewest()
+			assert.True(t, ok)
+			assert.Equal(t, expect[tt.cont][1], newest)
+
+			// peek does not change the eviction order.
+			cache.PeekOldest()
+			cache.Store(4, 4)
+			assert.False(t, cache.Contains(oldest))
+		})
+	}
+}
+
+func TestCacheCost(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheCost", func(t *testing.T) {
+			c := make(chan libcache.Event, 10)
+			cache := tt.cont.New(0)
+			cache.Store(1, "a")
+			assert.Equal(t, int64(1), cache.Cost())
+
+			cache.SetWeigher(func(key, value interface{}) int64 {
+				return int64(len(value.(string)))
+			})
+			cache.Notify(c, libcache.Write, libcache.Remove)
+
+			cache.Store(2, "bb")
+			cache.Store(3, "ccc")
+			cache.Load(2)
+			assert.Equal(t, int64(6), cache.Cost())
+			assert.Equal(t, 3, cache.Len())
+
+			cache.Update(3, "cccc")
+			assert.Equal(t, int64(7), cache.Cost())
+
+			cache.Delete(2)
+			assert.Equal(t, int64(5), cache.Cost())
+
+			cache.Purge()
+			assert.Equal(t, int64(0), cache.Cost())
+
+			costs := []int64{}
+			cache.Ignore(c)
+			close(c)
+			for e := range c {
+				costs = append(costs, e.Cost)
+			}
+
+			assert.Equal(t, []int64{2, 3, 4, 2}, costs[:4])
+		})
+	}
+}
+
+func TestCacheWarm(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheWarm", func(t *testing.T) {
+			c := make(chan libcache.Event, 20)
+			items := make(map[interface{}]interface{})
+			for i := 0; i < 10; i++ {
+				items[i] = i
+			}
+
+			cache := tt.cont.New(5)
+			cache.Notify(c, libcache.Write, libcache.Remove)
+			cache.WarmWithTTL(items, time.Hour)
+			cache.Ignore(c)
+			close(c)
+
+			assert.Equal(t, 5, cache.Len())
+			for k, v := range cache.Snapshot() {
+				assert.Equal(t, items[k], v)
+				exp, _ := cache.Expiry(k)
+				assert.WithinDuration(t, time.Now().Add(time.Hour), exp, time.Second)
+			}
+
+			for e := range c {
+				assert.Equal(t, libcache.Remove, e.Op)
+			}
+
+			// write events fired again after warm.
+			c = make(chan libcache.Event, 1)
+			cache.Notify(c, libcache.Write)
+			cache.Store(1, 1)
+			assert.Len(t, c, 1)
+		})
+	}
+}
+
+func TestCacheGetOrCompute(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheGetOrCompute", func(t *testing.T) {
+			var calls int32
+			wg := sync.WaitGroup{}
+			start := make(chan struct{})
+			cache := tt.cont.New(0)
+
+			loader := func(key interface{}) (interface{}, error) {
+				atomic.AddInt32(&calls, 1)
+				time.Sleep(time.Millisecond * 10)
+				return key, nil
+			}
+
+			for i := 0; i < 100; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					<-start
+					v, err := cache.GetOrCompute(1, loader)
+					assert.NoError(t, err)
+					assert.Equal(t, 1, v)
+				}()
+			}
+
+			close(start)
+			wg.Wait()
+
+			assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+			assert.True(t, cache.Contains(1))
+
+			// loader errors are not cached.
+			errLoader := func(key interface{}) (interface{}, error) {
+				return nil, fmt.Errorf("error")
+			}
+
+			_, err := cache.GetOrCompute(2, errLoader)
+			assert.Error(t, err)
+			assert.False(t, cache.Contains(2))
+
+			v, err := cache.GetOrCompute(2, loader)
+			assert.NoError(t, err)
+			assert.Equal(t, 2, v)
+		})
+	}
+}
+
+func TestCacheNegative(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheNegative", func(t *testing.T) {
+			calls := 0
+			clock := newFakeClock()
+			cache := tt.cont.New(0)
+			cache.SetClock(clock)
+
+			loader := func(key interface{}) (interface{}, error) {
+				calls++
+				return nil, libcache.NotFound(time.Minute)
+			}
+
+			for i := 0; i < 3; i++ {
+				v, err := cache.GetOrCompute(1, loader)
+				assert.Equal(t, libcache.ErrNotFound, err)
+				assert.Nil(t, v)
+			}
+
+			v, ok := cache.Load(1)
+			assert.False(t, ok)
+			assert.Nil(t, v)
+			assert.Equal(t, 1, calls)
+
+			clock.Advance(time.Minute * 2)
+
+			_, err := cache.GetOrCompute(1, loader)
+			assert.Equal(t, libcache.ErrNotFound, err)
+			assert.Equal(t, 2, calls)
+
+			cache.StoreNegative(2, time.Minute)
+			e, ok := cache.GetEntry(2)
+			assert.True(t, ok)
+			assert.Equal(t, libcache.Negative{}, e.Value)
+
+			cache.Store(3, 3)
+			assert.False(t, cache.Contains(2))
+			assert.Equal(t, []bool{false, false, true}, cache.ContainsMany([]interface{}{1, 2, 3}))
+			_, ok = cache.Expiry(2)
+			assert.False(t, ok)
+			assert.Equal(t, []interface{}{3}, cache.Keys())
+			assert.Equal(t, map[interface{}]interface{}{3: 3}, cache.Snapshot())
+			it := cache.Iterator()
+			for it.Next() {
+				assert.Equal(t, 3, it.Key())
+			}
+
+			frozen := cache.(libcache.Freezer).Freeze()
+			assert.False(t, frozen.Contains(2))
+			assert.Equal(t, []interface{}{3}, frozen.Keys())
+			assert.Equal(t, map[interface{}]interface{}{3: 3}, frozen.Snapshot())
+			_, err = frozen.GetOrCompute(2, loader)
+			assert.Equal(t, libcache.ErrNotFound, err)
+			assert.Equal(t, 2, calls)
+		})
+	}
+}
+
+// fixedSource is a rand.Source that always returns half the int63 range,
+// so rand.Float64 returns 0.5.
+type fixedSource struct{}
+
+func (fixedSource) Int63() int64 { return 1 << 62 }
+
+func (fixedSource) Seed(int64) {}
+
+func TestCacheXFetch(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheXFetch", func(t *testing.T) {
+			var calls int32
+			clock := newFakeClock()
+			cache := tt.cont.NewWithOptions(
+				0,
+				libcache.WithClock(clock),
+				libcache.WithTTL(time.Minute),
+				libcache.WithXFetch(1, fixedSource{}),
+			)
+
+			loader := func(key interface{}) (interface{}, error) {
+				n := atomic.AddInt32(&calls, 1)
+				if n == 1 {
+					// The first recompute takes 10s.
+					clock.Advance(time.Second * 10)
+				}
+				return n, nil
+			}
+
+			v, err := cache.GetOrCompute(1, loader)
+			assert.NoError(t, err)
+			assert.Equal(t, int32(1), v)
+
+			e, _ := cache.GetEntry(1)
+			assert.Equal(t, time.Second*10, e.Delta)
+
+			// 10s * ln(0.5) lead is ~6.9s, the key not recomputed with 10s left.
+			clock.Advance(time.Second * 50)
+			v, _ = cache.GetOrCompute(1, loader)
+			assert.Equal(t, int32(1), v)
+			assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+			// Within the lead, the current value returned and recomputed in the background.
+			clock.Advance(time.Second * 5)
+			v, _ = cache.GetOrCompute(1, loader)
+			assert.Equal(t, int32(1), v)
+
+			assert.Eventually(t, func() bool {
+				v, _ := cache.Peek(1)
+				return v == int32(2)
+			}, time.Second, time.Millisecond)
+
+			assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+		})
+	}
+}
+
+func TestCacheMetrics(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheMetrics", func(t *testing.T) {
+			clock := newFakeClock()
+			cache := tt.cont.NewWithOptions(3, libcache.WithClock(clock))
+			cache.Store(1, 1)
+			cache.Store(2, 2)
+			cache.StoreWithTTL(3, 3, time.Minute)
+
+			cache.Load(1)
+			cache.Peek(2)
+			cache.Load(10)
+
+			clock.Advance(time.Minute * 2)
+			cache.Load(3)
+
+			cache.Store(4, 4)
+			cache.Store(5, 5)
+
+			assert.Equal(t, libcache.Metrics{
+				Hits:            2,
+				Misses:          2,
+				Evictions:       1,
+				Expirations:     1,
+				RecentEvictions: 1,
+				Len:             3,
+				Cap:             3,
+				Cost:            3,
+			}, cache.Metrics())
+
+			cache.Reset()
+			assert.Equal(t, libcache.Metrics{Cap: 3}, cache.Metrics())
+		})
+	}
+}
+
+func TestCacheGCMetrics(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheGCMetrics", func(t *testing.T) {
+			clock := newFakeClock()
+			cache := tt.cont.NewWithOptions(0, libcache.WithClock(clock))
+			for i := 0; i < 5; i++ {
+				cache.StoreWithTTL(i, i, time.Second)
+			}
+			cache.StoreWithTTL(5, 5, time.Minute)
+			cache.Store(6, 6)
+
+			cache.GC()
+			assert.Equal(t, uint64(0), cache.Metrics().LastGCEvicted)
+
+			clock.Advance(time.Second)
+			cache.GC()
+			m := cache.Metrics()
+			assert.Equal(t, uint64(5), m.LastGCEvicted)
+			assert.Equal(t, 2, cache.Len())
+
+			cache.Reset()
+			assert.Equal(t, uint64(0), cache.Metrics().LastGCEvicted)
+		})
+	}
+}
+
+func TestCacheContainsNoGC(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheContainsNoGC", func(t *testing.T) {
+			clock := newFakeClock()
+			cache := tt.cont.NewWithOptions(0, libcache.WithClock(clock))
+			for i := 0; i < 100; i++ {
+				cache.StoreWithTTL(i, i, time.Second)
+			}
+			cache.StoreWithTTL(100, 100, time.Minute)
+
+			assert.True(t, cache.ContainsNoGC(1))
+			assert.False(t, cache.ContainsNoGC(101))
+
+			clock.Advance(time.Second)
+
+			// ContainsNoGC reports expired entries absent, but leaves them resident.
+			assert.False(t, cache.ContainsNoGC(1))
+			assert.True(t, cache.ContainsNoGC(100))
+			assert.Equal(t, 101, cache.Len())
+			assert.Equal(t, uint64(0), cache.Metrics().Expirations)
+
+			// Contains collects all of the expired entries.
+			assert.False(t, cache.Contains(1))
+			assert.Equal(t, 1, cache.Len())
+			assert.Equal(t, uint64(100), cache.Metrics().Expirations)
+		})
+	}
+}
+
+func TestCacheGCBatchSize(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheGCBatchSize", func(t *testing.T) {
+			clock := newFakeClock()
+			cache := tt.cont.NewWithOptions(0, libcache.WithClock(clock), libcache.WithGCBatchSize(10))
+			for i := 0; i < 45; i++ {
+				cache.StoreWithTTL(i, i, time.Second)
+			}
+			cache.StoreWithTTL(45, 45, time.Minute)
+
+			clock.Advance(time.Second)
+
+			calls, swept := 0, uint64(0)
+			for cache.GC() < time.Second {
+				n := cache.Metrics().LastGCEvicted
+				assert.True(t, n <= 10)
+				calls++
+				swept += n
+			}
+
+			swept += cache.Metrics().LastGCEvicted
+			assert.Equal(t, 4, calls)
+			assert.Equal(t, uint64(45), swept)
+			assert.Equal(t, 1, cache.Len())
+		})
+	}
+}
+
+func TestCacheGCBatchSizeInline(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheGCBatchSizeInline", func(t *testing.T) {
+			clock := newFakeClock()
+			cache := tt.cont.NewWithOptions(0, libcache.WithClock(clock), libcache.WithGCBatchSize(10))
+			for i := 0; i < 45; i++ {
+				cache.StoreWithTTL(i, i, time.Second)
+			}
+			cache.StoreWithTTL(45, 45, time.Minute)
+
+			clock.Advance(time.Second)
+
+			v, ok := cache.Load(45)
+			assert.True(t, ok)
+			assert.Equal(t, 45, v)
+			assert.True(t, cache.Len() >= 36)
+
+			// The expired key never served, even if left to the next sweeps.
+			n := cache.Len()
+			_, ok = cache.Load(44)
+			assert.False(t, ok)
+			assert.True(t, cache.Len() >= n-11)
+
+			for cache.GC() < time.Second {
+			}
+			assert.Equal(t, 1, cache.Len())
+		})
+	}
+}
+
+func TestCacheUtilization(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheUtilization", func(t *testing.T) {
+			clock := newFakeClock()
+			cache := tt.cont.NewWithOptions(4, libcache.WithClock(clock))
+
+			prev := cache.Utilization()
+			assert.Equal(t, float64(0), prev)
+
+			for i := 0; i < 4; i++ {
+				cache.Store(i, i)
+				u := cache.Utilization()
+				assert.True(t, u > prev)
+				prev = u
+			}
+
+			assert.Equal(t, 1.0, prev)
+			assert.Equal(t, uint64(0), cache.Metrics().Evictions)
+
+			for i := 4; i < 7; i++ {
+				cache.Store(i, i)
+			}
+
+			m := cache.Metrics()
+			assert.Equal(t, 1.0, cache.Utilization())
+			assert.Equal(t, uint64(3), m.Evictions)
+			assert.Equal(t, uint64(3), m.RecentEvictions)
+
+			// Recent evictions roll out of the window.
+			clock.Advance(time.Minute)
+			cache.Store(7, 7)
+			m = cache.Metrics()
+			assert.Equal(t, uint64(4), m.Evictions)
+			assert.Equal(t, uint64(1), m.RecentEvictions)
+
+			cache.ResizeCost(8)
+			assert.Equal(t, 0.5, cache.Utilization())
+
+			// Unbounded caches.
+			assert.Equal(t, float64(0), tt.cont.New(0).Utilization())
+		})
+	}
+}
+
+func TestWithHooks(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"WithHooks", func(t *testing.T) {
+			var (
+				calls   []string
+				evicted = make(chan interface{}, 1)
+			)
+
+			cache := tt.cont.NewWithOptions(1, libcache.WithHooks(libcache.Hooks{
+				BeforeLoad: func(key interface{}) {
+					calls = append(calls, fmt.Sprint("BeforeLoad ", key))
+				},
+				AfterLoad: func(key interface{}, hit bool) {
+					calls = append(calls, fmt.Sprint("AfterLoad ", key, " ", hit))
+				},
+				BeforeStore: func(key interface{}) {
+					calls = append(calls, fmt.Sprint("BeforeStore ", key))
+				},
+				AfterStore: func(key interface{}) {
+					calls = append(calls, fmt.Sprint("AfterStore ", key))
+				},
+				OnEvict: func(key, value interface{}) {
+					evicted <- key
+				},
+			}))
+
+			cache.Store(1, 1)
+			cache.Load(1)
+			cache.Load(2)
+			cache.StoreWithTTL(2, 2, time.Hour)
+
+			assert.Equal(t, []string{
+				"BeforeStore 1",
+				"AfterStore 1",
+				"BeforeLoad 1",
+				"AfterLoad 1 true",
+				"BeforeLoad 2",
+				"AfterLoad 2 false",
+				"BeforeStore 2",
+				"AfterStore 2",
+			}, calls)
+
+			select {
+			case key := <-evicted:
+				assert.Equal(t, 1, key)
+			case <-time.After(time.Second):
+				t.Fatal("OnEvict not called")
+			}
+		})
+	}
+}
+
+func TestLogging(t *testing.T) {
+	type entry struct {
+		op  libcache.Op
+		key interface{}
+		hit bool
+	}
+
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"Logging", func(t *testing.T) {
+			logs := []entry{}
+			cache := libcache.Logging(tt.cont.New(0), func(op libcache.Op, key interface{}, hit bool) {
+				logs = append(logs, entry{op, key, hit})
+			})
+
+			cache.Store(1, 1)
+			cache.Load(1)
+			cache.Load(2)
+			cache.Delete(1)
+
+			assert.Equal(t, []entry{
+				{libcache.Write, 1, true},
+				{libcache.Read, 1, true},
+				{libcache.Read, 2, false},
+				{libcache.Remove, 1, true},
+			}, logs)
+		})
+	}
+}
+
+func TestLoggingEveryOperation(t *testing.T) {
+	type entry struct {
+		op  libcache.Op
+		key interface{}
+		hit bool
+	}
+
+	logs := []entry{}
+	cache := libcache.Logging(libcache.LRU.New(1), func(op libcache.Op, key interface{}, hit bool) {
+		logs = append(logs, entry{op, key, hit})
+	})
+
+	cache.StoreEvicting("a", 1)
+	cache.StoreWithTTLEvicting("b", 2, time.Minute)
+	cache.StoreWithDeadlineEvicting("c", 3, time.Time{})
+	cache.LoadWithExpiry("c")
+	cache.LoadMany("b", "c")
+	cache.LoadCtx(context.Background(), "c", func(context.Context) (interface{}, error) {
+		return 3, nil
+	})
+	cache.ContainsNoGC("a")
+	cache.Expiry("c")
+	cache.DeleteWithPrefix("c")
+	cache.Warm(map[interface{}]interface{}{"d": 4})
+	cache.DeleteFunc(func(key, value interface{}) bool { return true })
+
+	assert.Equal(t, []entry{
+		{libcache.Write, "a", true},
+		{libcache.Write, "b", true},
+		{libcache.Write, "c", true},
+		{libcache.Read, "c", true},
+		{libcache.Read, "b", false},
+		{libcache.Read, "c", true},
+		{libcache.Read, "c", true},
+		{libcache.Read, "a", false},
+		{libcache.Read, "c", true},
+		{libcache.Remove, "c", true},
+		{libcache.Write, "d", true},
+		{libcache.Remove, "d", true},
+	}, logs)
+}
+
+func TestCacheLoadCtx(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheLoadCtx", func(t *testing.T) {
+			cache := tt.cont.New(0)
+
+			v, err := cache.LoadCtx(context.Background(), 1, func(ctx context.Context) (interface{}, error) {
+				return 1, nil
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, 1, v)
+
+			v, ok := cache.Load(1)
+			assert.True(t, ok)
+			assert.Equal(t, 1, v)
+		})
+	}
+}
+
+func TestCacheLoadCtxDeadlineExceeded(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheLoadCtxDeadlineExceeded", func(t *testing.T) {
+			cache := tt.cont.New(0)
+			canceled := make(chan struct{})
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+			defer cancel()
+
+			v, err := cache.LoadCtx(ctx, 1, func(ctx context.Context) (interface{}, error) {
+				<-ctx.Done()
+				close(canceled)
+				return 1, nil
+			})
+			assert.Equal(t, context.DeadlineExceeded, err)
+			assert.Nil(t, v)
+
+			// The sole waiter gave up, so the loader canceled.
+			select {
+			case <-canceled:
+			case <-time.After(time.Second):
+				t.Fatal("loader context not canceled")
+			}
+
+			assert.Never(t, func() bool {
+				return cache.Contains(1)
+			}, time.Millisecond*20, time.Millisecond)
+		})
+	}
+}
+
+func TestCacheLoadCtxSharedLoader(t *testing.T) {
+	cache := libcache.LRU.New(0)
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	loader := func(ctx context.Context) (interface{}, error) {
+		close(started)
+		select {
+		case <-release:
+			return 1, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, 1)
+	go func() {
+		_, err := cache.LoadCtx(ctx, 1, loader)
+		errs <- err
+	}()
+
+	<-started
+
+	result := make(chan interface{}, 1)
+	go func() {
+		v, _ := cache.LoadCtx(context.Background(), 1, loader)
+		result <- v
+	}()
+
+	// Wait for the second caller to join the in-flight call.
+	time.Sleep(time.Millisecond * 10)
+
+	// Canceling one waiter does not cancel the shared loader.
+	cancel()
+	assert.Equal(t, context.Canceled, <-errs)
+
+	close(release)
+	assert.Equal(t, 1, <-result)
+
+	v, ok := cache.Load(1)
+	assert.True(t, ok)
+	assert.Equal(t, 1, v)
+}
+
+func TestCacheLoadCtxPanic(t *testing.T) {
+	cache := libcache.LRU.New(0)
+
+	v, err := cache.LoadCtx(context.Background(), 1, func(context.Context) (interface{}, error) {
+		panic("boom")
+	})
+	assert.Nil(t, v)
+	assert.EqualError(t, err, "libcache: loader panicked: boom")
+	assert.False(t, cache.Contains(1))
+
+	v, err = cache.LoadCtx(context.Background(), 1, func(context.Context) (interface{}, error) {
+		return 1, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, v)
+}
+
+func TestCacheGetOrComputeAfterCanceledLoadCtx(t *testing.T) {
+	cache := libcache.LRU.New(0)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, 1)
+	go func() {
+		_, err := cache.LoadCtx(ctx, 1, func(context.Context) (interface{}, error) {
+			close(started)
+			<-release
+			return 1, nil
+		})
+		errs <- err
+	}()
+
+	<-started
+	cancel()
+	assert.Equal(t, context.Canceled, <-errs)
+
+	// The canceled call still in-flight, so GetOrCompute starts a new one instead of joining it.
+	v, err := cache.GetOrCompute(1, func(interface{}) (interface{}, error) {
+		return 2, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, v)
+}
+
+func TestWriteThrough(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"WriteThrough", func(t *testing.T) {
+			errWrite := errors.New("write failed")
+			store := map[interface{}]interface{}{}
+			fail := false
+
+			cache := libcache.WriteThrough(
+				tt.cont.New(0),
+				func(key, value interface{}) error {
+					if fail {
+						return errWrite
+					}
+					store[key] = value
+					return nil
+				},
+				func(key interface{}) error {
+					if fail {
+						return errWrite
+					}
+					delete(store, key)
+					return nil
+				},
+			)
+
+			assert.NoError(t, cache.StoreE(1, 1))
+			assert.NoError(t, cache.StoreWithTTLE(2, 2, time.Hour))
+			assert.NoError(t, cache.UpdateE(1, 10))
+			assert.Equal(t, map[interface{}]interface{}{1: 10, 2: 2}, store)
+
+			// A failed writer leaves the cache unchanged.
+			fail = true
+			assert.Equal(t, errWrite, cache.StoreE(3, 3))
+			assert.Equal(t, errWrite, cache.UpdateE(1, 100))
+			assert.Equal(t, errWrite, cache.DeleteE(2))
+			cache.Store(4, 4)
+
+			v, _ := cache.Load(1)
+			assert.Equal(t, 10, v)
+			assert.False(t, cache.Contains(3))
+			assert.False(t, cache.Contains(4))
+			assert.True(t, cache.Contains(2))
+
+			fail = false
+			assert.NoError(t, cache.DeleteE(2))
+			assert.False(t, cache.Contains(2))
+			assert.Equal(t, map[interface{}]interface{}{1: 10}, store)
+		})
+	}
+}
+
+func TestWriteThroughWrapsEveryWrite(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"WriteThroughWrapsEveryWrite", func(t *testing.T) {
+			store := map[interface{}]interface{}{}
+			cache := libcache.WriteThrough(
+				tt.cont.New(0),
+				func(key, value interface{}) error {
+					store[key] = value
+					return nil
+				},
+				func(key interface{}) error {
+					delete(store, key)
+					return nil
+				},
+			)
+
+			other := libcache.LRU.New(0)
+			other.Store("merged", 1)
+
+			cache.StoreEvicting("evicting", 1)
+			_, _ = cache.Increment("counter", 2)
+			cache.Store("swapped", 1)
+			assert.True(t, cache.CompareAndSwap("swapped", 1, 2))
+			assert.False(t, cache.CompareAndSwap("swapped", 1, 3))
+			cache.Merge(other, nil)
+
+			cache.Store("prefix-1", 1)
+			assert.Equal(t, 1, cache.DeleteWithPrefix("prefix-"))
+			cache.Store("func", 1)
+			assert.Equal(t, 1, cache.DeleteFunc(func(key, value interface{}) bool {
+				return key == "func"
+			}))
+			cache.Store("get", 1)
+			v, ok := cache.GetAndDelete("get")
+			assert.True(t, ok)
+			assert.Equal(t, 1, v)
+			cache.Store("compare", 1)
+			assert.True(t, cache.CompareAndDelete("compare", 1))
+
+			assert.Equal(t, map[interface{}]interface{}{
+				"evicting": 1,
+				"counter":  int64(2),
+				"swapped":  2,
+				"merged":   1,
+			}, store)
+			assert.ElementsMatch(t, []interface{}{"evicting", "counter", "swapped", "merged"}, cache.Keys())
+		})
+	}
+}
+
+func TestWriteBehind(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"WriteBehind", func(t *testing.T) {
+			flushed := make(chan map[interface{}]interface{}, 2)
+			cache := libcache.WriteBehind(tt.cont.New(0), func(items map[interface{}]interface{}) error {
+				flushed <- items
+				return nil
+			}, 0, 2)
+
+			// Repeated writes coalesced into a single flush of the latest value.
+			cache.Store(1, 1)
+			cache.Store(1, 2)
+			cache.Store(2, 2)
+
+			select {
+			case items := <-flushed:
+				assert.Equal(t, map[interface{}]interface{}{1: 2, 2: 2}, items)
+			case <-time.After(time.Second):
+				t.Fatal("batch not flushed")
+			}
+
+			// Deleted before flush, dropped.
+			cache.Store(3, 3)
+			cache.Store(4, 4)
+			cache.Delete(4)
+
+			assert.NoError(t, cache.Close())
+			assert.Equal(t, map[interface{}]interface{}{3: 3}, <-flushed)
+		})
+	}
+}
+
+func TestWriteBehindTracksEveryWrite(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"WriteBehindTracksEveryWrite", func(t *testing.T) {
+			flushed := make(chan map[interface{}]interface{}, 1)
+			cache := libcache.WriteBehind(tt.cont.New(0), func(items map[interface{}]interface{}) error {
+				flushed <- items
+				return nil
+			}, 0, 0)
+
+			other := libcache.LRU.New(0)
+			other.Store("merged", 1)
+
+			cache.StoreEvicting("evicting", 1)
+			_, _ = cache.Increment("counter", 2)
+			cache.Store("swapped", 1)
+			cache.CompareAndSwap("swapped", 1, 2)
+			cache.Merge(other, nil)
+			cache.Warm(map[interface{}]interface{}{"warm": 1})
+
+			cache.Store("prefix-1", 1)
+			cache.DeleteWithPrefix("prefix-")
+			cache.StoreWithTags("tagged", 1, "tag")
+			cache.InvalidateTag("tag")
+			cache.Store("func", 1)
+			cache.DeleteFunc(func(key, value interface{}) bool {
+				return key == "func"
+			})
+			cache.Store("negative", 1)
+			cache.StoreNegative("negative", time.Minute)
+
+			assert.NoError(t, cache.Close())
+			assert.Equal(t, map[interface{}]interface{}{
+				"evicting": 1,
+				"counter":  int64(2),
+				"swapped":  2,
+				"merged":   1,
+			}, <-flushed)
+		})
+	}
+}
+
+func TestWriteBehindPurge(t *testing.T) {
+	flushed := make(chan map[interface{}]interface{}, 1)
+	cache := libcache.WriteBehind(libcache.LRU.New(0), func(items map[interface{}]interface{}) error {
+		flushed <- items
+		return nil
+	}, 0, 0)
+
+	cache.Store(1, 1)
+	cache.Purge()
+	cache.Store(2, 2)
+
+	assert.NoError(t, cache.Close())
+	assert.Equal(t, map[interface{}]interface{}{2: 2}, <-flushed)
+}
+
+func TestWriteBehindConcurrentWrites(t *testing.T) {
+	flushed := make(chan map[interface{}]interface{}, 1)
+	cache := libcache.WriteBehind(libcache.LRU.New(0), func(items map[interface{}]interface{}) error {
+		flushed <- items
+		return nil
+	}, 0, 0)
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				if (i+j)%2 == 0 {
+					cache.Store(j%10, j)
+				} else {
+					cache.Delete(j % 10)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// The pending writes match the cache content, whatever the writes interleaving.
+	want := cache.Snapshot()
+	assert.NoError(t, cache.Close())
+
+	// Close flushes synchronously, and only if there are dirty keys.
+	select {
+	case got := <-flushed:
+		assert.Equal(t, want, got)
+	default:
+		assert.Empty(t, want)
+	}
+}
+
+func TestWriteBehindInterval(t *testing.T) {
+	flushed := make(chan map[interface{}]interface{}, 1)
+	cache := libcache.WriteBehind(libcache.LRU.New(0), func(items map[interface{}]interface{}) error {
+		flushed <- items
+		return nil
+	}, time.Millisecond*10, 0)
+	defer cache.Close()
+
+	cache.Store(1, 1)
+
+	select {
+	case items := <-flushed:
+		assert.Equal(t, map[interface{}]interface{}{1: 1}, items)
+	case <-time.After(time.Second):
+		t.Fatal("interval not flushed")
+	}
+}
+
+func TestCachePauseEviction(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CachePauseEviction", func(t *testing.T) {
+			clock := newFakeClock()
+			cache := tt.cont.NewWithOptions(5, libcache.WithClock(clock))
+			cache.PauseEviction()
+
+			for i := 0; i < 10; i++ {
+				cache.Store(i, i)
+			}
+
+			assert.Equal(t, 10, cache.Len())
+			for i := 0; i < 10; i++ {
+				assert.True(t, cache.Contains(i))
+			}
+
+			// Expiry still collects entries while paused.
+			cache.StoreWithTTL(10, 10, time.Second)
+			clock.Advance(time.Second * 2)
+			assert.False(t, cache.Contains(10))
+
+			cache.ResumeEviction()
+			assert.Equal(t, 5, cache.Len())
+
+			cache.Store(11, 11)
+			assert.Equal(t, 5, cache.Len())
+		})
+	}
+}
+
+func TestCacheFreeze(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheFreeze", func(t *testing.T) {
+			cache := tt.cont.New(0)
+			for i := 0; i < 10; i++ {
+				cache.Store(i, i)
+			}
+
+			frozen := cache.(libcache.Freezer).Freeze()
+
+			// The frozen cache is independent of its source.
+			cache.Delete(1)
+
+			frozen.Store(10, 10)
+			frozen.Update(2, 20)
+			frozen.Delete(3)
+			frozen.Purge()
+			frozen.Resize(1)
+
+			assert.Equal(t, 10, frozen.Len())
+			assert.False(t, frozen.Contains(10))
+			assert.True(t, frozen.Contains(3))
+			assert.ElementsMatch(t, []interface{}{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, frozen.Keys())
+
+			wg := sync.WaitGroup{}
+			for i := 0; i < 10; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					v, ok := frozen.Load(i)
+					assert.True(t, ok)
+					assert.Equal(t, i, v)
+					_, _ = frozen.Peek(i)
+					_ = frozen.Keys()
+				}(i)
+			}
+
+			wg.Wait()
+		})
+	}
+}
+
+func TestCacheMerge(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheMerge", func(t *testing.T) {
+			cache := tt.cont.New(0)
+			cache.Store(1, 1)
+			cache.Store(2, 5)
+
+			other := tt.cont.New(0)
+			other.Store(2, 3)
+			other.StoreWithTTL(3, 3, time.Hour)
+
+			cache.Merge(other, func(existing, incoming interface{}) interface{} {
+				if existing.(int) > incoming.(int) {
+					return existing
+				}
+				return incoming
+			})
+
+			assert.Equal(t, map[interface{}]interface{}{1: 1, 2: 5, 3: 3}, cache.Snapshot())
+
+			exp, _ := other.Expiry(3)
+			got, _ := cache.Expiry(3)
+			assert.Equal(t, exp, got)
+
+			// Nil onConflict, incoming wins.
+			cache.Merge(other, nil)
+			v, _ := cache.Peek(2)
+			assert.Equal(t, 3, v)
+		})
+	}
+}
+
+func TestCacheFilter(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheFilter", func(t *testing.T) {
+			clock := newFakeClock()
+			cache := tt.cont.New(0)
+			cache.SetClock(clock)
+
+			for i := 1; i <= 6; i++ {
+				cache.Store(i, i)
+			}
+			cache.StoreWithTTL(8, 8, time.Hour)
+			cache.StoreWithTTL(10, 10, time.Minute)
+			cache.StoreNegative(12, time.Hour)
+			clock.Advance(time.Minute * 2)
+
+			even := cache.(libcache.Cloner).Filter(func(key, value interface{}) bool {
+				return key.(int)%2 == 0
+			})
+
+			assert.Equal(t, map[interface{}]interface{}{2: 2, 4: 4, 6: 6, 8: 8}, even.Snapshot())
+			assert.Equal(t, 8, cache.Len())
+
+			ttl, _ := even.RemainingTTL(8)
+			assert.True(t, ttl > time.Minute*57 && ttl <= time.Minute*58)
+		})
+	}
+}
+
+func TestCacheDeleteFunc(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheDeleteFunc", func(t *testing.T) {
+			c := make(chan libcache.Event, 10)
+			cache := tt.cont.New(0)
+			cache.Notify(c, libcache.Remove)
+
+			for i := 1; i <= 6; i++ {
+				cache.Store(i, i*10)
+			}
+			cache.StoreNegative(7, time.Hour)
+
+			n := cache.DeleteFunc(func(key, value interface{}) bool {
+				return value.(int) > 30
+			})
+
+			// The negative entry never passed to pred, and never listed by Keys.
+			assert.Equal(t, 3, n)
+			assert.ElementsMatch(t, []interface{}{1, 2, 3}, cache.Keys())
+			_, ok := cache.GetEntry(7)
+			assert.True(t, ok)
+			assert.Len(t, c, 3)
+		})
+	}
+}
+
+func TestCacheOrderedKeys(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheOrderedKeys", func(t *testing.T) {
+			cache := tt.cont.New(5)
+			for i := 1; i <= 5; i++ {
+				cache.Store(i, i)
+			}
+			cache.Load(3)
+			cache.Load(3)
+			cache.Load(5)
+			cache.Load(1)
+
+			for i := 0; i < 3; i++ {
+				keys := cache.OrderedKeys()
+				oldest, _, _ := cache.PeekOldest()
+				assert.ElementsMatch(t, cache.Keys(), keys)
+				assert.Equal(t, oldest, keys[0])
+
+				cache.Store(10+i, i)
+				assert.False(t, cache.Contains(keys[0]))
+				assert.Equal(t, 5, cache.Len())
+			}
+		})
+	}
+}
+
+func TestCacheCapacityOne(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheCapacityOne", func(t *testing.T) {
+			cache := tt.cont.New(1)
+			cache.Store(1, 1)
+			cache.Store(2, 2)
+
+			assert.Equal(t, 1, cache.Len())
+			assert.True(t, cache.Contains(1) != cache.Contains(2))
+
+			// Overwriting the only key never discards it.
+			cache.Store(3, 3)
+			cache.Store(3, 4)
+			v, ok := cache.Peek(3)
+			assert.True(t, ok)
+			assert.Equal(t, 4, v)
+			assert.Equal(t, 1, cache.Len())
+		})
+	}
+}
+
+func TestCacheNegativeCapacity(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheNegativeCapacity", func(t *testing.T) {
+			cache := tt.cont.New(-5)
+			assert.Equal(t, 0, cache.Cap())
+
+			for i := 0; i < 10; i++ {
+				cache.Store(i, i)
+			}
+			assert.Equal(t, 10, cache.Len())
+
+			assert.Equal(t, 0, cache.Resize(-1))
+			assert.Equal(t, 0, cache.Cap())
+			assert.Equal(t, 10, cache.Len())
+		})
+	}
+}
+
+func TestCacheStoreEvicting(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheStoreEvicting", func(t *testing.T) {
+			cache := tt.cont.New(3)
+			for i := 1; i <= 3; i++ {
+				_, _, evicted := cache.StoreEvicting(i, i)
+				assert.False(t, evicted)
+			}
+
+			// overwrite never evicts.
+			_, _, evicted := cache.StoreEvicting(2, 2)
+			assert.False(t, evicted)
+
+			for i := 4; i <= 6; i++ {
+				candidate, value, _ := cache.PeekOldest()
+				k, v, evicted := cache.StoreWithTTLEvicting(i, i, time.Minute)
+				assert.True(t, evicted)
+				assert.Equal(t, candidate, k)
+				assert.Equal(t, value, v)
+				assert.False(t, cache.Contains(k))
+				assert.Equal(t, 3, cache.Len())
+			}
+		})
+	}
+}
+
+func TestCacheLoadWithExpiry(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheLoadWithExpiry", func(t *testing.T) {
+			clock := newFakeClock()
+			cache := tt.cont.NewWithClock(0, clock)
+			cache.StoreWithTTL(1, 1, time.Minute)
+			cache.Store(2, 2)
+			cache.StoreNegative(3, time.Minute)
+
+			v, exp, ok := cache.LoadWithExpiry(1)
+			assert.True(t, ok)
+			assert.Equal(t, 1, v)
+			assert.Equal(t, clock.Now().UTC().Add(time.Minute), exp)
+
+			v, exp, ok = cache.LoadWithExpiry(2)
+			assert.True(t, ok)
+			assert.Equal(t, 2, v)
+			assert.True(t, exp.IsZero())
+
+			for _, k := range []interface{}{3, 4} {
+				v, exp, ok = cache.LoadWithExpiry(k)
+				assert.False(t, ok)
+				assert.Nil(t, v)
+				assert.True(t, exp.IsZero())
+			}
+
+			clock.Advance(time.Minute)
+			_, _, ok = cache.LoadWithExpiry(1)
+			assert.False(t, ok)
+		})
+	}
+}
+
+func TestCacheKeysByExpiry(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheKeysByExpiry", func(t *testing.T) {
+			clock := newFakeClock()
+			cache := tt.cont.NewWithClock(0, clock)
+			cache.StoreWithTTL(1, 1, time.Minute*3)
+			cache.Store(2, 2)
+			cache.StoreWithTTL(3, 3, time.Minute)
+			cache.StoreWithTTL(4, 4, time.Second)
+			cache.StoreWithTTL(5, 5, time.Minute*2)
+
+			assert.Equal(t, []interface{}{4, 3, 5, 1, 2}, cache.KeysByExpiry())
+			assert.Equal(t, []interface{}{4, 3}, cache.ExpiringWithin(time.Minute))
+			assert.Empty(t, cache.ExpiringWithin(0))
+
+			clock.Advance(time.Minute)
+			assert.Equal(t, []interface{}{5, 1, 2}, cache.KeysByExpiry())
+			assert.Equal(t, []interface{}{5, 1}, cache.ExpiringWithin(time.Minute*2))
+		})
+	}
+}
+
+func TestKeysByExpiryKeyFunc(t *testing.T) {
+	table := []struct {
+		name   string
+		cache  func() libcache.Cache
+		freeze bool
+	}{
+		{name: "ARC", cache: func() libcache.Cache { return libcache.ARC.New(0) }},
+		{name: "Tiered", cache: func() libcache.Cache { return libcache.Tiered(libcache.LRU.New(2), libcache.LRU.New(0)) }},
+		{name: "Associative", cache: func() libcache.Cache { return libcache.NewAssociative(2, 4) }},
+		{name: "Frozen", cache: func() libcache.Cache { return libcache.LRU.New(0) }, freeze: true},
+	}
+
+	for _, tt := range table {
+		t.Run("Test"+tt.name+"KeysByExpiryKeyFunc", func(t *testing.T) {
+			clock := newFakeClock()
+			cache := tt.cache()
+			cache.SetClock(clock)
+			libcache.WithKeyFunc(func(key interface{}) interface{} {
+				return string(key.([]byte))
+			})(cache)
+
+			cache.StoreWithTTL([]byte("1"), 1, time.Minute*3)
+			cache.Store([]byte("2"), 2)
+			cache.StoreWithTTL([]byte("3"), 3, time.Minute)
+			cache.StoreWithTTL([]byte("4"), 4, time.Second)
+			cache.Load([]byte("4"))
+
+			if tt.freeze {
+				cache = cache.(libcache.Freezer).Freeze()
+			}
+
+			assert.Equal(t, []interface{}{[]byte("4"), []byte("3"), []byte("1"), []byte("2")}, cache.KeysByExpiry())
+			assert.Equal(t, []interface{}{[]byte("4"), []byte("3")}, cache.ExpiringWithin(time.Minute))
+		})
+	}
+}
+
+func TestCacheColdestHottestN(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheColdestHottestN", func(t *testing.T) {
+			cache := tt.cont.New(5)
+			for i := 1; i <= 5; i++ {
+				cache.Store(i, i)
+			}
+			cache.Load(3)
+			cache.Load(3)
+			cache.Load(5)
+
+			keys := cache.OrderedKeys()
+			coldest := cache.ColdestN(2)
+			hottest := cache.HottestN(2)
+
+			assert.Len(t, coldest, 2)
+			assert.Equal(t, keys[0], coldest[0].Key)
+			assert.Equal(t, keys[1], coldest[1].Key)
+			assert.Equal(t, keys[4], hottest[0].Key)
+			assert.Equal(t, keys[3], hottest[1].Key)
+			assert.Len(t, cache.ColdestN(10), 5)
+			assert.Nil(t, cache.HottestN(0))
+
+			// ColdestN never changes the eviction order.
+			assert.Equal(t, keys, cache.OrderedKeys())
+
+			cache.Store(6, 6)
+			assert.False(t, cache.Contains(coldest[0].Key))
+		})
+	}
+}
+
+func TestCacheDeterministic(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheDeterministic", func(t *testing.T) {
+			clock := newFakeClock()
+			populate := func() libcache.Cache {
+				cache := tt.cont.NewWithOptions(0, libcache.WithDeterministic(true), libcache.WithClock(clock))
+				cache.SetJitter(time.Minute)
+				for i := 0; i < 100; i++ {
+					cache.StoreWithTTL(i, i, time.Hour)
+				}
+				cache.Delete(50)
+				return cache
+			}
+
+			c1, c2 := populate(), populate()
+
+			want := []interface{}{}
+			for i := 0; i < 100; i++ {
+				if i != 50 {
+					want = append(want, i)
+				}
+			}
+
+			assert.Equal(t, want, c1.Keys())
+			assert.Equal(t, c1.Keys(), c2.Keys())
+
+			keys := []interface{}{}
+			for it := c1.Iterator(); it.Next(); {
+				keys = append(keys, it.Key())
+			}
+			assert.Equal(t, want, keys)
+
+			for _, k := range want {
+				exp1, _ := c1.Expiry(k)
+				exp2, _ := c2.Expiry(k)
+				assert.Equal(t, exp1, exp2)
+			}
+		})
+	}
+}
+
+func TestCachePurgeExpiring(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CachePurgeExpiring", func(t *testing.T) {
+			clock := newFakeClock()
+			cache := tt.cont.NewWithClock(0, clock)
+
+			for i := 0; i < 5; i++ {
+				cache.StoreWithTTL(i, i, time.Millisecond*100)
+			}
+
+			cache.Purge()
+			cache.StoreWithTTL(10, 10, time.Millisecond*300)
+
+			clock.Advance(time.Millisecond * 200)
+			cache.Peek("")
+
+			// Purge without subscribers drops the expiring heap along the entries.
+			assert.Equal(t, uint64(0), cache.Metrics().Expirations)
+			assert.Equal(t, 1, cache.Len())
+		})
+	}
+}
+
+func TestCacheRefreshAllTTL(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheRefreshAllTTL", func(t *testing.T) {
+			clock := newFakeClock()
+			cache := tt.cont.NewWithClock(0, clock)
+			cache.Store(1, 1)
+			cache.StoreWithTTL(2, 2, time.Minute)
+			cache.StoreWithTTL(3, 3, time.Hour)
+			cache.StoreNegative(4, time.Hour)
+
+			clock.Advance(time.Second * 30)
+			cache.RefreshAllTTL(time.Minute * 10)
+
+			want := clock.Now().UTC().Add(time.Minute * 10)
+			for _, k := range []interface{}{1, 2, 3} {
+				exp, ok := cache.Expiry(k)
+				assert.True(t, ok)
+				assert.Equal(t, want, exp)
+			}
+
+			// The negative entry keeps its expiry.
+			clock.Advance(time.Minute * 10)
+			cache.GC()
+			assert.Empty(t, cache.Keys())
+			_, ok := cache.GetEntry(4)
+			assert.True(t, ok)
+		})
+	}
+}
+
+func TestCacheClearTTL(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheClearTTL", func(t *testing.T) {
+			clock := newFakeClock()
+			cache := tt.cont.NewWithClock(0, clock)
+			cache.StoreWithTTL(1, 1, time.Minute)
+			cache.StoreWithTTL(2, 2, time.Hour)
+			cache.StoreNegative(3, time.Minute)
+
+			cache.ClearTTL()
+
+			exp, ok := cache.Expiry(1)
+			assert.True(t, ok)
+			assert.True(t, exp.IsZero())
+
+			clock.Advance(time.Hour * 2)
+			cache.GC()
+			assert.ElementsMatch(t, []interface{}{1, 2}, cache.Keys())
+		})
+	}
+}
+
+func TestCachePolicy(t *testing.T) {
+	policies := []libcache.ReplacementPolicy{libcache.IDLE}
+	for _, tt := range cacheTests {
+		policies = append(policies, tt.cont)
+	}
+
+	for _, p := range policies {
+		t.Run("Test"+p.String()+"CachePolicy", func(t *testing.T) {
+			cache := p.New(0)
+			assert.Equal(t, p.String(), cache.Policy().String())
+			assert.Equal(t, p, p.NewUnsafe(0).Policy())
+
+			frozen := cache.(libcache.Freezer).Freeze()
+			assert.Equal(t, p, frozen.Policy())
+		})
+	}
+
+	assert.Equal(t, libcache.LRU, libcache.NewAssociative(2, 2).Policy())
+	assert.Equal(t, libcache.LFU, libcache.Tiered(libcache.LFU.New(1), libcache.LRU.New(0)).Policy())
+}
+
+type keyer struct {
+	id   int
+	name string
+}
+
+func (k *keyer) CacheKey() interface{} {
+	return k.id
+}
+
+func TestCacheKeyer(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheKeyer", func(t *testing.T) {
+			cache := tt.cont.New(0)
+			c := make(chan libcache.Event, 10)
+			cache.Notify(c, libcache.Write)
+
+			k1, k2 := &keyer{id: 1, name: "a"}, &keyer{id: 1, name: "b"}
+			cache.Store(k1, 1)
+
+			v, ok := cache.Load(k2)
+			assert.True(t, ok)
+			assert.Equal(t, 1, v)
+
+			cache.Store(k2, 2)
+			assert.Equal(t, 1, cache.Len())
+			assert.Same(t, k2, cache.Keys()[0])
+			assert.Same(t, k1, (<-c).Key)
+			assert.Same(t, k2, (<-c).Key)
+
+			// nil Keyer used as is.
+			var k3 *keyer
+			cache.Store(k3, 3)
+			v, ok = cache.Load(k3)
+			assert.True(t, ok)
+			assert.Equal(t, 3, v)
+			assert.Equal(t, 2, cache.Len())
+
+			cache.Delete(&keyer{id: 1})
+			assert.False(t, cache.Contains(k1))
+		})
+	}
+}
+
+func TestCacheAdmissionFunc(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheAdmissionFunc", func(t *testing.T) {
+			even := func(key, value interface{}) bool {
+				return key.(int)%2 == 0
+			}
+
+			cache := tt.cont.NewWithOptions(4, libcache.WithAdmissionFunc(even))
+			for i := 0; i < 6; i++ {
+				cache.Store(i, i)
+			}
+			cache.StoreWithTTL(7, 7, time.Minute)
+			cache.StoreNegative(9, time.Minute)
+
+			// The negative entry admitted, and counted by Len, but never listed by Keys.
+			assert.ElementsMatch(t, []interface{}{0, 2, 4}, cache.Keys())
+			assert.Equal(t, 4, cache.Len())
+			assert.False(t, cache.Contains(1))
+			assert.False(t, cache.Contains(7))
+
+			// existing keys unaffected.
+			cache.SetAdmissionFunc(func(key, value interface{}) bool { return false })
+			cache.Update(0, 10)
+			cache.Store(2, 20)
+			cache.Store(6, 6)
+
+			v, _ := cache.Peek(0)
+			assert.Equal(t, 10, v)
+			v, _ = cache.Peek(2)
+			assert.Equal(t, 20, v)
+			assert.False(t, cache.Contains(6))
+		})
+	}
+}
+
+func TestCacheAdmissionFuncIncrement(t *testing.T) {
+	even := func(key, value interface{}) bool {
+		return key.(int)%2 == 0
+	}
+
+	caches := []libcache.Cache{libcache.Tiered(libcache.LRU.New(2), libcache.LRU.New(0))}
+	for _, tt := range cacheTests {
+		caches = append(caches, tt.cont.New(0))
+	}
+
+	for _, cache := range caches {
+		cache.SetAdmissionFunc(even)
+
+		n, err := cache.Increment(1, 5)
+		assert.Equal(t, libcache.ErrNotStored, err)
+		assert.Equal(t, int64(0), n)
+		assert.False(t, cache.Contains(1))
+
+		n, err = cache.Increment(2, 5)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(5), n)
+
+		// existing keys unaffected.
+		cache.SetAdmissionFunc(func(key, value interface{}) bool { return false })
+		n, err = cache.Decrement(2, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), n)
+	}
+}
+
+func TestCacheKeyFunc(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheKeyFunc", func(t *testing.T) {
+			cache := tt.cont.NewWithOptions(0, libcache.WithKeyFunc(func(key interface{}) interface{} {
+				if b, ok := key.([]byte); ok {
+					return string(b)
+				}
+				return key
+			}))
+
+			cache.Store([]byte("1"), 1)
+			cache.Store([]byte("2"), 2)
+			cache.Store([]byte("2"), 3)
+
+			v, ok := cache.Load([]byte("2"))
+			assert.True(t, ok)
+			assert.Equal(t, 3, v)
+			assert.Equal(t, 2, cache.Len())
+			assert.ElementsMatch(t, [][]byte{[]byte("1"), []byte("2")}, cache.Keys())
+
+			assert.True(t, cache.Pin([]byte("1")))
+			assert.True(t, cache.Unpin([]byte("1")))
+
+			v, err := cache.GetOrCompute([]byte("3"), func(key interface{}) (interface{}, error) {
+				return 4, nil
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, 4, v)
+
+			cache.Delete([]byte("1"))
+			assert.False(t, cache.Contains([]byte("1")))
+			assert.Equal(t, 2, cache.Len())
+		})
+	}
+}
+
+func TestCacheBloomFilter(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheBloomFilter", func(t *testing.T) {
+			cache := tt.cont.New(0)
+			cache.Store(-1, -1)
+			cache.SetBloomFilter(100, 0.01)
+
+			for i := 0; i < 100; i++ {
+				cache.Store(i, i)
+			}
+
+			// Keys stored before and after enabling the filter never missed.
+			assert.True(t, cache.Contains(-1))
+			for i := 0; i < 100; i++ {
+				v, ok := cache.Load(i)
+				assert.True(t, ok)
+				assert.Equal(t, i, v)
+			}
+
+			cache.Delete(1)
+			assert.False(t, cache.Contains(1))
+			cache.Store(1, 1)
+			assert.True(t, cache.Contains(1))
 
+			cache.Purge()
+			assert.False(t, cache.Contains(1))
+			cache.Store(1, 1)
+			assert.True(t, cache.Contains(1))
 		})
 	}
 }
 
-func TestNotify(t *testing.T) {
+// mutableKey is a pointer key target, mutated after the key stored.
+type mutableKey struct {
+	n int
+}
+
+func TestCacheBloomFilterKeyEquality(t *testing.T) {
 	for _, tt := range cacheTests {
-		t.Run("Test"+tt.cont.String()+"CacheNotify", func(t *testing.T) {
-			got := 0
-			c := make(chan libcache.Event, 10)
+		t.Run("Test"+tt.cont.String()+"CacheBloomFilterKeyEquality", func(t *testing.T) {
 			cache := tt.cont.New(0)
+			cache.SetBloomFilter(100, 0.01)
 
-			cache.Notify(c)
+			// Pointer keys hashed by identity, not by their target.
+			p := &mutableKey{n: 1}
+			cache.Store(p, "v")
+			p.n = 2
+			v, ok := cache.Load(p)
+			assert.True(t, ok)
+			assert.Equal(t, "v", v)
 
-			cache.Load(1)
-			cache.StoreWithTTL(1, 0, time.Second)
-			cache.Peek(1)
-			cache.Delete(1)
-			close(c)
+			// The negative zero equals zero as a map key.
+			cache.Store(0.0, "zero")
+			v, ok = cache.Load(math.Copysign(0, -1))
+			assert.True(t, ok)
+			assert.Equal(t, "zero", v)
 
-			for e := range c {
-				t.Logf("Operation %s on Key %v \n", e.Op, e.Key)
-				got += e.Key.(int)
+			// Struct and array keys hashed field by field.
+			cache.Store([2]mutableKey{{1}, {2}}, "array")
+			assert.True(t, cache.Contains([2]mutableKey{{1}, {2}}))
+		})
+	}
+}
+
+func TestNewWithOptions(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"NewWithOptions", func(t *testing.T) {
+			evicted := make(chan interface{}, 1)
+			clock := newFakeClock()
+			cache := tt.cont.NewWithOptions(
+				0,
+				libcache.WithTTL(time.Second),
+				libcache.WithCapacity(2),
+				libcache.WithClock(clock),
+				libcache.WithOnEvicted(func(key, value interface{}) {
+					evicted <- key
+				}),
+			)
+			defer cache.Close()
+
+			assert.Equal(t, time.Second, cache.TTL())
+			assert.Equal(t, 2, cache.Cap())
+
+			cache.Store(1, 1)
+			exp, _ := cache.Expiry(1)
+			assert.Equal(t, clock.Now().UTC().Add(time.Second), exp)
+
+			cache.Delete(1)
+			select {
+			case k := <-evicted:
+				assert.Equal(t, 1, k)
+			case <-time.After(time.Second):
+				t.Fatal("expected on evicted to be called")
 			}
+		})
+	}
+}
+
+func TestWithOnEvictedOrder(t *testing.T) {
+	evicted := make(chan interface{}, 50)
+	cache := libcache.LRU.NewWithOptions(10, libcache.WithOnEvicted(func(key, value interface{}) {
+		evicted <- key
+	}))
+	defer cache.Close()
+
+	goroutines := runtime.NumGoroutine()
+	for i := 0; i < 60; i++ {
+		cache.Store(i, i)
+	}
+	assert.True(t, runtime.NumGoroutine() <= goroutines)
+
+	for i := 0; i < 50; i++ {
+		select {
+		case k := <-evicted:
+			assert.Equal(t, i, k)
+		case <-time.After(time.Second):
+			t.Fatal("expected on evicted to be called")
+		}
+	}
+}
+
+func TestWithOnEvictedSlowCallback(t *testing.T) {
+	const n = 500
+
+	var cache libcache.Cache
+	evicted := make(chan interface{}, n)
+	cache = libcache.LRU.NewWithOptions(1, libcache.WithOnEvicted(func(key, value interface{}) {
+		// The callback may call the cache, and lags behind the stores.
+		cache.Len()
+		time.Sleep(time.Microsecond * 10)
+		evicted <- key
+	}))
+	defer cache.Close()
+
+	for i := 0; i <= n; i++ {
+		cache.Store(i, i)
+	}
+
+	// Removals beyond the subscription buffer are queued, not dropped.
+	for i := 0; i < n; i++ {
+		select {
+		case k := <-evicted:
+			assert.Equal(t, i, k)
+		case <-time.After(time.Second):
+			t.Fatalf("expected on evicted to be called for %d", i)
+		}
+	}
+}
+
+func TestWithHooksOnEvictAfterReset(t *testing.T) {
+	evicted := make(chan interface{}, 10)
+	cache := libcache.LRU.NewWithOptions(1, libcache.WithHooks(libcache.Hooks{
+		OnEvict: func(key, value interface{}) {
+			evicted <- key
+		},
+	}))
+	defer cache.Close()
 
-			if tt.cont == libcache.ARC {
-				assert.Equal(t, 7, got)
-			} else {
-				assert.Equal(t, 4, got)
+	for i := 0; i < 3; i++ {
+		cache.Reset()
+		cache.Store(1, 1)
+		cache.Delete(1)
+
+		select {
+		case k := <-evicted:
+			assert.Equal(t, 1, k)
+		case <-time.After(time.Second):
+			t.Fatal("expected on evict to be called after reset")
+		}
+	}
+}
+
+func TestWithOnEvictedExpired(t *testing.T) {
+	evicted := make(chan interface{}, 10)
+	clock := newFakeClock()
+	cache := libcache.LRU.NewWithOptions(0, libcache.WithClock(clock), libcache.WithOnEvicted(func(key, value interface{}) {
+		evicted <- key
+	}))
+	defer cache.Close()
+
+	cache.StoreWithTTL(1, 1, time.Millisecond)
+	clock.Advance(time.Millisecond)
+	cache.GC()
+
+	select {
+	case k := <-evicted:
+		assert.Equal(t, 1, k)
+	case <-time.After(time.Second):
+		t.Fatal("expected on evicted to be called for the expired entry")
+	}
+}
+
+func TestWithOnEvictedClose(t *testing.T) {
+	for _, reset := range []bool{false, true} {
+		goroutines := runtime.NumGoroutine()
+		cache := libcache.LRU.NewWithOptions(1, libcache.WithOnEvicted(func(key, value interface{}) {}))
+		if reset {
+			cache.Reset()
+		}
+		cache.Close()
+
+		// Eventually calls the condition from its own goroutine.
+		assert.Eventually(t, func() bool {
+			return runtime.NumGoroutine() <= goroutines+1
+		}, time.Second, time.Millisecond*5)
+	}
+}
+
+func TestWithPanicHandler(t *testing.T) {
+	recovered := make(chan interface{}, 10)
+	cache := libcache.LRU.NewWithOptions(
+		1,
+		libcache.WithPanicHandler(func(r interface{}) {
+			recovered <- r
+		}),
+		libcache.WithOnEvicted(func(key, value interface{}) {
+			panic(key)
+		}),
+		libcache.WithHooks(libcache.Hooks{
+			AfterLoad: func(key interface{}, hit bool) {
+				panic("after load")
+			},
+		}),
+	)
+	defer cache.Close()
+
+	for i := 0; i < 3; i++ {
+		cache.Store(i, i)
+	}
+
+	// the callback goroutine survives the panics, and keeps receiving evictions.
+	for i := 0; i < 2; i++ {
+		select {
+		case r := <-recovered:
+			assert.Equal(t, i, r)
+		case <-time.After(time.Second):
+			t.Fatal("expected panic handler to be called")
+		}
+	}
+
+	v, ok := cache.Load(2)
+	assert.True(t, ok)
+	assert.Equal(t, 2, v)
+	assert.Equal(t, "after load", <-recovered)
+
+	// without a handler the panics propagate.
+	cache = libcache.LRU.NewWithOptions(1, libcache.WithHooks(libcache.Hooks{
+		BeforeStore: func(key interface{}) {
+			panic("before store")
+		},
+	}))
+	assert.PanicsWithValue(t, "before store", func() {
+		cache.Store(1, 1)
+	})
+}
+
+func TestTiered(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"Tiered", func(t *testing.T) {
+			l1 := tt.cont.New(2)
+			l2 := libcache.LRU.New(10)
+			cache := libcache.Tiered(l1, l2)
+
+			cache.Store(1, 1)
+			cache.Store(2, 2)
+			cache.Store(3, 3)
+
+			// l1 evicted key demoted into l2.
+			assert.Equal(t, 2, l1.Len())
+			assert.Equal(t, 1, l2.Len())
+			assert.Equal(t, 3, cache.Len())
+			assert.ElementsMatch(t, []interface{}{1, 2, 3}, cache.Keys())
+
+			evicted := l2.Keys()[0]
+
+			// promoted back on access.
+			v, ok := cache.Load(evicted)
+			assert.True(t, ok)
+			assert.Equal(t, evicted, v)
+			assert.True(t, l1.Contains(evicted))
+			assert.False(t, l2.Contains(evicted))
+			assert.Equal(t, 3, cache.Len())
+
+			// deleted keys are not demoted.
+			cache.Delete(evicted)
+			assert.Equal(t, 2, cache.Len())
+			assert.False(t, cache.Contains(evicted))
+		})
+	}
+}
+
+func TestTieredKeepsExpiry(t *testing.T) {
+	clock := newFakeClock()
+	l1 := libcache.LRU.NewWithOptions(1, libcache.WithClock(clock))
+	l2 := libcache.LRU.NewWithOptions(10, libcache.WithClock(clock))
+	l1.SetJitter(time.Second * 30)
+	l2.SetJitter(time.Second * 30)
+	cache := libcache.Tiered(l1, l2)
+
+	cache.StoreWithTTL(1, 1, time.Minute)
+	exp, _ := cache.Expiry(1)
+
+	// the demoted entry keeps its absolute expiry, without a second jitter.
+	cache.Store(2, 2)
+	clock.Advance(time.Second * 10)
+	got, ok := l2.Expiry(1)
+	assert.True(t, ok)
+	assert.Equal(t, exp, got)
+
+	// and so does the promoted entry.
+	cache.Load(1)
+	got, ok = l1.Expiry(1)
+	assert.True(t, ok)
+	assert.Equal(t, exp, got)
+}
+
+func TestTieredBulkEviction(t *testing.T) {
+	table := []struct {
+		name  string
+		setup func(c libcache.Cache)
+		evict func(c libcache.Cache)
+	}{
+		{
+			name:  "Resize",
+			evict: func(c libcache.Cache) { c.Resize(10) },
+		},
+		{
+			name:  "ResizeCost",
+			evict: func(c libcache.Cache) { c.ResizeCost(10) },
+		},
+		{
+			name:  "SetMaxKeyBytes",
+			evict: func(c libcache.Cache) { c.SetMaxKeyBytes(10 * internal.NonStringKeyBytes) },
+		},
+		{
+			name:  "ResumeEviction",
+			setup: func(c libcache.Cache) { c.PauseEviction() },
+			evict: func(c libcache.Cache) { c.ResumeEviction() },
+		},
+	}
+
+	for _, tt := range table {
+		t.Run("TestTieredBulkEviction"+tt.name, func(t *testing.T) {
+			l1 := libcache.LRU.New(1000)
+			l2 := libcache.LRU.New(1000)
+			cache := libcache.Tiered(l1, l2)
+			if tt.setup != nil {
+				tt.setup(cache)
+				l1.Resize(10)
 			}
 
-			// check it will not try to write on chan after ignore
-			cache.Ignore(c)
-			for i := 0; i < 10; i++ {
+			for i := 0; i < 200; i++ {
 				cache.Store(i, i)
 			}
+			tt.evict(cache)
+
+			// every l1 evicted entry demoted, none lost.
+			assert.Equal(t, 10, l1.Len())
+			assert.Equal(t, 190, l2.Len())
+			assert.Equal(t, 200, cache.Len())
 		})
 	}
 }
 
-func TestCacheGC(t *testing.T) {
-	for _, tt := range cacheTests {
-		t.Run("Test"+tt.cont.String()+"CacheGC", func(t *testing.T) {
-			cache := tt.cont.NewUnsafe(0)
-			cache.StoreWithTTL(0, 0, time.Nanosecond)
-			cache.StoreWithTTL(1, 1, time.Millisecond*100)
-			dur := cache.GC()
+func TestTieredConcurrentSubscriber(t *testing.T) {
+	const size = 10
 
-			assert.GreaterOrEqual(t, int64(dur), int64(time.Millisecond*99))
-			time.Sleep(dur)
+	for _, cont := range []libcache.ReplacementPolicy{libcache.LRU, libcache.ARC} {
+		t.Run("Test"+cont.String()+"TieredConcurrentSubscriber", func(t *testing.T) {
+			wg := sync.WaitGroup{}
+			cache := libcache.Tiered(cont.NewUnsafe(size), libcache.LRU.NewUnsafe(size*10))
+			done := make(chan struct{})
 
-			assert.Zero(t, int(cache.GC()))
-			assert.Zero(t, cache.Len())
+			go func() {
+				defer close(done)
+				for i := 0; i < 100; i++ {
+					ch, cancel := cache.Subscribe()
+					select {
+					case <-ch:
+					case <-time.After(time.Millisecond):
+					}
+					cancel()
+				}
+			}()
+
+			// the tiers are non-thread safe, so reads and configuration
+			// race the stores unless they hold the tiered lock too.
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < 1000; i++ {
+					cache.SetTTL(time.Hour)
+					cache.SetJitter(0)
+					_ = cache.TTL()
+					_ = cache.Cap()
+					_ = cache.Cost()
+					cache.PeekOldest()
+					cache.PeekNewest()
+				}
+			}()
+
+			for i := 0; i < 4; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := 0; i < 1000; i++ {
+						k := rand.Intn(size * 2)
+						cache.Store(k, k)
+						cache.Load(k)
+					}
+				}()
+			}
+
+			wg.Wait()
+			<-done
+			assert.Equal(t, size*2, cache.Len())
 		})
 	}
 }
 
-func TestGC(t *testing.T) {
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+func TestAssociativeMutatedPointerKey(t *testing.T) {
+	cache := libcache.NewAssociative(64, 2)
+
+	q := &mutableKey{n: 1}
+	cache.Store(q, 1)
+	q.n = 7
+	cache.Store(q, 2)
+
+	assert.Equal(t, 1, cache.Len())
+	v, ok := cache.Load(q)
+	assert.True(t, ok)
+	assert.Equal(t, 2, v)
+}
+
+func TestAssociative(t *testing.T) {
+	const sets, ways = 4, 2
+
+	set := func(key interface{}) uint64 {
+		return internal.Hash(key) % sets
+	}
+
+	cache := libcache.NewAssociative(sets, ways)
+	assert.Equal(t, sets*ways, cache.Cap())
 
+	// keys routed to the first set, and a key routed to another set.
+	var same []interface{}
+	var other interface{}
+	for i := 0; len(same) < 3 || other == nil; i++ {
+		if set(i) == 0 && len(same) < 3 {
+			same = append(same, i)
+		} else if set(i) != 0 && other == nil {
+			other = i
+		}
+	}
+
+	cache.Store(same[0], 0)
+	cache.Store(same[1], 1)
+	cache.Store(other, 2)
+	cache.Load(same[0])
+
+	// the set full, so its least recently used entry evicted,
+	// although the cache has room.
+	cache.Store(same[2], 3)
+	assert.Equal(t, 3, cache.Len())
+	assert.True(t, cache.Contains(same[0]))
+	assert.False(t, cache.Contains(same[1]))
+	assert.True(t, cache.Contains(same[2]))
+	assert.True(t, cache.Contains(other))
+
+	for i := 0; i < 1000; i++ {
+		cache.Store(i, i)
+		assert.True(t, cache.Len() <= sets*ways)
+	}
+
+	perSet := map[uint64]int{}
+	for _, k := range cache.Keys() {
+		perSet[set(k)]++
+	}
+
+	assert.Len(t, perSet, sets)
+	for _, n := range perSet {
+		assert.Equal(t, ways, n)
+	}
+
+	cache.Purge()
+	assert.Equal(t, 0, cache.Len())
+	assert.Empty(t, cache.Keys())
+}
+
+func TestMemoize(t *testing.T) {
+	calls := map[interface{}]int{}
 	cache := libcache.LRU.New(0)
-	go libcache.GC(ctx, cache)
+	square := libcache.Memoize(cache, func(arg interface{}) (interface{}, error) {
+		calls[arg]++
+		if arg.(int) < 0 {
+			return nil, errors.New("negative")
+		}
+		return arg.(int) * arg.(int), nil
+	})
 
-	cache.StoreWithTTL(1, 1, time.Millisecond*100)
-	time.Sleep(time.Millisecond * 150)
-	assert.Zero(t, cache.Len())
+	for i := 0; i < 3; i++ {
+		for _, arg := range []int{1, 2, 3} {
+			v, err := square(arg)
+			assert.NoError(t, err)
+			assert.Equal(t, arg*arg, v)
+		}
 
-	cache.StoreWithTTL(1, 1, time.Millisecond*100)
-	cache.StoreWithTTL(2, 2, time.Millisecond*200)
+		_, err := square(-1)
+		assert.Error(t, err)
+	}
 
-	time.Sleep(time.Millisecond * 150)
-	assert.Equal(t, 1, cache.Len())
+	assert.Equal(t, map[interface{}]int{1: 1, 2: 1, 3: 1, -1: 3}, calls)
+}
 
-	time.Sleep(time.Millisecond * 150)
-	assert.Zero(t, cache.Len())
+func TestMemoizeN(t *testing.T) {
+	calls := 0
+	cache := libcache.LRU.New(0)
+	join := libcache.MemoizeN(cache, func(args ...interface{}) (interface{}, error) {
+		calls++
+		return fmt.Sprint(args...), nil
+	})
+
+	for i := 0; i < 3; i++ {
+		for _, args := range [][]interface{}{{}, {nil}, {"a"}, {"a", "b"}, {"b", "a"}, {"a", "b", nil}} {
+			v, err := join(args...)
+			assert.NoError(t, err)
+			assert.Equal(t, fmt.Sprint(args...), v)
+		}
+	}
+
+	assert.Equal(t, 6, calls)
+	assert.Equal(t, 6, cache.Len())
+}
+
+func TestEnableAutoRefresh(t *testing.T) {
+	cache := libcache.LRU.New(0)
+	cache.SetTTL(time.Millisecond * 100)
+	cache.Store(1, 0)
+	exp, _ := cache.Expiry(1)
+
+	n := int32(0)
+	loader := func(key interface{}) (interface{}, error) {
+		return int(atomic.AddInt32(&n, 1)), nil
+	}
+
+	r := libcache.EnableAutoRefresh(cache, loader, time.Millisecond*50, 2)
+	defer r.Close()
+
+	for i := 0; i < 30; i++ {
+		_, ok := cache.Load(1)
+		assert.True(t, ok, "Expected auto refresh to prevent cache miss")
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	got, _ := cache.Expiry(1)
+	assert.True(t, got.After(exp))
+	assert.NotZero(t, atomic.LoadInt32(&n))
+}
+
+func TestEnableAutoRefreshCacheClock(t *testing.T) {
+	clock := newFakeClock()
+	cache := libcache.LRU.NewWithOptions(0, libcache.WithClock(clock), libcache.WithTTL(time.Hour))
+	defer cache.Close()
+	cache.Store(1, 0)
+
+	loader := func(key interface{}) (interface{}, error) {
+		return 1, nil
+	}
+
+	r := libcache.EnableAutoRefresh(cache, loader, time.Millisecond*50, 1)
+	defer r.Close()
+
+	// Only the cache clock brings the key within the lead time.
+	clock.Advance(time.Hour - time.Millisecond*10)
+	assert.Eventually(t, func() bool {
+		v, _ := cache.Peek(1)
+		return v == 1
+	}, time.Second, time.Millisecond*5)
+}
+
+func TestEnableAutoRefreshKeepsTTL(t *testing.T) {
+	cache := libcache.LRU.New(0)
+	defer cache.Close()
+	cache.SetKeyFunc(func(key interface{}) interface{} {
+		return string(key.([]byte))
+	})
+	cache.StoreWithTTL([]byte("k"), 0, time.Millisecond*100)
+
+	n := int32(0)
+	loader := func(key interface{}) (interface{}, error) {
+		return int(atomic.AddInt32(&n, 1)), nil
+	}
+
+	r := libcache.EnableAutoRefresh(cache, loader, time.Millisecond*50, 2)
+	defer r.Close()
+
+	// The non comparable key deduped by its normalized form,
+	// and the entry keeps expiring, although the cache has no default TTL.
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&n) > 0
+	}, time.Second, time.Millisecond*5)
+
+	got, ok := cache.Expiry([]byte("k"))
+	assert.True(t, ok)
+	assert.False(t, got.IsZero())
+	assert.WithinDuration(t, time.Now().Add(time.Millisecond*100), got, time.Millisecond*100)
+}
+
+func TestEnableAutoRefreshDeleted(t *testing.T) {
+	cache := libcache.LRU.New(0)
+	defer cache.Close()
+	cache.StoreWithTTL(1, 0, time.Millisecond*60)
+
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	loader := func(key interface{}) (interface{}, error) {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-release
+		return 1, nil
+	}
+
+	r := libcache.EnableAutoRefresh(cache, loader, time.Millisecond*50, 1)
+	defer r.Close()
+
+	<-started
+	cache.Delete(1)
+	close(release)
+
+	assert.Never(t, func() bool {
+		return cache.Contains(1)
+	}, time.Millisecond*100, time.Millisecond*5)
+}
+
+func TestEnableAutoRefreshInvalidArgs(t *testing.T) {
+	loader := func(key interface{}) (interface{}, error) {
+		return nil, nil
+	}
+
+	assert.Panics(t, func() {
+		libcache.EnableAutoRefresh(libcache.LRU.New(0), loader, 0, 1)
+	})
+	assert.Panics(t, func() {
+		libcache.EnableAutoRefresh(libcache.LRU.New(0), loader, time.Second, 0)
+	})
+}
+
+func TestNewWithJanitor(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"NewWithJanitor", func(t *testing.T) {
+			cache := tt.cont.NewWithJanitor(0, time.Millisecond*10)
+			defer cache.Close()
+
+			cache.StoreWithTTL(1, 1, time.Millisecond*20)
+			cache.StoreWithTTL(2, 2, time.Hour)
+			time.Sleep(time.Millisecond * 50)
+
+			assert.Equal(t, 1, cache.Len())
+		})
+	}
+}
+
+func TestCacheConcurrentReads(t *testing.T) {
+	for _, tt := range cacheTests {
+		t.Run("Test"+tt.cont.String()+"CacheConcurrentReads", func(t *testing.T) {
+			wg := sync.WaitGroup{}
+			cache := tt.cont.New(100)
+
+			for i := 0; i < 4; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					for j := 0; j < 100; j++ {
+						if i == 0 {
+							cache.Store(j, j)
+							continue
+						}
+
+						assert.LessOrEqual(t, len(cache.Keys()), cache.Cap())
+						assert.LessOrEqual(t, cache.Len(), cache.Cap())
+						assert.Equal(t, time.Duration(0), cache.TTL())
+					}
+				}(i)
+			}
+
+			wg.Wait()
+			assert.Equal(t, 100, cache.Len())
+		})
+	}
+}
+
+func BenchmarkCacheReads(b *testing.B) {
+	table := []struct {
+		name string
+		read func(c libcache.Cache, key int)
+	}{
+		{
+			// Read locked.
+			name: "Len",
+			read: func(c libcache.Cache, _ int) {
+				_ = c.Len()
+				_ = c.Cap()
+				_ = c.TTL()
+			},
+		},
+		{
+			// Write locked, as it collects the key entry if expired.
+			name: "Peek",
+			read: func(c libcache.Cache, key int) {
+				_, _ = c.Peek(key)
+			},
+		},
+		{
+			// Read locked, the Peek alternative.
+			name: "ContainsNoGC",
+			read: func(c libcache.Cache, key int) {
+				_ = c.ContainsNoGC(key)
+			},
+		},
+	}
+
+	for _, tt := range cacheTests {
+		for _, r := range table {
+			b.Run("Benchmark"+tt.cont.String()+"CacheReads"+r.name, func(b *testing.B) {
+				cache := tt.cont.New(0)
+
+				for i := 0; i < 100; i++ {
+					cache.Store(i, i)
+				}
+
+				b.ResetTimer()
+				b.RunParallel(func(pb *testing.PB) {
+					for i := 0; pb.Next(); i++ {
+						r.read(cache, i%100)
+					}
+				})
+			})
+		}
+	}
+}
+
+func BenchmarkCacheIterator(b *testing.B) {
+	cache := libcache.LRU.New(0)
+	for i := 0; i < 1000; i++ {
+		cache.Store(i, i)
+	}
+
+	b.Run("Keys", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for _, k := range cache.Keys() {
+				_, _ = cache.Peek(k)
+			}
+		}
+	})
+
+	b.Run("Iterator", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			it := cache.Iterator()
+			for it.Next() {
+				_, _ = it.Key(), it.Value()
+			}
+		}
+	})
 }
 
 func BenchmarkCache(b *testing.B) {
@@ -385,3 +4351,36 @@ func BenchmarkCache(b *testing.B) {
 		})
 	}
 }
+
+func TestFrequency(t *testing.T) {
+	table := []struct {
+		cont   libcache.ReplacementPolicy
+		expect int
+		ok     bool
+	}{
+		{cont: libcache.LFU, expect: 3, ok: true},
+		{cont: libcache.LRU, expect: 0, ok: true},
+		{cont: libcache.ARC, expect: 2, ok: true},
+	}
+
+	for _, tt := range table {
+		t.Run("Test"+tt.cont.String()+"CacheFrequency", func(t *testing.T) {
+			cache := tt.cont.New(0)
+			f, ok := cache.(libcache.Frequencyer)
+			assert.True(t, ok)
+
+			cache.Store(1, 1)
+			for i := 0; i < 3; i++ {
+				cache.Load(1)
+			}
+
+			n, ok := f.Frequency(1)
+			assert.Equal(t, tt.expect, n)
+			assert.Equal(t, tt.ok, ok)
+
+			n, ok = f.Frequency(2)
+			assert.Equal(t, 0, n)
+			assert.False(t, ok)
+		})
+	}
+}
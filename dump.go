@@ -0,0 +1,292 @@
+package libcache
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"time"
+)
+
+// defaultBatch is the number of records per batch written by DumpStream,
+// when a non-positive batch given.
+const defaultBatch = 1024
+
+// Record represents a single cache entry serialized by Dump.
+type Record struct {
+	Key   interface{}
+	Value interface{}
+	// Expiry is the entry absolute expiry time, zero for entries never expires.
+	Expiry time.Time
+}
+
+// Codec serializes the cache records written by Dump and read by Restore.
+type Codec interface {
+	Marshal([]Record) ([]byte, error)
+	Unmarshal([]byte) ([]Record, error)
+}
+
+// GobCodec is a Codec using encoding/gob, the default codec of Dump and Restore.
+//
+// Keys and values are encoded as interfaces, therefore the caller must
+// register their concrete types using gob.Register.
+type GobCodec struct{}
+
+// Marshal returns the gob encoding of records.
+func (GobCodec) Marshal(records []Record) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	err := gob.NewEncoder(buf).Encode(records)
+	return buf.Bytes(), err
+}
+
+// Unmarshal parses the gob encoded records.
+func (GobCodec) Unmarshal(data []byte) ([]Record, error) {
+	records := []Record{}
+	err := gob.NewDecoder(bytes.NewReader(data)).Decode(&records)
+	return records, err
+}
+
+// JSONCodec is a Codec using encoding/json.
+//
+// Keys and values are decoded as the encoding/json generic types,
+// so numbers decoded as float64, and objects and arrays decoded as
+// map[string]interface{} and []interface{}. Hence, keys must be strings,
+// numbers or booleans to round trip, and numeric keys restored as float64 keys.
+// Unmarshal returns an error for objects and arrays keys as they are not comparable.
+type JSONCodec struct{}
+
+// Marshal returns the json encoding of records.
+func (JSONCodec) Marshal(records []Record) ([]byte, error) {
+	return json.Marshal(records)
+}
+
+// Unmarshal parses the json encoded records.
+func (JSONCodec) Unmarshal(data []byte) ([]Record, error) {
+	records := []Record{}
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, err
+	}
+
+	for _, r := range records {
+		switch r.Key.(type) {
+		case map[string]interface{}, []interface{}:
+			return nil, fmt.Errorf("libcache: json record key %v is not comparable", r.Key)
+		}
+	}
+
+	return records, nil
+}
+
+// Dump writes the key, value and absolute expiry of every non-expired
+// cache entry to w using the GobCodec.
+//
+// Keys and values are encoded as interfaces, therefore the caller must
+// register their concrete types using gob.Register before calling Dump.
+func Dump(cache Cache, w io.Writer) error {
+	return DumpWithCodec(cache, w, GobCodec{})
+}
+
+// DumpWithCodec writes the key, value and absolute expiry of every non-expired
+// cache entry to w using the given codec.
+//
+// The records read from a single cache Iterator, so they are a consistent snapshot
+// of the thread safe cache, and dumping neither emits Read events nor counts lookups.
+func DumpWithCodec(cache Cache, w io.Writer, codec Codec) error {
+	records := []Record{}
+	for it := cache.Iterator(); it.Next(); {
+		records = append(records, Record{Key: it.Key(), Value: it.Value(), Expiry: it.Expiry()})
+	}
+
+	data, err := codec.Marshal(records)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(data)
+	return err
+}
+
+// DumpStream writes the key, value and absolute expiry of every non-expired
+// cache entry to w in batches of the given number of records,
+// holding the cache Iterator snapshot and a single encoded batch in memory
+// instead of the whole encoded entries.
+// Non-positive batch defaults to 1024 records.
+//
+// The stream is a sequence of length-prefixed batches, terminated by a zero length:
+//
+//	stream = *(length batch) 0x00
+//	length = uvarint, the batch size in bytes
+//	batch  = the GobCodec encoding of []Record
+//
+// Each batch encoded by its own gob encoder, so it decodable on its own,
+// and a reader can process the stream incrementally or resume it at any batch.
+//
+// Keys and values are encoded as interfaces, therefore the caller must
+// register their concrete types using gob.Register before calling DumpStream.
+func DumpStream(cache Cache, w io.Writer, batch int) error {
+	if batch <= 0 {
+		batch = defaultBatch
+	}
+
+	var (
+		prefix  [binary.MaxVarintLen64]byte
+		records = make([]Record, 0, batch)
+	)
+
+	flush := func() error {
+		var (
+			data []byte
+			err  error
+		)
+
+		if len(records) > 0 {
+			if data, err = (GobCodec{}).Marshal(records); err != nil {
+				return err
+			}
+		}
+
+		n := binary.PutUvarint(prefix[:], uint64(len(data)))
+		if _, err := w.Write(prefix[:n]); err != nil {
+			return err
+		}
+
+		_, err = w.Write(data)
+		records = records[:0]
+		return err
+	}
+
+	for it := cache.Iterator(); it.Next(); {
+		records = append(records, Record{Key: it.Key(), Value: it.Value(), Expiry: it.Expiry()})
+
+		if len(records) == batch {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(records) > 0 {
+		if err := flush(); err != nil {
+			return err
+		}
+	}
+
+	// The zero length terminates the stream.
+	return flush()
+}
+
+// Restore reads the entries previously written by Dump from r,
+// and stores them into the given cache.
+//
+// Each entry stored with its absolute expiry, so its remaining TTL follows the cache clock,
+// and entries already expired by the cache clock are skipped.
+//
+// Keys and values are decoded as interfaces, therefore the caller must
+// register their concrete types using gob.Register before calling Restore.
+func Restore(cache Cache, r io.Reader) error {
+	return RestoreWithCodec(cache, r, GobCodec{})
+}
+
+// RestoreWithCodec reads the entries previously written by DumpWithCodec from r
+// using the given codec, and stores them into the given cache.
+//
+// Each entry stored with its absolute expiry, so its remaining TTL follows the cache clock,
+// and entries already expired by the cache clock are skipped.
+func RestoreWithCodec(cache Cache, r io.Reader, codec Codec) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	records, err := codec.Unmarshal(data)
+	if err != nil {
+		return err
+	}
+
+	restore(cache, records)
+	return nil
+}
+
+// RestoreStream reads the batches previously written by DumpStream from r,
+// and stores each batch entries into the given cache as it arrives,
+// holding a single batch in memory instead of the whole entries.
+//
+// Each entry stored with its absolute expiry, so its remaining TTL follows the cache clock,
+// and entries already expired by the cache clock are skipped.
+// RestoreStream returns io.ErrUnexpectedEOF if r ends before the stream terminated,
+// the batches read by then are kept in the cache.
+//
+// Keys and values are decoded as interfaces, therefore the caller must
+// register their concrete types using gob.Register before calling RestoreStream.
+func RestoreStream(cache Cache, r io.Reader) error {
+	br, ok := r.(io.ByteReader)
+	if !ok {
+		b := bufio.NewReader(r)
+		r, br = b, b
+	}
+
+	var buf []byte
+
+	for {
+		n, err := binary.ReadUvarint(br)
+		if errors.Is(err, io.EOF) {
+			return io.ErrUnexpectedEOF
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if n == 0 {
+			return nil
+		}
+
+		if uint64(cap(buf)) < n {
+			buf = make([]byte, n)
+		}
+
+		buf = buf[:n]
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return err
+		}
+
+		records, err := (GobCodec{}).Unmarshal(buf)
+		if err != nil {
+			return err
+		}
+
+		restore(cache, records)
+	}
+}
+
+// nower is implemented by the caches that expose their clock current time.
+type nower interface {
+	lockedNow() time.Time
+}
+
+// lockedNow returns the cache clock current time, while c.mu read lock held.
+func (c *cache) lockedNow() time.Time {
+	c.mu.RLock()
+	now := c.now()
+	c.mu.RUnlock()
+	return now
+}
+
+// restore stores the records into the given cache, skipping the expired ones.
+// Caches that do not expose their clock, e.g. the tiered cache,
+// collect the expired records as the next cache operation runs.
+func restore(cache Cache, records []Record) {
+	n, ok := cache.(nower)
+	for _, r := range records {
+		if ok && !r.Expiry.IsZero() && !n.lockedNow().Before(r.Expiry) {
+			continue
+		}
+
+		cache.StoreWithDeadline(r.Key, r.Value, r.Expiry)
+	}
+}
@@ -0,0 +1,123 @@
+package libcache
+
+import "github.com/shaj13/libcache/internal"
+
+// forwarder relays the events of a NotifyBlocking channel from a dedicated goroutine,
+// and queues them in order while the receiver is slow,
+// so the receiver never stalls the thread safe cache lock.
+type forwarder struct {
+	// in is registered in the unsafe cache instead of the receiver channel.
+	in     chan Event
+	done   chan struct{}
+	exited chan struct{}
+}
+
+func newForwarder(out chan<- Event) *forwarder {
+	f := &forwarder{
+		in:     make(chan Event, internal.SubscriptionBuffer),
+		done:   make(chan struct{}),
+		exited: make(chan struct{}),
+	}
+
+	go f.run(out)
+	return f
+}
+
+func (f *forwarder) run(out chan<- Event) {
+	defer close(f.exited)
+
+	var queue []Event
+	for {
+		// A nil send channel disables its case while the queue empty.
+		var (
+			send chan<- Event
+			next Event
+		)
+
+		if len(queue) > 0 {
+			send, next = out, queue[0]
+		}
+
+		select {
+		case e := <-f.in:
+			queue = append(queue, e)
+		case send <- next:
+			queue[0] = Event{}
+			queue = queue[1:]
+		case <-f.done:
+			return
+		}
+	}
+}
+
+// stop stops the forwarder goroutine and drops the queued events,
+// it waits for the goroutine to exit, so the receiver channel can be closed once it returns.
+func (f *forwarder) stop() {
+	close(f.done)
+	<-f.exited
+}
+
+// forward registers a forwarder of ch in the unsafe cache,
+// replacing the previous one if any, forward must be called while holding the cache lock.
+// forward does nothing once the cache closed, as nothing would stop the forwarder.
+func (c *cache) forward(ch chan<- Event, ops ...Op) {
+	if c.closed() {
+		return
+	}
+
+	c.unforward(ch)
+
+	if c.forwarders == nil {
+		c.forwarders = make(map[chan<- Event]*forwarder)
+	}
+
+	f := newForwarder(ch)
+	c.forwarders[ch] = f
+	c.unsafe.NotifyBlocking(f.in, ops...)
+}
+
+// unforward unregisters and stops the forwarder of ch if any,
+// unforward must be called while holding the cache lock.
+func (c *cache) unforward(ch chan<- Event) {
+	f, ok := c.forwarders[ch]
+	if !ok {
+		return
+	}
+
+	c.unsafe.Ignore(f.in)
+	f.stop()
+	delete(c.forwarders, ch)
+}
+
+// stopForwarders stops all the forwarders,
+// stopForwarders must be called while holding the cache lock.
+func (c *cache) stopForwarders() {
+	for ch := range c.forwarders {
+		c.unforward(ch)
+	}
+}
+
+// listen registers a forwarder of ch like NotifyBlocking, that Reset registers again,
+// and returns the channel closed once the cache closed, so the listener unregisters ch by Ignore.
+func (c *cache) listen(ch chan<- Event, ops ...Op) <-chan struct{} {
+	c.mu.Lock()
+	if c.listeners == nil {
+		c.listeners = make(map[chan<- Event][]Op)
+	}
+
+	c.listeners[ch] = ops
+	c.forward(ch, ops...)
+	c.mu.Unlock()
+	return c.done
+}
+
+// closed reports whether the cache closed,
+// closed must be called while holding the cache lock.
+func (c *cache) closed() bool {
+	select {
+	case <-c.done:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,305 @@
+package libcache
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/shaj13/libcache/internal"
+)
+
+// frozen is a read-only cache, populated once from a cache snapshot.
+// It never mutates after its creation, therefore it safe for concurrent reads
+// without locking. Its write operations are no-ops inherited from idle.
+type frozen struct {
+	idle
+	entries map[interface{}]EntryInfo
+	keyFunc KeyFunc
+	clock   Clock
+	iter    Iterator
+	cap     int
+	ttl     time.Duration
+	policy  ReplacementPolicy
+}
+
+func (c *cache) Freeze() Cache {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	f := &frozen{
+		entries: make(map[interface{}]EntryInfo),
+		keyFunc: c.keyFunc,
+		clock:   c.clock,
+		iter:    *c.unsafe.Iterator(),
+		cap:     c.unsafe.Cap(),
+		ttl:     c.unsafe.TTL(),
+		policy:  c.policy,
+	}
+
+	// ColdestN keeps the negative entries, which Keys excludes,
+	// so the frozen GetOrCompute still honors them.
+	for _, e := range c.unsafe.ColdestN(c.unsafe.Len()) {
+		f.entries[c.keyFunc.Key(e.Key)] = e
+	}
+
+	return f
+}
+
+// Policy returns the replacement policy of the cache frozen from.
+func (f *frozen) Policy() ReplacementPolicy {
+	return f.policy
+}
+
+// get returns the key entry if not expired, negative entries included.
+func (f *frozen) get(key interface{}) (EntryInfo, bool) {
+	e, ok := f.entries[f.keyFunc.Key(key)]
+	if !ok || !f.live(e) {
+		return EntryInfo{}, false
+	}
+	return e, true
+}
+
+func (f *frozen) live(e EntryInfo) bool {
+	return e.Expiry.IsZero() || f.now().Before(e.Expiry)
+}
+
+// visible reports whether the entry is live and not negative,
+// as reads and enumerations never expose negative entries.
+func (f *frozen) visible(e EntryInfo) bool {
+	return f.live(e) && !internal.IsNegative(e.Value)
+}
+
+// lookup returns the key entry if visible.
+func (f *frozen) lookup(key interface{}) (EntryInfo, bool) {
+	e, ok := f.get(key)
+	if !ok || internal.IsNegative(e.Value) {
+		return EntryInfo{}, false
+	}
+	return e, true
+}
+
+func (f *frozen) now() time.Time {
+	if f.clock == nil {
+		return time.Now()
+	}
+	return f.clock.Now()
+}
+
+func (f *frozen) Load(key interface{}) (interface{}, bool) {
+	e, ok := f.lookup(key)
+	return e.Value, ok
+}
+
+func (f *frozen) LoadWithExpiry(key interface{}) (interface{}, time.Time, bool) {
+	e, ok := f.lookup(key)
+	return e.Value, e.Expiry, ok
+}
+
+func (f *frozen) Peek(key interface{}) (interface{}, bool) {
+	return f.Load(key)
+}
+
+func (f *frozen) Test(key interface{}, pred func(value interface{}) bool) (bool, bool) {
+	v, ok := f.Load(key)
+	if !ok {
+		return false, ok
+	}
+	return pred(v), ok
+}
+
+func (f *frozen) LoadMany(keys ...interface{}) map[interface{}]interface{} {
+	items := make(map[interface{}]interface{})
+	for _, k := range keys {
+		if v, ok := f.Load(k); ok {
+			items[f.keyFunc.Key(k)] = v
+		}
+	}
+	return items
+}
+
+func (f *frozen) GetEntry(key interface{}) (EntryInfo, bool) {
+	return f.get(key)
+}
+
+func (f *frozen) Contains(key interface{}) bool {
+	_, ok := f.lookup(key)
+	return ok
+}
+
+func (f *frozen) ContainsNoGC(key interface{}) bool {
+	return f.Contains(key)
+}
+
+func (f *frozen) ContainsMany(keys []interface{}) []bool {
+	flags := make([]bool, len(keys))
+	for i, k := range keys {
+		flags[i] = f.Contains(k)
+	}
+	return flags
+}
+
+func (f *frozen) Expiry(key interface{}) (time.Time, bool) {
+	e, ok := f.lookup(key)
+	return e.Expiry, ok
+}
+
+func (f *frozen) RemainingTTL(key interface{}) (time.Duration, bool) {
+	e, ok := f.lookup(key)
+	if !ok || e.Expiry.IsZero() {
+		return 0, ok
+	}
+	return e.Expiry.Sub(f.now()), ok
+}
+
+// GetOrCompute returns the key value if exist, Otherwise,
+// it returns the loader result without storing it.
+func (f *frozen) GetOrCompute(key interface{}, loader Loader) (interface{}, error) {
+	e, ok := f.get(key)
+	if !ok {
+		return loader(key)
+	}
+
+	if internal.IsNegative(e.Value) {
+		return nil, ErrNotFound
+	}
+
+	return e.Value, nil
+}
+
+func (f *frozen) LoadCtx(ctx context.Context, key interface{}, loader func(context.Context) (interface{}, error)) (interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return f.GetOrCompute(key, internal.ContextLoader(ctx, loader))
+}
+
+func (f *frozen) Keys() (keys []interface{}) {
+	for _, e := range f.entries {
+		if f.visible(e) {
+			keys = append(keys, e.Key)
+		}
+	}
+	return
+}
+
+// OrderedKeys returns the keys in unspecified order, as frozen caches never evict.
+func (f *frozen) OrderedKeys() []interface{} {
+	return f.Keys()
+}
+
+// ColdestN returns up to n entries in unspecified order, as frozen caches never evict.
+func (f *frozen) ColdestN(n int) (infos []EntryInfo) {
+	for _, e := range f.entries {
+		if len(infos) >= n {
+			break
+		}
+		if f.live(e) {
+			infos = append(infos, e)
+		}
+	}
+	return
+}
+
+// HottestN returns up to n entries in unspecified order, as frozen caches never evict.
+func (f *frozen) HottestN(n int) []EntryInfo {
+	return f.ColdestN(n)
+}
+
+func (f *frozen) KeysByExpiry() []interface{} {
+	return internal.SortByExpiry(f.ExpiryPairs())
+}
+
+func (f *frozen) ExpiringWithin(d time.Duration) []interface{} {
+	return internal.SortByExpiry(f.ExpiryPairsWithin(d))
+}
+
+// ExpiryPairs returns all the live keys paired with their expiry, in unspecified order.
+func (f *frozen) ExpiryPairs() []internal.KeyExpiry {
+	return f.expiryPairs(time.Time{})
+}
+
+// ExpiryPairsWithin returns the live keys expiring within d from now paired with their expiry,
+// in unspecified order.
+func (f *frozen) ExpiryPairsWithin(d time.Duration) []internal.KeyExpiry {
+	return f.expiryPairs(f.now().Add(d))
+}
+
+// expiryPairs returns the live keys expiring by the given time paired with their expiry,
+// zero time means all the live keys.
+func (f *frozen) expiryPairs(by time.Time) []internal.KeyExpiry {
+	pairs := []internal.KeyExpiry{}
+	for _, e := range f.entries {
+		if f.visible(e) && (by.IsZero() || (!e.Expiry.IsZero() && !e.Expiry.After(by))) {
+			pairs = append(pairs, internal.KeyExpiry{Key: e.Key, Expiry: e.Expiry})
+		}
+	}
+	return pairs
+}
+
+func (f *frozen) KeysWithPrefix(prefix string) (keys []interface{}) {
+	for _, e := range f.entries {
+		if s, ok := e.Key.(string); ok && strings.HasPrefix(s, prefix) && f.visible(e) {
+			keys = append(keys, e.Key)
+		}
+	}
+	return
+}
+
+func (f *frozen) Snapshot() map[interface{}]interface{} {
+	m := make(map[interface{}]interface{}, len(f.entries))
+	for k, e := range f.entries {
+		if f.visible(e) {
+			m[k] = e.Value
+		}
+	}
+	return m
+}
+
+// Iterator returns a copy of the iterator taken at freeze time,
+// as iterators snapshot their entries.
+func (f *frozen) Iterator() *Iterator {
+	it := f.iter
+	return &it
+}
+
+// Len returns the number of live entries, negative entries included, as in the cache frozen from.
+func (f *frozen) Len() (n int) {
+	for _, e := range f.entries {
+		if f.live(e) {
+			n++
+		}
+	}
+	return
+}
+
+func (f *frozen) Cap() int {
+	return f.cap
+}
+
+func (f *frozen) TTL() time.Duration {
+	return f.ttl
+}
+
+func (f *frozen) Cost() (cost int64) {
+	for _, e := range f.entries {
+		if f.live(e) {
+			cost += e.Cost
+		}
+	}
+	return
+}
+
+func (f *frozen) Utilization() float64 {
+	if f.cap == 0 {
+		return 0
+	}
+	return float64(f.Len()) / float64(f.cap)
+}
+
+func (f *frozen) Metrics() Metrics {
+	return Metrics{
+		Len:  f.Len(),
+		Cap:  f.cap,
+		Cost: f.Cost(),
+	}
+}
@@ -0,0 +1,196 @@
+// Package gdsf implements a GDSF (Greedy Dual Size Frequency) cache.
+package gdsf
+
+import (
+	"container/heap"
+	"sort"
+
+	"github.com/shaj13/libcache"
+	"github.com/shaj13/libcache/internal"
+)
+
+func init() {
+	libcache.GDSF.Register(New)
+}
+
+// New returns a new non-thread safe cache.
+//
+// The entries size is their cost computed by the cache weigher,
+// therefore without a weigher all entries have the same size,
+// and the cache discards the least frequently used entries first.
+func New(cap int) libcache.Cache {
+	col := &collection{}
+	col.Init()
+	return cache{internal.New(col, cap)}
+}
+
+// cache is the internal cache, that reports the GDSF replacement policy.
+type cache struct {
+	*internal.Cache
+}
+
+// Policy returns libcache.GDSF.
+func (cache) Policy() libcache.ReplacementPolicy {
+	return libcache.GDSF
+}
+
+type element struct {
+	value *internal.Entry
+	freq  int
+	// priority is the element H value, clock + freq / size.
+	priority float64
+	// seq breaks priority ties, the least recently used discarded first.
+	seq   uint64
+	index int
+}
+
+// collection is a GDSF, it discards the entry with the lowest priority,
+// computed as clock + frequency * cost / size, with a uniform retrieval cost of 1,
+// and advances the clock to the discarded entry priority,
+// so entries that stopped being accessed eventually age out.
+//
+// An entry priority computed when added or accessed,
+// so a size changed by Update takes effect on the entry next access.
+type collection struct {
+	clock float64
+	seq   uint64
+	pq    priorityQueue
+}
+
+func (c *collection) prioritize(ele *element) {
+	size := float64(ele.value.Cost)
+	if size <= 0 {
+		size = 1
+	}
+
+	c.seq++
+	ele.seq = c.seq
+	ele.priority = c.clock + float64(ele.freq)/size
+}
+
+func (c *collection) Move(e *internal.Entry) {
+	ele := e.Element.(*element)
+	if ele.index < 0 {
+		return
+	}
+
+	ele.freq++
+	c.prioritize(ele)
+	heap.Fix(&c.pq, ele.index)
+}
+
+func (c *collection) Add(e *internal.Entry) {
+	ele := &element{value: e, freq: 1}
+	e.Element = ele
+	c.prioritize(ele)
+	heap.Push(&c.pq, ele)
+}
+
+func (c *collection) Remove(e *internal.Entry) {
+	if ele := e.Element.(*element); ele.index >= 0 {
+		heap.Remove(&c.pq, ele.index)
+	}
+}
+
+func (c *collection) Discard() *internal.Entry {
+	if len(c.pq) == 0 {
+		return nil
+	}
+
+	ele := heap.Pop(&c.pq).(*element)
+	c.clock = ele.priority
+	return ele.value
+}
+
+func (c *collection) Front() *internal.Entry {
+	if len(c.pq) == 0 {
+		return nil
+	}
+	return c.pq[0].value
+}
+
+// Back returns the entry with the highest priority, in O(n).
+func (c *collection) Back() *internal.Entry {
+	var last *element
+	for _, ele := range c.pq {
+		if last == nil || c.pq.less(last, ele) {
+			last = ele
+		}
+	}
+
+	if last == nil {
+		return nil
+	}
+
+	return last.value
+}
+
+// Walk walks the entries from the lowest to the highest priority, in O(n log n).
+func (c *collection) Walk(fn func(*internal.Entry)) {
+	pq := make([]*element, len(c.pq))
+	copy(pq, c.pq)
+	sort.Slice(pq, func(i, j int) bool {
+		return c.pq.less(pq[i], pq[j])
+	})
+
+	for _, ele := range pq {
+		fn(ele.value)
+	}
+}
+
+// Frequency returns the number of times the entry accessed.
+func (c *collection) Frequency(e *internal.Entry) int {
+	if ele, ok := e.Element.(*element); ok {
+		return ele.freq - 1
+	}
+	return 0
+}
+
+func (c *collection) Len() int {
+	return len(c.pq)
+}
+
+func (c *collection) Init() {
+	c.pq = nil
+	c.clock = 0
+}
+
+// priorityQueue is a min-heap of elements ordered by their priority.
+type priorityQueue []*element
+
+func (pq priorityQueue) less(a, b *element) bool {
+	if a.priority == b.priority {
+		return a.seq < b.seq
+	}
+	return a.priority < b.priority
+}
+
+func (pq priorityQueue) Len() int {
+	return len(pq)
+}
+
+func (pq priorityQueue) Less(i, j int) bool {
+	return pq.less(pq[i], pq[j])
+}
+
+func (pq priorityQueue) Swap(i, j int) {
+	pq[i], pq[j] = pq[j], pq[i]
+	pq[i].index = i
+	pq[j].index = j
+}
+
+func (pq *priorityQueue) Push(x interface{}) {
+	ele := x.(*element)
+	ele.index = len(*pq)
+	*pq = append(*pq, ele)
+}
+
+func (pq *priorityQueue) Pop() interface{} {
+	old := *pq
+	n := len(old)
+	ele := old[n-1]
+	old[n-1] = nil
+	ele.index = -1
+	*pq = old[:n-1]
+	return ele
+}
@@ -0,0 +1,60 @@
+package gdsf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/shaj13/libcache/internal"
+)
+
+func TestCollection(t *testing.T) {
+	entries := []*internal.Entry{
+		{Key: 1, Cost: 1},
+		{Key: 2, Cost: 4},
+		{Key: 3, Cost: 1},
+	}
+
+	c := &collection{}
+	c.Init()
+
+	for _, e := range entries {
+		c.Add(e)
+	}
+
+	c.Move(entries[0])
+
+	assert.Equal(t, 3, c.Len())
+	assert.Equal(t, 2, c.Front().Key)
+	assert.Equal(t, 1, c.Back().Key)
+	assert.Equal(t, 1, c.Frequency(entries[0]))
+
+	assert.Equal(t, 2, c.Discard().Key)
+	// clock advanced to the discarded entry priority.
+	assert.Equal(t, 0.25, c.clock)
+
+	c.Remove(entries[2])
+	assert.Equal(t, 1, c.Discard().Key)
+	assert.Nil(t, c.Discard())
+}
+
+func TestSmallFrequentOutlivesLargeRare(t *testing.T) {
+	cache := New(3)
+	cache.SetWeigher(func(key, value interface{}) int64 {
+		return int64(len(value.(string)))
+	})
+
+	cache.Store("large", "llllllllllllllllllll")
+	cache.Store("small", "s")
+	for i := 0; i < 3; i++ {
+		cache.Load("small")
+	}
+	cache.Load("large")
+
+	cache.Store("a", "a")
+	cache.Store("b", "b")
+	cache.Store("c", "c")
+
+	assert.False(t, cache.Contains("large"))
+	assert.True(t, cache.Contains("small"))
+}
@@ -0,0 +1,74 @@
+package libcache
+
+// Hooks are optional callbacks invoked around the cache operations,
+// as integration points for tracing, such as OpenTelemetry spans,
+// without the cache depending on a tracing library. Nil callbacks are skipped.
+//
+// BeforeLoad and AfterLoad wrap Load and Peek, and BeforeStore and AfterStore wrap
+// Store, StoreWithTTL, StoreWithTTLJitter, StoreWithDeadline and StoreWithTags.
+// They run on the calling goroutine without holding the cache lock,
+// so they may call the cache, and their panics routed to the handler set by WithPanicHandler.
+type Hooks struct {
+	// BeforeLoad called with the key before it looked up.
+	BeforeLoad func(key interface{})
+	// AfterLoad called with the key after it looked up, and whether it found.
+	AfterLoad func(key interface{}, hit bool)
+	// BeforeStore called with the key before its value stored.
+	BeforeStore func(key interface{})
+	// AfterStore called with the key after its value stored.
+	AfterStore func(key interface{})
+	// OnEvict called with the key and value of each removed or expired entry,
+	// in the same manner as WithOnEvicted.
+	OnEvict func(key, value interface{})
+}
+
+// WithHooks installs the given hooks, it has effect only on caches
+// returned by the ReplacementPolicy New methods.
+func WithHooks(h Hooks) Option {
+	return func(c Cache) {
+		sc, ok := c.(*cache)
+		if !ok {
+			return
+		}
+
+		sc.mu.Lock()
+		sc.hooks = h
+		sc.mu.Unlock()
+
+		if h.OnEvict != nil {
+			WithOnEvicted(h.OnEvict)(c)
+		}
+	}
+}
+
+func (c *cache) beforeLoad(key interface{}) {
+	if fn := c.hooks.BeforeLoad; fn != nil {
+		c.guard(func() {
+			fn(key)
+		})
+	}
+}
+
+func (c *cache) afterLoad(key interface{}, hit bool) {
+	if fn := c.hooks.AfterLoad; fn != nil {
+		c.guard(func() {
+			fn(key, hit)
+		})
+	}
+}
+
+func (c *cache) beforeStore(key interface{}) {
+	if fn := c.hooks.BeforeStore; fn != nil {
+		c.guard(func() {
+			fn(key)
+		})
+	}
+}
+
+func (c *cache) afterStore(key interface{}) {
+	if fn := c.hooks.AfterStore; fn != nil {
+		c.guard(func() {
+			fn(key)
+		})
+	}
+}
@@ -0,0 +1,180 @@
+// Package httpcache implements an http.RoundTripper that caches GET responses in a libcache.Cache.
+package httpcache
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shaj13/libcache"
+)
+
+// XFromCache is the header set on the responses served from cache.
+const XFromCache = "X-From-Cache"
+
+// Transport is an http.RoundTripper that caches GET responses in a libcache.Cache,
+// keyed by the request URL, and serves the cached responses until they expire.
+//
+// Only 200 responses cached, and responses with Cache-Control no-store, private or max-age=0,
+// or with a Vary header skipped, as the cache key does not account for the request headers.
+// Responses with a Set-Cookie header skipped too, so a user cookie never served to another.
+// Responses with Cache-Control max-age cached for max-age seconds,
+// other responses cached with the cache default TTL.
+//
+// Requests with an Authorization or Cookie header, or with Cache-Control no-store,
+// neither served from nor stored into the cache, so a user response never served to another.
+type Transport struct {
+	// Cache holds the cached responses.
+	Cache libcache.Cache
+	// Transport sends the requests not served from cache,
+	// nil means http.DefaultTransport.
+	Transport http.RoundTripper
+}
+
+// NewTransport returns a new Transport caching the responses in c.
+func NewTransport(c libcache.Cache) *Transport {
+	return &Transport{Cache: c}
+}
+
+// response is a cached response.
+type response struct {
+	Status     string
+	StatusCode int
+	Proto      string
+	ProtoMajor int
+	ProtoMinor int
+	Header     http.Header
+	Body       []byte
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !cacheable(req) {
+		return t.transport().RoundTrip(req)
+	}
+
+	key := req.URL.String()
+	if v, ok := t.Cache.Load(key); ok {
+		if cached, ok := v.(*response); ok {
+			return cached.response(req), nil
+		}
+	}
+
+	resp, err := t.transport().RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return resp, err
+	}
+
+	ttl, ok := lifetime(resp.Header)
+	if !ok || resp.Header.Get("Vary") != "" || resp.Header.Get("Set-Cookie") != "" {
+		return resp, nil
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	cached := &response{
+		Status:     resp.Status,
+		StatusCode: resp.StatusCode,
+		Proto:      resp.Proto,
+		ProtoMajor: resp.ProtoMajor,
+		ProtoMinor: resp.ProtoMinor,
+		Header:     resp.Header.Clone(),
+		Body:       body,
+	}
+
+	if ttl > 0 {
+		t.Cache.StoreWithTTL(key, cached, ttl)
+	} else {
+		t.Cache.Store(key, cached)
+	}
+
+	return resp, nil
+}
+
+func (t *Transport) transport() http.RoundTripper {
+	if t.Transport == nil {
+		return http.DefaultTransport
+	}
+	return t.Transport
+}
+
+// response returns a new http response of the cached response to req.
+func (r *response) response(req *http.Request) *http.Response {
+	header := r.Header.Clone()
+	header.Set(XFromCache, "1")
+
+	return &http.Response{
+		Status:        r.Status,
+		StatusCode:    r.StatusCode,
+		Proto:         r.Proto,
+		ProtoMajor:    r.ProtoMajor,
+		ProtoMinor:    r.ProtoMinor,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(r.Body)),
+		ContentLength: int64(len(r.Body)),
+		Request:       req,
+	}
+}
+
+// cacheable reports whether req may be served from and stored into the cache.
+func cacheable(req *http.Request) bool {
+	if req.Method != http.MethodGet {
+		return false
+	}
+
+	if req.Header.Get("Authorization") != "" || req.Header.Get("Cookie") != "" {
+		return false
+	}
+
+	for _, directive := range directives(req.Header) {
+		if directive == "no-store" {
+			return false
+		}
+	}
+
+	return true
+}
+
+// lifetime returns the response cache lifetime derived from its Cache-Control header,
+// zero means the cache default TTL, it returns false if the response must not be cached.
+func lifetime(h http.Header) (time.Duration, bool) {
+	var ttl time.Duration
+
+	for _, directive := range directives(h) {
+		if directive == "no-store" || directive == "private" || strings.HasPrefix(directive, "private=") {
+			return 0, false
+		}
+
+		if !strings.HasPrefix(directive, "max-age=") {
+			continue
+		}
+
+		secs, err := strconv.Atoi(strings.TrimPrefix(directive, "max-age="))
+		if err != nil || secs <= 0 {
+			return 0, false
+		}
+
+		ttl = time.Duration(secs) * time.Second
+	}
+
+	return ttl, true
+}
+
+// directives returns the lower cased Cache-Control directives of h.
+func directives(h http.Header) (ds []string) {
+	for _, v := range h["Cache-Control"] {
+		for _, directive := range strings.Split(v, ",") {
+			ds = append(ds, strings.ToLower(strings.TrimSpace(directive)))
+		}
+	}
+	return
+}
@@ -0,0 +1,199 @@
+package httpcache
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/shaj13/libcache"
+	_ "github.com/shaj13/libcache/lru"
+)
+
+// fakeClock is a manually advanced clock.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (f *fakeClock) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *fakeClock) Advance(d time.Duration) {
+	f.mu.Lock()
+	f.now = f.now.Add(d)
+	f.mu.Unlock()
+}
+
+func TestTransport(t *testing.T) {
+	table := []struct {
+		name         string
+		status       int
+		cacheControl string
+		vary         string
+		requests     int32
+	}{
+		{name: "MaxAge", status: http.StatusOK, cacheControl: "public, max-age=60", requests: 1},
+		{name: "NoCacheControl", status: http.StatusOK, requests: 1},
+		{name: "NoStore", status: http.StatusOK, cacheControl: "no-store", requests: 2},
+		{name: "MaxAgeZero", status: http.StatusOK, cacheControl: "max-age=0", requests: 2},
+		{name: "NotOK", status: http.StatusNotFound, cacheControl: "max-age=60", requests: 2},
+		{name: "Private", status: http.StatusOK, cacheControl: "private, max-age=60", requests: 2},
+		{name: "Vary", status: http.StatusOK, cacheControl: "max-age=60", vary: "Accept-Encoding", requests: 2},
+	}
+
+	for _, tt := range table {
+		t.Run("Test"+tt.name, func(t *testing.T) {
+			var requests int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&requests, 1)
+				if tt.cacheControl != "" {
+					w.Header().Set("Cache-Control", tt.cacheControl)
+				}
+				if tt.vary != "" {
+					w.Header().Set("Vary", tt.vary)
+				}
+				w.Header().Set("X-Test", "test")
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte("body"))
+			}))
+			defer srv.Close()
+
+			client := &http.Client{Transport: NewTransport(libcache.LRU.New(0))}
+
+			for i := 0; i < 2; i++ {
+				resp, err := client.Get(srv.URL)
+				assert.NoError(t, err)
+
+				body, err := ioutil.ReadAll(resp.Body)
+				resp.Body.Close()
+
+				assert.NoError(t, err)
+				assert.Equal(t, "body", string(body))
+				assert.Equal(t, tt.status, resp.StatusCode)
+				assert.Equal(t, "test", resp.Header.Get("X-Test"))
+				assert.Equal(t, i == 1 && tt.requests == 1, resp.Header.Get(XFromCache) == "1")
+			}
+
+			assert.Equal(t, tt.requests, atomic.LoadInt32(&requests))
+		})
+	}
+}
+
+func TestTransportMaxAgeExpiry(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("Cache-Control", "max-age=60")
+		_, _ = w.Write([]byte("body"))
+	}))
+	defer srv.Close()
+
+	clock := &fakeClock{now: time.Now()}
+	client := &http.Client{Transport: NewTransport(libcache.LRU.NewWithClock(0, clock))}
+
+	get := func() {
+		resp, err := client.Get(srv.URL)
+		assert.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	get()
+	clock.Advance(time.Second * 59)
+	get()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+
+	clock.Advance(time.Second)
+	get()
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+}
+
+func TestTransportUncacheableRequests(t *testing.T) {
+	table := []struct {
+		name   string
+		header string
+		value  string
+	}{
+		{name: "Authorization", header: "Authorization", value: "Bearer token"},
+		{name: "Cookie", header: "Cookie", value: "session=1"},
+		{name: "NoStore", header: "Cache-Control", value: "no-store"},
+	}
+
+	for _, tt := range table {
+		t.Run("Test"+tt.name, func(t *testing.T) {
+			var requests int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&requests, 1)
+				w.Header().Set("Cache-Control", "max-age=60")
+				_, _ = w.Write([]byte(r.Header.Get(tt.header)))
+			}))
+			defer srv.Close()
+
+			cache := libcache.LRU.New(0)
+			client := &http.Client{Transport: NewTransport(cache)}
+
+			for i := 0; i < 2; i++ {
+				req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+				assert.NoError(t, err)
+				req.Header.Set(tt.header, tt.value)
+
+				resp, err := client.Do(req)
+				assert.NoError(t, err)
+				resp.Body.Close()
+				assert.Empty(t, resp.Header.Get(XFromCache))
+			}
+
+			assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+			assert.Equal(t, 0, cache.Len())
+		})
+	}
+}
+
+func TestTransportSetCookie(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("Cache-Control", "public, max-age=60")
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "1"})
+	}))
+	defer srv.Close()
+
+	cache := libcache.LRU.New(0)
+	client := &http.Client{Transport: NewTransport(cache)}
+
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(srv.URL)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		assert.Empty(t, resp.Header.Get(XFromCache))
+	}
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+	assert.Equal(t, 0, cache.Len())
+}
+
+func TestTransportNonGET(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("Cache-Control", "max-age=60")
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: NewTransport(libcache.LRU.New(0))}
+	for i := 0; i < 2; i++ {
+		resp, err := client.Post(srv.URL, "text/plain", nil)
+		assert.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+}
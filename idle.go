@@ -0,0 +1,149 @@
+package libcache
+
+import (
+	"context"
+	"time"
+
+	"github.com/shaj13/libcache/internal"
+)
+
+func init() {
+	IDLE.Register(newIdle)
+}
+
+// newIdle returns the builtin idle cache, that never finds/stores a key's value.
+func newIdle(cap int) Cache {
+	return idle{}
+}
+
+type idle struct{}
+
+func (idle) Policy() ReplacementPolicy { return IDLE }
+
+func (idle) Load(interface{}) (v interface{}, ok bool)                          { return }
+func (idle) LoadWithExpiry(interface{}) (v interface{}, t time.Time, ok bool)   { return }
+func (idle) Peek(interface{}) (v interface{}, ok bool)                          { return }
+func (idle) Test(interface{}, func(interface{}) bool) (b, ok bool)              { return }
+func (idle) LoadMany(...interface{}) (m map[interface{}]interface{})            { return }
+func (idle) GetAndDelete(interface{}) (v interface{}, ok bool)                  { return }
+func (idle) CompareAndSwap(interface{}, interface{}, interface{}) (ok bool)     { return }
+func (idle) CompareAndDelete(interface{}, interface{}) (ok bool)                { return }
+func (idle) Increment(key interface{}, delta int64) (int64, error)              { return delta, nil }
+func (idle) Decrement(key interface{}, delta int64) (int64, error)              { return -delta, nil }
+func (idle) Keys() (keys []interface{})                                         { return }
+func (idle) ContainsMany(keys []interface{}) []bool                             { return make([]bool, len(keys)) }
+func (idle) Contains(interface{}) (ok bool)                                     { return }
+func (idle) ContainsNoGC(interface{}) (ok bool)                                 { return }
+func (idle) Resize(int) (i int)                                                 { return }
+func (idle) ResizeCost(int64) (n int64)                                         { return }
+func (idle) SetMaxKeyBytes(int64)                                               {}
+func (idle) Len() (len int)                                                     { return }
+func (idle) Cap() (cap int)                                                     { return }
+func (idle) TTL() (t time.Duration)                                             { return }
+func (idle) Expiry(interface{}) (t time.Time, ok bool)                          { return }
+func (idle) RemainingTTL(interface{}) (t time.Duration, ok bool)                { return }
+func (idle) Touch(interface{}, time.Duration) (ok bool)                         { return }
+func (idle) SetExpiry(interface{}, time.Time) (ok bool)                         { return }
+func (idle) RefreshAllTTL(time.Duration)                                        {}
+func (idle) ClearTTL()                                                          {}
+func (idle) GC() (dur time.Duration)                                            { return }
+func (idle) Update(interface{}, interface{})                                    {}
+func (idle) Store(interface{}, interface{})                                     {}
+func (idle) StoreWithTTL(interface{}, interface{}, time.Duration)               {}
+func (idle) StoreEvicting(interface{}, interface{}) (k, v interface{}, ok bool) { return }
+func (idle) StoreWithTTLEvicting(interface{}, interface{}, time.Duration) (k, v interface{}, ok bool) {
+	return
+}
+func (idle) StoreWithDeadline(interface{}, interface{}, time.Time) {}
+func (idle) StoreWithDeadlineEvicting(interface{}, interface{}, time.Time) (k, v interface{}, ok bool) {
+	return
+}
+func (idle) StoreMany(map[interface{}]interface{})                                     {}
+func (idle) DeleteMany(...interface{})                                                 {}
+func (idle) StoreWithTTLJitter(interface{}, interface{}, time.Duration, time.Duration) {}
+func (idle) Delete(interface{})                                                        {}
+func (idle) Reset()                                                                    {}
+func (idle) Purge()                                                                    {}
+func (idle) SetUpdateRefreshesTTL(bool)                                                {}
+func (idle) SetJitter(time.Duration)                                                   {}
+func (idle) SetTTL(ttl time.Duration)                                                  {}
+func (idle) RegisterOnExpired(f func(key, value interface{}))                          {}
+func (idle) RegisterOnEvicted(f func(key, value interface{}))                          {}
+func (idle) Notify(ch chan<- Event, ops ...Op)                                         {}
+func (idle) NotifyBlocking(ch chan<- Event, ops ...Op)                                 {}
+func (idle) NotifyWithReplay(ch chan<- Event, ops ...Op)                               {}
+func (idle) Ignore(ch chan<- Event, ops ...Op)                                         {}
+
+func (i idle) Subscribe(ops ...Op) (<-chan Event, func()) {
+	return internal.Subscribe(i, ops...)
+}
+
+func (idle) Watch(key interface{}) (<-chan Event, func()) {
+	return new(internal.Emitter).Watch(key)
+}
+
+func (idle) Pin(interface{}) (ok bool)   { return }
+func (idle) Unpin(interface{}) (ok bool) { return }
+
+func (idle) Close() error { return nil }
+
+func (idle) SetClock(Clock) {}
+
+func (idle) Iterator() *Iterator { return new(Iterator) }
+
+func (idle) KeysWithPrefix(string) (keys []interface{})     { return }
+func (idle) DeleteWithPrefix(string) (n int)                { return }
+func (idle) DeleteFunc(func(k, v interface{}) bool) (n int) { return }
+
+func (idle) StoreWithTags(interface{}, interface{}, ...string) {}
+func (idle) InvalidateTag(string) (n int)                      { return }
+
+func (idle) GetOrCompute(key interface{}, loader Loader) (interface{}, error) {
+	return loader(key)
+}
+
+func (idle) Merge(EntrySource, func(existing, incoming interface{}) interface{}) {}
+
+func (idle) LoadCtx(ctx context.Context, key interface{}, loader func(context.Context) (interface{}, error)) (interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return internal.ContextLoader(ctx, loader)(key)
+}
+
+func (idle) Snapshot() map[interface{}]interface{} { return make(map[interface{}]interface{}) }
+
+func (idle) Warm(map[interface{}]interface{})                       {}
+func (idle) WarmWithTTL(map[interface{}]interface{}, time.Duration) {}
+
+func (idle) Cost() (n int64) { return }
+
+func (idle) Metrics() (m Metrics)           { return }
+func (idle) Utilization() (u float64)       { return }
+func (idle) SetWeigher(Weigher)             {}
+func (idle) SetAdmissionFunc(AdmissionFunc) {}
+
+func (idle) PeekOldest() (k, v interface{}, ok bool)           { return }
+func (idle) PeekNewest() (k, v interface{}, ok bool)           { return }
+func (idle) OrderedKeys() (keys []interface{})                 { return }
+func (idle) KeysByExpiry() (keys []interface{})                { return }
+func (idle) ExpiringWithin(time.Duration) (keys []interface{}) { return }
+func (idle) ColdestN(int) (infos []EntryInfo)                  { return }
+func (idle) HottestN(int) (infos []EntryInfo)                  { return }
+
+func (idle) GetEntry(interface{}) (e EntryInfo, ok bool) { return }
+
+func (idle) StoreNegative(interface{}, time.Duration) {}
+
+func (idle) SetMaxIdle(time.Duration) {}
+
+func (idle) SetBloomFilter(int, float64) {}
+func (idle) SetDeterministic(bool)       {}
+func (idle) SetGCBatchSize(int)          {}
+func (idle) SetPurgeRemoveEvents(bool)   {}
+
+func (idle) PauseEviction() {}
+
+func (idle) ResumeEviction() {}
+
+func (idle) SetKeyFunc(KeyFunc) {}
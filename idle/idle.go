@@ -1,40 +1,14 @@
 // Package idle implements an IDLE cache, that never finds/stores a key's value.
+//
+// The IDLE cache replacement policy is builtin and registered by the libcache package,
+// this package kept for API symmetry with the other replacement policies packages.
 package idle
 
 import (
-	"time"
-
 	"github.com/shaj13/libcache"
 )
 
-func init() {
-	libcache.IDLE.Register(New)
-}
-
 // New return idle cache that never finds/stores a key's value.
 func New(cap int) libcache.Cache {
-	return idle{}
+	return libcache.IDLE.NewUnsafe(cap)
 }
-
-type idle struct{}
-
-func (idle) Load(interface{}) (v interface{}, ok bool)            { return }
-func (idle) Peek(interface{}) (v interface{}, ok bool)            { return }
-func (idle) Keys() (keys []interface{})                           { return }
-func (idle) Contains(interface{}) (ok bool)                       { return }
-func (idle) Resize(int) (i int)                                   { return }
-func (idle) Len() (len int)                                       { return }
-func (idle) Cap() (cap int)                                       { return }
-func (idle) TTL() (t time.Duration)                               { return }
-func (idle) Expiry(interface{}) (t time.Time, ok bool)            { return }
-func (idle) GC() (dur time.Duration)                              { return }
-func (idle) Update(interface{}, interface{})                      {}
-func (idle) Store(interface{}, interface{})                       {}
-func (idle) StoreWithTTL(interface{}, interface{}, time.Duration) {}
-func (idle) Delete(interface{})                                   {}
-func (idle) Purge()                                               {}
-func (idle) SetTTL(ttl time.Duration)                             {}
-func (idle) RegisterOnExpired(f func(key, value interface{}))     {}
-func (idle) RegisterOnEvicted(f func(key, value interface{}))     {}
-func (idle) Notify(ch chan<- libcache.Event, ops ...libcache.Op)  {}
-func (idle) Ignore(ch chan<- libcache.Event, ops ...libcache.Op)  {}
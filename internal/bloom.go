@@ -0,0 +1,80 @@
+package internal
+
+import "math"
+
+// maxBloomCount is the maximum value of a bloom filter counter,
+// a saturated counter never decremented, so it never yields a false negative.
+const maxBloomCount = math.MaxUint8
+
+// bloomFilter is a counting bloom filter, that reports whether a key hash
+// is definitely absent or may be present, using counters instead of bits
+// so hashes can be removed.
+type bloomFilter struct {
+	counters []uint8
+	k        int
+}
+
+// newBloomFilter returns a bloom filter sized for n keys at the given false positive rate.
+func newBloomFilter(n int, fpRate float64) *bloomFilter {
+	if fpRate <= 0 || fpRate >= 1 {
+		fpRate = 0.01
+	}
+
+	m := math.Ceil(-float64(n) * math.Log(fpRate) / (math.Ln2 * math.Ln2))
+	k := int(math.Round(m / float64(n) * math.Ln2))
+
+	if m < 1 {
+		m = 1
+	}
+
+	if k < 1 {
+		k = 1
+	}
+
+	return &bloomFilter{
+		counters: make([]uint8, int(m)),
+		k:        k,
+	}
+}
+
+// locations calls fn with the k counter indexes of the hash h,
+// derived by double hashing.
+func (b *bloomFilter) locations(h uint64, fn func(i uint64)) {
+	h1, h2 := h, mix(h)|1
+	m := uint64(len(b.counters))
+	for i := 0; i < b.k; i++ {
+		fn((h1 + uint64(i)*h2) % m)
+	}
+}
+
+func (b *bloomFilter) add(h uint64) {
+	b.locations(h, func(i uint64) {
+		if b.counters[i] < maxBloomCount {
+			b.counters[i]++
+		}
+	})
+}
+
+func (b *bloomFilter) remove(h uint64) {
+	b.locations(h, func(i uint64) {
+		if c := b.counters[i]; c > 0 && c < maxBloomCount {
+			b.counters[i]--
+		}
+	})
+}
+
+// mayContain reports whether the hash h may be present,
+// false means it is definitely absent.
+func (b *bloomFilter) mayContain(h uint64) bool {
+	ok := true
+	b.locations(h, func(i uint64) {
+		ok = ok && b.counters[i] > 0
+	})
+	return ok
+}
+
+func (b *bloomFilter) reset() {
+	for i := range b.counters {
+		b.counters[i] = 0
+	}
+}
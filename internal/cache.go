@@ -2,10 +2,25 @@ package internal
 
 import (
 	"container/heap"
+	"container/list"
+	"context"
+	"errors"
 	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"sync"
 	"time"
 )
 
+// ErrNotInt64 is returned by Increment and Decrement,
+// when the existing key value is not an int64.
+var ErrNotInt64 = errors.New("libcache: key value is not an int64")
+
+// ErrNotStored is returned by Increment and Decrement,
+// when the missing key rejected, e.g. by the admission function or the key bytes budget.
+var ErrNotStored = errors.New("libcache: key not stored")
+
 // Op describes a set of cache operations.
 type Op uint8
 
@@ -14,6 +29,8 @@ const (
 	Read Op = iota + 1
 	Write
 	Remove
+	Expire
+	Purge
 	maxOp
 )
 
@@ -25,13 +42,19 @@ func (op Op) String() string {
 		return "WRITE"
 	case Remove:
 		return "REMOVE"
+	case Expire:
+		return "EXPIRE"
+	case Purge:
+		return "PURGE"
 	default:
 		return "UNKNOWN"
 	}
 }
 
 type handler struct {
-	mask [((maxOp - 1) + 7) / 8]uint8
+	mask [(maxOp + 7) / 8]uint8
+	// block reports whether events delivery waits for the receiver.
+	block bool
 }
 
 func (h *handler) want(op Op) bool {
@@ -42,10 +65,298 @@ func (h *handler) set(op Op) {
 	h.mask[op/8] |= 1 << uint8(op&7)
 }
 
+// setAll sets the provided operations, or all operations if none provided.
+func (h *handler) setAll(ops []Op) {
+	if len(ops) == 0 {
+		for i := 1; i < int(maxOp); i++ {
+			h.set(Op(i))
+		}
+		return
+	}
+
+	for _, op := range ops {
+		h.set(op)
+	}
+}
+
+// funcHandler is a handler calling fn instead of sending to a channel.
+type funcHandler struct {
+	handler
+	fn func(Event)
+}
+
 func (h *handler) clear(op Op) {
 	h.mask[op/8] &^= 1 << uint8(op&7)
 }
 
+// Wants reports whether the given operations include op,
+// no operations means all operations.
+func Wants(ops []Op, op Op) bool {
+	if len(ops) == 0 {
+		return true
+	}
+
+	for _, o := range ops {
+		if o == op {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Emitter relay events to the registered channels.
+// The zero value is ready to use.
+//
+// Emitter is not safe for concurrent use, registration and emission
+// must be serialized by the cache owning it.
+type Emitter struct {
+	handlers map[chan<- Event]*handler
+	// funcs holds the functions registered by NotifyFunc.
+	funcs []funcHandler
+	// watchers holds the channels registered by Watch, keyed by the normalized keys.
+	watchers map[interface{}]map[chan Event]struct{}
+	keyFunc  KeyFunc
+}
+
+// Notify causes emitter to relay events to ch.
+// If no operations are provided, all incoming operations will be relayed to ch.
+// Otherwise, just the provided operations will.
+func (em *Emitter) Notify(ch chan<- Event, ops ...Op) {
+	em.register(ch, false, ops...)
+}
+
+// NotifyBlocking causes emitter to relay events to ch,
+// and waits for ch receiver, instead of dropping events when ch is full.
+func (em *Emitter) NotifyBlocking(ch chan<- Event, ops ...Op) {
+	em.register(ch, true, ops...)
+}
+
+// NotifyFunc causes emitter to call fn synchronously for each event,
+// of the provided operations, or all operations if none provided,
+// so unlike a full Notify channel, no event dropped.
+// fn must not call the cache, and it is removed only by Clear.
+func (em *Emitter) NotifyFunc(fn func(Event), ops ...Op) {
+	h := funcHandler{fn: fn}
+	h.setAll(ops)
+	em.funcs = append(em.funcs, h)
+}
+
+func (em *Emitter) register(ch chan<- Event, block bool, ops ...Op) {
+	if ch == nil {
+		panic("libcache: Notify using nil channel")
+	}
+
+	if em.handlers == nil {
+		em.handlers = make(map[chan<- Event]*handler)
+	}
+
+	h := new(handler)
+	h.block = block
+	h.setAll(ops)
+	em.handlers[ch] = h
+}
+
+// Ignore causes the provided ops to be ignored. Ignore undoes the effect
+// of any prior calls to Notify for the provided ops.
+// If no ops are provided, ch removed.
+func (em *Emitter) Ignore(ch chan<- Event, ops ...Op) {
+	if len(ops) == 0 {
+		delete(em.handlers, ch)
+		return
+	}
+
+	h, ok := em.handlers[ch]
+	if !ok {
+		return
+	}
+
+	for _, op := range ops {
+		h.clear(op)
+	}
+}
+
+// Watch allocates a buffered channel and causes emitter to relay
+// the key Write, Remove and Expire events to it. It returns the channel
+// along with an idempotent function that unregisters and closes the channel.
+func (em *Emitter) Watch(key interface{}) (<-chan Event, func()) {
+	once := sync.Once{}
+	id := em.keyFunc.Key(key)
+	ch := make(chan Event, SubscriptionBuffer)
+
+	if em.watchers == nil {
+		em.watchers = make(map[interface{}]map[chan Event]struct{})
+	}
+
+	if em.watchers[id] == nil {
+		em.watchers[id] = make(map[chan Event]struct{})
+	}
+
+	em.watchers[id][ch] = struct{}{}
+
+	return ch, func() {
+		once.Do(func() {
+			if chs, ok := em.watchers[id]; ok {
+				delete(chs, ch)
+				if len(chs) == 0 {
+					delete(em.watchers, id)
+				}
+			}
+			close(ch)
+		})
+	}
+}
+
+// SetKeyFunc sets the function used to normalize the watched keys.
+func (em *Emitter) SetKeyFunc(fn KeyFunc) {
+	em.keyFunc = fn
+}
+
+// Emit relays the event to the channels registered for its operation,
+// and to the channels watching its key.
+func (em *Emitter) Emit(e Event) {
+	if len(em.watchers) > 0 && e.Op == Purge {
+		// Purge removes all keys, so every watcher notified.
+		for _, chs := range em.watchers {
+			for c := range chs {
+				select {
+				case c <- e:
+				default:
+				}
+			}
+		}
+	} else if len(em.watchers) > 0 && e.Op != Read {
+		for c := range em.watchers[em.keyFunc.Key(e.Key)] {
+			select {
+			case c <- e:
+			default:
+			}
+		}
+	}
+
+	for _, h := range em.funcs {
+		if h.want(e.Op) {
+			h.fn(e)
+		}
+	}
+
+	for c, h := range em.handlers {
+		if h.want(e.Op) && h.block {
+			c <- e
+			continue
+		}
+
+		if h.want(e.Op) {
+			// send but do not block for it
+			select {
+			case c <- e:
+			default:
+			}
+		}
+	}
+}
+
+// Len returns the number of registered channels, watchers and functions included.
+func (em *Emitter) Len() int {
+	n := len(em.handlers) + len(em.funcs)
+	for _, chs := range em.watchers {
+		n += len(chs)
+	}
+	return n
+}
+
+// Clear removes all registered channels, watchers and functions included.
+func (em *Emitter) Clear() {
+	em.handlers = nil
+	em.funcs = nil
+	em.watchers = nil
+}
+
+// Weigher returns the cost of a key value.
+type Weigher func(key, value interface{}) int64
+
+// AdmissionFunc reports whether a new key value worth caching.
+type AdmissionFunc func(key, value interface{}) bool
+
+// KeyFunc normalizes a key into a comparable form,
+// used to index the cache entries.
+type KeyFunc func(key interface{}) interface{}
+
+// Keyer is implemented by keys having their own identity,
+// the cache indexes the entries by the CacheKey result instead of the key itself.
+type Keyer interface {
+	CacheKey() interface{}
+}
+
+// Key returns the normalized key, if fn is nil it returns the key CacheKey result
+// if the key is a non-nil Keyer, or the key itself.
+func (fn KeyFunc) Key(key interface{}) interface{} {
+	if fn != nil {
+		return fn(key)
+	}
+
+	if k, ok := key.(Keyer); ok && !isNilPtr(key) {
+		return k.CacheKey()
+	}
+
+	return key
+}
+
+func isNilPtr(v interface{}) bool {
+	rv := reflect.ValueOf(v)
+	return rv.Kind() == reflect.Ptr && rv.IsNil()
+}
+
+// Loader loads the key value from the underlying data source.
+type Loader func(key interface{}) (value interface{}, err error)
+
+// Clock provides the current time.
+type Clock interface {
+	Now() time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// SubscriptionBuffer is the buffer size of the channels allocated by Subscribe.
+const SubscriptionBuffer = 64
+
+// Notifier relay cache events to channels.
+type Notifier interface {
+	Notify(ch chan<- Event, ops ...Op)
+	Ignore(ch chan<- Event, ops ...Op)
+}
+
+// Subscribe allocates a buffered channel and registers it in the given notifier,
+// It returns the channel along with an idempotent function that unregisters
+// and closes the channel.
+func Subscribe(n Notifier, ops ...Op) (<-chan Event, func()) {
+	once := sync.Once{}
+	ch := make(chan Event, SubscriptionBuffer)
+	n.Notify(ch, ops...)
+
+	return ch, func() {
+		once.Do(func() {
+			n.Ignore(ch)
+			close(ch)
+		})
+	}
+}
+
+// frequencer is implemented by collections that track entries access frequency.
+type frequencer interface {
+	Frequency(*Entry) int
+}
+
+// walker is implemented by collections that can walk their entries in discard order,
+// from the Front to the Back entry, without mutating them.
+type walker interface {
+	Walk(fn func(*Entry))
+}
+
 // Collection represents the cache underlying data structure,
 // and defines the functions or operations that can be applied to the data elements.
 type Collection interface {
@@ -53,6 +364,10 @@ type Collection interface {
 	Add(*Entry)
 	Remove(*Entry)
 	Discard() *Entry
+	// Front returns the entry Discard would return, without removing it.
+	Front() *Entry
+	// Back returns the last entry in the discard order.
+	Back() *Entry
 	Len() int
 	Init()
 }
@@ -69,6 +384,12 @@ type Event struct {
 	Expiry time.Time
 	// Ok report whether the read operation succeed.
 	Ok bool
+	// Old represents cache key previous value, on write operation.
+	Old interface{}
+	// HadOld report whether the key had a previous value, on write operation.
+	HadOld bool
+	// Cost represents cache key value cost.
+	Cost int64
 }
 
 // String returns a string representation of the event in the form
@@ -77,24 +398,163 @@ func (e Event) String() string {
 	return fmt.Sprintf("%v: %s", e.Key, e.Op.String())
 }
 
+// EntryInfo is a copy of a cache entry metadata.
+type EntryInfo struct {
+	// Key represents cache entry key.
+	Key interface{}
+	// Value represents cache entry value.
+	Value interface{}
+	// Expiry represents cache entry expiry time, zero for entries never expires.
+	Expiry time.Time
+	// Cost represents cache entry cost.
+	Cost int64
+	// Frequency represents cache entry access count,
+	// zero if the cache does not track it.
+	Frequency int
+	// Created represents the time the cache entry value stored.
+	Created time.Time
+	// Accessed represents the time the cache entry last loaded,
+	// or stored if it never loaded.
+	Accessed time.Time
+	// Delta represents the duration of the GetOrCompute loader call,
+	// that computed the cache entry value, zero if the value stored directly.
+	Delta time.Duration
+
+	clock Clock
+}
+
+// Age returns the time elapsed since the entry value stored,
+// computed against the cache clock.
+func (i EntryInfo) Age() time.Duration {
+	return i.now().Sub(i.Created)
+}
+
+// IdleTime returns the time elapsed since the entry last accessed,
+// computed against the cache clock.
+func (i EntryInfo) IdleTime() time.Duration {
+	return i.now().Sub(i.Accessed)
+}
+
+func (i EntryInfo) now() time.Time {
+	if i.clock == nil {
+		return time.Now()
+	}
+	return i.clock.Now()
+}
+
 // Entry is used to hold a value in the cache.
 type Entry struct {
-	Key     interface{}
+	Key interface{}
+	// id is the normalized key, the entry indexed by.
+	id      interface{}
 	Value   interface{}
 	Element interface{}
 	Exp     time.Time
-	index   int
+	// Cost is the entry value cost, as returned by the cache weigher.
+	Cost int64
+	// Created and Accessed are the entry store and last load time.
+	Created  time.Time
+	Accessed time.Time
+	// delta is the duration of the loader call that computed the entry value.
+	delta time.Duration
+	// deadline is the earliest of the entry expiry and max idle time,
+	// the entry garbage collected at.
+	deadline time.Time
+	// index is the entry index in the expiring heap, -1 if not in the heap.
+	index int
+	tags  []string
+	// ordered is the entry node in the cache store order index.
+	ordered *list.Element
+}
+
+// ID returns the entry normalized key, the entry indexed by,
+// or the entry key if the entry is not stored by a cache.
+func (e *Entry) ID() interface{} {
+	if e.id == nil {
+		return e.Key
+	}
+	return e.id
+}
+
+// entryPool recycles the entries removed from caches,
+// to reduce allocations under churny workloads.
+var entryPool = sync.Pool{
+	New: func() interface{} {
+		return new(Entry)
+	},
+}
+
+// newEntry returns a zeroed entry from the pool, not yet in the expiring heap.
+func newEntry() *Entry {
+	e := entryPool.Get().(*Entry)
+	e.index = -1
+	return e
+}
+
+// release zeroes the entry and returns it to the pool.
+// release must be called only once the entry removed from the cache,
+// and its fields no longer read by the caller.
+func release(e *Entry) {
+	*e = Entry{}
+	entryPool.Put(e)
 }
 
 // Cache is an abstracted cache that provides a skeletal implementation,
 // of the Cache interface to minimize the effort required to implement interface.
 type Cache struct {
+	// counters kept first to be 64-bit aligned for atomic operations.
+	counters Counters
 	coll     Collection
 	heap     expiringHeap
 	entries  map[interface{}]*Entry
-	handlers map[chan<- Event]*handler
+	pinned   map[interface{}]*Entry
+	// tags maps each tag to the keys bearing it.
+	tags    map[string]map[interface{}]struct{}
+	emitter Emitter
+	// relay receives the cache events instead of the emitter when set.
+	relay func(Event)
+	// subs holds the channels allocated by Subscribe and their cancel funcs.
+	subs     map[<-chan Event]func()
+	closed   bool
+	clock    Clock
 	ttl      time.Duration
+	jitter   time.Duration
+	rand     *rand.Rand
 	capacity int
+	// initCap is the capacity the cache constructed with.
+	initCap int
+	// reserve is the number of entries the entries map preallocated for.
+	reserve int
+	// refresh reports whether update resets entry expiry.
+	refresh bool
+	// maxIdle is the max duration an entry lives without access, zero means no limit.
+	maxIdle time.Duration
+	// gcBatch is the max number of entries a GC call sweeps, zero means no limit.
+	gcBatch int
+	// silent reports whether write events are suppressed.
+	silent bool
+	// weigher returns entries cost, nil means each entry costs 1.
+	weigher Weigher
+	// admit reports whether new keys stored, nil means all of them.
+	admit AdmissionFunc
+	// maxCost is the entries total cost ceiling, zero means no ceiling.
+	maxCost int64
+	// maxKeyBytes is the entries total key bytes budget, zero means no budget.
+	maxKeyBytes int64
+	// keyFunc normalizes the keys, nil means keys used as is.
+	keyFunc KeyFunc
+	// bloom fronts the entries lookups, nil means disabled.
+	bloom *bloomFilter
+	// paused reports whether capacity and cost eviction deferred.
+	paused bool
+	// order indexes the entries in store order, nil means disabled.
+	order *list.List
+	// cost is the total cost of the cache entries.
+	cost int64
+	// keyBytes is the total key bytes of the cache entries.
+	keyBytes int64
+	// purgeRemoves reports whether purge fires a Remove event for each entry.
+	purgeRemoves bool
 }
 
 // Load returns key value.
@@ -102,197 +562,1470 @@ func (c *Cache) Load(key interface{}) (interface{}, bool) {
 	return c.get(key, false)
 }
 
+// LoadWithExpiry returns key value and its absolute expiry,
+// zero time for a key that never expires.
+func (c *Cache) LoadWithExpiry(key interface{}) (interface{}, time.Time, bool) {
+	v, ok := c.get(key, false)
+	if !ok {
+		return nil, time.Time{}, false
+	}
+	return v, c.entries[c.keyFunc.Key(key)].Exp, true
+}
+
 // Peek returns key value without updating the underlying "rank".
 func (c *Cache) Peek(key interface{}) (interface{}, bool) {
 	return c.get(key, true)
 }
 
+// Test reports whether key value satisfies pred, without returning the value.
+func (c *Cache) Test(key interface{}, pred func(value interface{}) bool) (bool, bool) {
+	v, ok := c.get(key, false)
+	if !ok {
+		return false, ok
+	}
+	return pred(v), ok
+}
+
 func (c *Cache) get(key interface{}, peek bool) (interface{}, bool) {
+	id := c.keyFunc.Key(key)
+	if !c.mayContain(id) {
+		c.counters.Lookup(false)
+		c.emit(Read, key, nil, time.Time{}, 0, false)
+		return nil, false
+	}
+
 	// Run GC inline before return the entry.
-	c.GC()
+	c.gcKey(id)
 
-	e, ok := c.entries[key]
+	e, ok := c.entries[id]
 	if !ok {
-		c.emit(Read, key, nil, time.Time{}, ok)
+		c.counters.Lookup(false)
+		c.emit(Read, key, nil, time.Time{}, 0, ok)
 		return nil, ok
 	}
 
+	if IsNegative(e.Value) {
+		c.counters.Lookup(false)
+		c.emit(Read, key, e.Value, e.Exp, e.Cost, false)
+		return nil, false
+	}
+
+	c.counters.Lookup(true)
+
 	if !peek {
+		e.Accessed = c.clock.Now().UTC()
+		if c.maxIdle > 0 {
+			c.schedule(e)
+		}
 		c.coll.Move(e)
 	}
 
-	c.emit(Read, key, e.Value, e.Exp, ok)
+	c.emit(Read, key, e.Value, e.Exp, e.Cost, ok)
 	return e.Value, ok
 }
 
+// GetEntry returns a copy of the key entry metadata,
+// without updating the underlying "rank".
+// Unlike Peek, GetEntry returns negative entries.
+func (c *Cache) GetEntry(key interface{}) (EntryInfo, bool) {
+	id := c.keyFunc.Key(key)
+	if !c.mayContain(id) {
+		c.emit(Read, key, nil, time.Time{}, 0, false)
+		return EntryInfo{}, false
+	}
+
+	// Run GC inline before return the entry.
+	c.gcKey(id)
+
+	e, ok := c.entries[id]
+	if !ok {
+		c.emit(Read, key, nil, time.Time{}, 0, ok)
+		return EntryInfo{}, false
+	}
+
+	c.emit(Read, key, e.Value, e.Exp, e.Cost, !IsNegative(e.Value))
+	return c.info(e), true
+}
+
+// info returns a copy of the entry metadata.
+func (c *Cache) info(e *Entry) EntryInfo {
+	info := EntryInfo{
+		Key:      e.Key,
+		Value:    e.Value,
+		Expiry:   e.Exp,
+		Cost:     e.Cost,
+		Created:  e.Created,
+		Accessed: e.Accessed,
+		Delta:    e.delta,
+		clock:    c.clock,
+	}
+
+	if f, ok := c.coll.(frequencer); ok {
+		info.Frequency = f.Frequency(e)
+	}
+
+	return info
+}
+
 // Expiry returns key value expiry time.
 func (c *Cache) Expiry(key interface{}) (t time.Time, ok bool) {
 	ok = c.Contains(key)
 	if ok {
-		t = c.entries[key].Exp
+		t = c.entries[c.keyFunc.Key(key)].Exp
 	}
 	return t, ok
 }
 
-// Store sets the value for a key.
-func (c *Cache) Store(key, value interface{}) {
-	c.StoreWithTTL(key, value, c.ttl)
-}
+// Touch extends the key value expiry to now plus the given ttl,
+// without updating the value or the underlying "rank".
+func (c *Cache) Touch(key interface{}, ttl time.Duration) bool {
+	id := c.keyFunc.Key(key)
 
-// StoreWithTTL sets the key value with TTL overrides the default.
-func (c *Cache) StoreWithTTL(key, value interface{}, ttl time.Duration) {
-	// Run GC inline before pushing the new entry.
-	c.GC()
+	// Run GC inline before touch the entry.
+	c.gcKey(id)
 
-	if e, ok := c.entries[key]; ok {
-		c.removeEntry(e)
+	e, ok := c.live(id)
+	if !ok {
+		return false
 	}
 
-	e := &Entry{Key: key, Value: value}
+	c.setExp(e, ttl)
+	c.emitWrite(e, e.Value, true)
+	return true
+}
+
+// SetExpiry sets the key value expiry to the given absolute time,
+// without updating the value or the underlying "rank".
+// Zero time makes the key value never expires.
+// SetExpiry reports whether the key exist and not yet expired.
+func (c *Cache) SetExpiry(key interface{}, t time.Time) bool {
+	id := c.keyFunc.Key(key)
 
-	if ttl > 0 {
-		e.Exp = time.Now().UTC().Add(ttl)
-		heap.Push(&c.heap, e)
+	// Run GC inline before set the entry expiry.
+	c.gcKey(id)
+
+	e, ok := c.live(id)
+	if !ok {
+		return false
 	}
 
-	c.entries[key] = e
-	if c.capacity != 0 && c.Len() >= c.capacity {
-		c.Discard()
+	e.Exp = time.Time{}
+	if !t.IsZero() {
+		e.Exp = t.UTC()
 	}
 
-	c.coll.Add(e)
-	c.emit(Write, e.Key, e.Value, e.Exp, false)
+	c.schedule(e)
+	c.emitWrite(e, e.Value, true)
+	return true
 }
 
-// Update the key value without updating the underlying "rank".
-func (c *Cache) Update(key, value interface{}) {
-	// Run GC inline before update the entry.
-	c.GC()
-
-	if c.Contains(key) {
-		e := c.entries[key]
-		e.Value = value
-		c.emit(Write, e.Key, e.Value, e.Exp, false)
+// RefreshAllTTL sets the expiry of all the cache entries to now plus the given ttl,
+// without updating the values or the underlying "rank", and rebuilds the expiring heap.
+// Zero or negative ttl makes the entries never expire.
+// Negative entries keep their expiry. Like Touch, RefreshAllTTL emits a Write event per entry.
+func (c *Cache) RefreshAllTTL(ttl time.Duration) {
+	var exp time.Time
+	if ttl > 0 {
+		exp = c.clock.Now().UTC().Add(ttl)
 	}
+
+	c.setAllExp(exp)
 }
 
-// Purge Clears all cache entries.
-func (c *Cache) Purge() {
-	defer c.coll.Init()
+// ClearTTL makes all the cache entries never expire, and removes them from the expiring heap,
+// unless they have a max idle time. Negative entries keep their expiry.
+// Like Touch, ClearTTL emits a Write event per entry.
+func (c *Cache) ClearTTL() {
+	c.setAllExp(time.Time{})
+}
 
-	if len(c.handlers) == 0 {
-		c.entries = make(map[interface{}]*Entry)
-		c.heap = nil
-		return
-	}
+// setAllExp sets the expiry of all the non-negative entries,
+// and rebuilds the expiring heap in O(n).
+func (c *Cache) setAllExp(exp time.Time) {
+	// Run GC inline before set the entries expiry.
+	c.gc()
 
-	for _, e := range c.entries {
-		c.evict(e)
-	}
+	c.heap = make(expiringHeap, 0, len(c.heap))
+	c.each(func(e *Entry) {
+		if !IsNegative(e.Value) {
+			e.Exp = exp
+		}
+
+		e.index = -1
+		e.deadline = c.deadline(e)
+		if !e.deadline.IsZero() {
+			e.index = len(c.heap)
+			c.heap = append(c.heap, e)
+		}
+	})
+	heap.Init(&c.heap)
+
+	c.each(func(e *Entry) {
+		if !IsNegative(e.Value) {
+			c.emitWrite(e, e.Value, true)
+		}
+	})
 }
 
-// Resize cache, returning number evicted
-func (c *Cache) Resize(size int) int {
-	c.capacity = size
-	diff := c.Len() - size
+// RemainingTTL returns the remaining duration until key value expires,
+// zero duration returned for a key that never expires.
+func (c *Cache) RemainingTTL(key interface{}) (time.Duration, bool) {
+	id := c.keyFunc.Key(key)
 
-	if diff < 0 {
-		diff = 0
+	// Run GC inline before return the entry ttl.
+	c.gcKey(id)
+
+	e, ok := c.entries[id]
+	if !ok || IsNegative(e.Value) {
+		return 0, false
 	}
 
-	for i := 0; i < diff; i++ {
-		c.Discard()
+	if e.Exp.IsZero() {
+		return 0, ok
 	}
 
-	return diff
+	return e.Exp.Sub(c.clock.Now()), ok
 }
 
-// DelSilently the key value silently without call onEvicted.
-func (c *Cache) DelSilently(key interface{}) {
-	if e, ok := c.entries[key]; ok {
-		c.removeEntry(e)
-	}
+// Store sets the value for a key.
+func (c *Cache) Store(key, value interface{}) {
+	c.StoreWithTTL(key, value, c.ttl)
 }
 
-// Delete deletes the key value.
-func (c *Cache) Delete(key interface{}) {
-	if e, ok := c.entries[key]; ok {
-		c.evict(e)
-	}
+// StoreWithTTL sets the key value with TTL overrides the default.
+func (c *Cache) StoreWithTTL(key, value interface{}, ttl time.Duration) {
+	c.StoreWithTTLJitter(key, value, ttl, c.jitter)
 }
 
-// Contains Checks if a key exists in cache.
-func (c *Cache) Contains(key interface{}) (ok bool) {
-	_, ok = c.Peek(key)
-	return
+// StoreWithTTLJitter sets the key value with TTL overrides the default,
+// and applies a random jitter in range [-jitter, +jitter] to the TTL.
+func (c *Cache) StoreWithTTLJitter(key, value interface{}, ttl, jitter time.Duration) {
+	c.store(key, value, c.ExpiresAt(ttl, jitter))
 }
 
-// Keys return cache records keys.
-func (c *Cache) Keys() (keys []interface{}) {
-	for k := range c.entries {
-		keys = append(keys, k)
-	}
-	return
+// StoreEvicting sets the key value like Store,
+// and returns the first entry evicted to make room for it, if any.
+func (c *Cache) StoreEvicting(key, value interface{}) (evictedKey, evictedValue interface{}, evicted bool) {
+	return c.StoreWithTTLEvicting(key, value, c.ttl)
 }
 
-// Len Returns the number of items in the cache.
-func (c *Cache) Len() int {
-	return c.coll.Len()
+// StoreWithTTLEvicting sets the key value like StoreWithTTL,
+// and returns the first entry evicted to make room for it, if any.
+func (c *Cache) StoreWithTTLEvicting(key, value interface{}, ttl time.Duration) (evictedKey, evictedValue interface{}, evicted bool) {
+	return c.store(key, value, c.ExpiresAt(ttl, c.jitter))
 }
 
-// Discard oldest entry from cache to make room for the new ones.
-func (c *Cache) Discard() (key, value interface{}) {
-	if e := c.coll.Discard(); e != nil {
-		c.evict(e)
-		return e.Key, e.Value
+// ExpiresAt returns the expiry time of an entry stored now with ttl and jitter,
+// zero means never expires.
+func (c *Cache) ExpiresAt(ttl, jitter time.Duration) (exp time.Time) {
+	if ttl = c.applyJitter(ttl, jitter); ttl > 0 {
+		exp = c.clock.Now().UTC().Add(ttl)
 	}
-
 	return
 }
 
-func (c *Cache) removeEntry(e *Entry) {
-	c.coll.Remove(e)
-	delete(c.entries, e.Key)
-	// Remove entry from the heap, the entry may does not exist because
-	// it has zero ttl or already popped up by gc
-	if len(c.heap) > 0 && e.index < len(c.heap) && e.Key == c.heap[e.index].Key {
-		heap.Remove(&c.heap, e.index)
+// StoreWithDeadline sets the key value with an absolute expiry time,
+// zero deadline means the key value never expires.
+// A deadline in the past stores the key value, which then collected by the next operation.
+func (c *Cache) StoreWithDeadline(key, value interface{}, deadline time.Time) {
+	if !deadline.IsZero() {
+		deadline = deadline.UTC()
 	}
-}
 
-// evict remove entry and fire on evicted callback.
-func (c *Cache) evict(e *Entry) {
-	c.removeEntry(e)
-	c.emit(Remove, e.Key, e.Value, e.Exp, false)
+	c.store(key, value, deadline)
 }
 
-func (c *Cache) emit(op Op, k, v interface{}, exp time.Time, ok bool) {
-	e := Event{
-		Op:     op,
+// StoreWithDeadlineEvicting sets the key value like StoreWithDeadline,
+// and returns the first entry evicted to make room for it, if any.
+func (c *Cache) StoreWithDeadlineEvicting(key, value interface{}, deadline time.Time) (evictedKey, evictedValue interface{}, evicted bool) {
+	if !deadline.IsZero() {
+		deadline = deadline.UTC()
+	}
+
+	return c.store(key, value, deadline)
+}
+
+// store sets the key value with the given expiry time, zero means never expires,
+// and returns the first entry evicted to make room for it, if any.
+func (c *Cache) store(key, value interface{}, exp time.Time) (evictedKey, evictedValue interface{}, evicted bool) {
+	if c.closed {
+		return
+	}
+
+	id := c.keyFunc.Key(key)
+
+	// Run GC inline before pushing the new entry.
+	c.gcKey(id)
+
+	var (
+		old    interface{}
+		hadOld bool
+	)
+
+	// Overwritten entry keeps its pin.
+	_, pinned := c.pinned[id]
+
+	prev, ok := c.entries[id]
+	if !ok && !Admits(c.admit, key, value) {
+		return
+	}
+
+	if ok {
+		old, hadOld = prev.Value, ok
+		c.removeEntry(prev)
+		release(prev)
+	}
+
+	now := c.clock.Now().UTC()
+	e := newEntry()
+	e.Key, e.id = key, id
+	e.Created, e.Accessed = now, now
+	e.Exp = exp
+	c.setValue(e, value)
+	c.schedule(e)
+
+	c.entries[id] = e
+	c.keyBytes += KeyBytes(key)
+	if c.order != nil {
+		e.ordered = c.order.PushBack(e)
+	}
+	if c.bloom != nil {
+		c.bloom.add(Hash(id))
+	}
+
+	discard := func() {
+		k, v, ok := c.discard()
+		if ok && !evicted {
+			evictedKey, evictedValue, evicted = k, v, ok
+		}
+	}
+
+	// The new entry not yet counted by Len, and the previous entry of the key
+	// already removed, so discard one to make room for it once the cache full.
+	if !c.paused && c.capacity > 0 && c.Len() >= c.capacity {
+		discard()
+	}
+
+	// The entry not yet added to the collection,
+	// so it kept even if its cost alone exceeds the ceiling.
+	for !c.paused && c.maxCost > 0 && c.cost > c.maxCost && c.evictable() > 0 {
+		discard()
+	}
+
+	for !c.paused && c.maxKeyBytes > 0 && c.keyBytes > c.maxKeyBytes && c.evictable() > 0 {
+		discard()
+	}
+
+	c.coll.Add(e)
+	if pinned {
+		c.pinned[id] = e
+	}
+
+	c.emitWrite(e, old, hadOld)
+	return
+}
+
+// GetOrCompute returns the key value if exist, Otherwise,
+// it loads the key value using loader and stores it.
+// Loader errors are returned and not cached.
+func (c *Cache) GetOrCompute(key interface{}, loader Loader) (interface{}, error) {
+	if v, ok := c.Load(key); ok {
+		return v, nil
+	}
+
+	if e, ok := c.entries[c.keyFunc.Key(key)]; ok && IsNegative(e.Value) {
+		return nil, ErrNotFound
+	}
+
+	v, err := loader(key)
+	return StoreLoaded(c, key, v, err)
+}
+
+// LoadCtx returns the key value if exist, Otherwise, it loads the key value
+// using loader and stores it, unless ctx done before loader returned.
+func (c *Cache) LoadCtx(ctx context.Context, key interface{}, loader func(context.Context) (interface{}, error)) (interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.GetOrCompute(key, ContextLoader(ctx, loader))
+}
+
+// Merge stores the other cache live entries keeping their absolute expiry,
+// and resolves the value of the keys already in the cache using onConflict,
+// nil onConflict means the incoming value wins.
+func (c *Cache) Merge(other EntrySource, onConflict func(existing, incoming interface{}) interface{}) {
+	MergeEntries(c, LiveEntries(other), onConflict)
+}
+
+// StoreNegative marks the key as known to be absent for ttl,
+// Load and GetOrCompute report the key missing until the ttl elapses.
+func (c *Cache) StoreNegative(key interface{}, ttl time.Duration) {
+	c.StoreWithTTL(key, Negative{}, ttl)
+}
+
+// StoreMany sets the items key value.
+func (c *Cache) StoreMany(items map[interface{}]interface{}) {
+	for k, v := range items {
+		c.Store(k, v)
+	}
+}
+
+// Warm populates the cache with items silently, using the default TTL.
+func (c *Cache) Warm(items map[interface{}]interface{}) {
+	c.WarmWithTTL(items, c.ttl)
+}
+
+// WarmWithTTL populates the cache with items silently, using the given TTL.
+// Write events are not fired, while evictions fire as usual.
+func (c *Cache) WarmWithTTL(items map[interface{}]interface{}, ttl time.Duration) {
+	c.silent = true
+	defer func() {
+		c.silent = false
+	}()
+
+	for k, v := range items {
+		c.StoreWithTTL(k, v, ttl)
+	}
+}
+
+// LoadMany returns the found keys value.
+func (c *Cache) LoadMany(keys ...interface{}) map[interface{}]interface{} {
+	items := make(map[interface{}]interface{})
+	for _, k := range keys {
+		if v, ok := c.Load(k); ok {
+			items[c.keyFunc.Key(k)] = v
+		}
+	}
+	return items
+}
+
+// Update the key value without updating the underlying "rank".
+func (c *Cache) Update(key, value interface{}) {
+	id := c.keyFunc.Key(key)
+
+	// Run GC inline before update the entry.
+	c.gcKey(id)
+
+	e, ok := c.live(id)
+	if !ok {
+		return
+	}
+
+	old := e.Value
+	c.setValue(e, value)
+	if c.refresh {
+		c.setExp(e, c.ttl)
+	}
+
+	c.emitWrite(e, old, true)
+}
+
+// live returns the entry of the normalized key, negative entries excluded,
+// so the write paths treat negative entries as absent, like the reads.
+func (c *Cache) live(id interface{}) (*Entry, bool) {
+	e, ok := c.entries[id]
+	if !ok || IsNegative(e.Value) {
+		return nil, false
+	}
+	return e, true
+}
+
+// CompareAndSwap swaps the key value if the current value equal to old,
+// without updating the underlying "rank".
+func (c *Cache) CompareAndSwap(key, old, new interface{}) bool {
+	id := c.keyFunc.Key(key)
+
+	// Run GC inline before swap the entry value.
+	c.gcKey(id)
+
+	e, ok := c.live(id)
+	if !ok || !Equal(e.Value, old) {
+		return false
+	}
+
+	c.setValue(e, new)
+	if c.refresh {
+		c.setExp(e, c.ttl)
+	}
+
+	c.emitWrite(e, old, true)
+	return true
+}
+
+// CompareAndDelete deletes the key value if the current value equal to old.
+func (c *Cache) CompareAndDelete(key, old interface{}) bool {
+	id := c.keyFunc.Key(key)
+
+	// Run GC inline before delete the entry.
+	c.gcKey(id)
+
+	e, ok := c.live(id)
+	if !ok || !Equal(e.Value, old) {
+		return false
+	}
+
+	c.evict(e)
+	return true
+}
+
+// Increment adds delta to the key int64 value and returns the new value,
+// without updating the underlying "rank" or the key expiry.
+func (c *Cache) Increment(key interface{}, delta int64) (int64, error) {
+	id := c.keyFunc.Key(key)
+
+	// Run GC inline before update the entry.
+	c.gcKey(id)
+
+	e, ok := c.live(id)
+	if !ok {
+		c.Store(key, delta)
+		if _, ok := c.live(id); !ok {
+			return 0, ErrNotStored
+		}
+		return delta, nil
+	}
+
+	n, ok := e.Value.(int64)
+	if !ok {
+		return 0, ErrNotInt64
+	}
+
+	c.setValue(e, n+delta)
+	c.emitWrite(e, n, true)
+	return n + delta, nil
+}
+
+// Decrement subtracts delta from the key int64 value and returns the new value,
+// without updating the underlying "rank" or the key expiry.
+func (c *Cache) Decrement(key interface{}, delta int64) (int64, error) {
+	return c.Increment(key, -delta)
+}
+
+// Purge Clears all cache entries, and fires a single Purge event with nil key,
+// or a Remove event for each entry if per-key purge events enabled.
+func (c *Cache) Purge() {
+	defer c.coll.Init()
+
+	if !c.purgeRemoves || (c.emitter.Len() == 0 && c.relay == nil) {
+		c.entries = make(map[interface{}]*Entry, c.reserve)
+		c.pinned = make(map[interface{}]*Entry)
+		c.tags = make(map[string]map[interface{}]struct{})
+		c.heap = nil
+		c.cost = 0
+		c.keyBytes = 0
+		if c.bloom != nil {
+			c.bloom.reset()
+		}
+		if c.order != nil {
+			c.order.Init()
+		}
+		if c.emitter.Len() > 0 || c.relay != nil {
+			c.emit(Purge, nil, nil, time.Time{}, 0, false)
+		}
+		return
+	}
+
+	c.each(c.evict)
+}
+
+// Reset Clears all cache entries, closes the channels allocated by Subscribe and Watch,
+// removes all Notify channels, and restores the cache configuration to its defaults.
+func (c *Cache) Reset() {
+	c.unsubscribe()
+	c.emitter.Clear()
+	c.Purge()
+	c.counters.Reset()
+	c.clock = realClock{}
+	c.ttl = 0
+	c.jitter = 0
+	c.rand = nil
+	c.refresh = false
+	c.maxIdle = 0
+	c.gcBatch = 0
+	c.weigher = nil
+	c.admit = nil
+	c.maxCost = 0
+	c.maxKeyBytes = 0
+	c.purgeRemoves = false
+	c.keyFunc = nil
+	c.emitter.SetKeyFunc(nil)
+	c.bloom = nil
+	c.paused = false
+	c.order = nil
+	c.capacity = c.initCap
+}
+
+// PauseEviction defers the capacity and cost eviction, so stores exceed
+// the cache capacity until ResumeEviction called. Expired entries still collected.
+func (c *Cache) PauseEviction() {
+	c.paused = true
+}
+
+// ResumeEviction evicts entries down to the cache capacity, cost ceiling
+// and key bytes budget in one pass, and re-enables the eviction on store.
+func (c *Cache) ResumeEviction() {
+	c.paused = false
+
+	// Pinned entries are never discarded,
+	// so stop once the collection drained.
+	for c.capacity != 0 && c.Len() > c.capacity && c.evictable() > 0 {
+		c.Discard()
+	}
+
+	c.fitCost()
+	c.fitKeyBytes()
+}
+
+// Reserve preallocates the cache entries index for n entries,
+// it takes effect immediately if the cache empty, otherwise once purged.
+func (c *Cache) Reserve(n int) {
+	if n < 0 {
+		n = 0
+	}
+
+	c.reserve = n
+	if len(c.entries) == 0 {
+		c.entries = make(map[interface{}]*Entry, n)
+	}
+}
+
+// Resize cache, returning number evicted.
+// Resize is an alias of SetCapacity.
+func (c *Cache) Resize(size int) int {
+	return c.SetCapacity(size)
+}
+
+// SetCapacity sets the cache capacity, and discards entries down to the new capacity,
+// firing a Remove event for each, returning the number evicted.
+// Zero capacity means unbounded, and never discards,
+// and negative capacity clamped to zero.
+func (c *Cache) SetCapacity(n int) int {
+	if n < 0 {
+		n = 0
+	}
+
+	c.capacity = n
+	evicted := 0
+
+	// Pinned entries are never discarded,
+	// so stop once the collection drained.
+	for n > 0 && c.Len() > n && c.evictable() > 0 {
+		c.Discard()
+		evicted++
+	}
+
+	return evicted
+}
+
+// ResizeCost sets the entries total cost ceiling, zero means no ceiling,
+// and discards entries until the total cost fits, returning the cost evicted.
+func (c *Cache) ResizeCost(maxCost int64) int64 {
+	c.maxCost = maxCost
+	cost := c.cost
+	c.fitCost()
+	return cost - c.cost
+}
+
+// fitCost discards entries until the total cost fits the cost ceiling.
+//
+// The cost ceiling and key bytes budget enforced by one rule, on ResizeCost,
+// SetMaxKeyBytes and ResumeEviction alike: the entries exceeding the limit alone
+// discarded first, as they never fit, then the entries in eviction order down to the limit,
+// the last remaining entry included. Pinned entries are never discarded,
+// so the cache stays above the limit once only pinned entries remain.
+// Stores discard in eviction order only, and never the stored entry.
+func (c *Cache) fitCost() {
+	if c.maxCost <= 0 || c.cost <= c.maxCost {
+		return
+	}
+
+	c.EvictOversized(c.maxCost, 0)
+	for c.cost > c.maxCost && c.evictable() > 0 {
+		c.Discard()
+	}
+}
+
+// EvictOversized evicts the unpinned entries whose cost alone exceeds maxCost,
+// or whose key alone exceeds maxKeyBytes, and returns their keys, zero means no limit.
+func (c *Cache) EvictOversized(maxCost, maxKeyBytes int64) []interface{} {
+	var keys []interface{}
+	for _, e := range c.unpinned() {
+		if (maxCost > 0 && e.Cost > maxCost) || (maxKeyBytes > 0 && KeyBytes(e.Key) > maxKeyBytes) {
+			keys = append(keys, e.Key)
+			c.evictEntry(e)
+		}
+	}
+	return keys
+}
+
+// SetMaxKeyBytes sets the entries total key bytes budget, zero or negative means no budget,
+// and discards entries until the total key bytes fits.
+func (c *Cache) SetMaxKeyBytes(n int64) {
+	if n < 0 {
+		n = 0
+	}
+
+	c.maxKeyBytes = n
+	c.fitKeyBytes()
+}
+
+// fitKeyBytes discards entries until the total key bytes fits the key bytes budget,
+// by the fitCost rule.
+func (c *Cache) fitKeyBytes() {
+	if c.maxKeyBytes <= 0 || c.keyBytes <= c.maxKeyBytes {
+		return
+	}
+
+	c.EvictOversized(0, c.maxKeyBytes)
+	for c.keyBytes > c.maxKeyBytes && c.evictable() > 0 {
+		c.Discard()
+	}
+}
+
+// KeyBytes returns the total key bytes of the cache entries.
+func (c *Cache) KeyBytes() int64 {
+	return c.keyBytes
+}
+
+// NonStringKeyBytes is the fixed number of bytes a non string key counts
+// toward the key bytes budget, the size of an interface value.
+const NonStringKeyBytes = 16
+
+// KeyBytes returns the number of bytes the key counts toward the key bytes budget,
+// the length of string keys, and NonStringKeyBytes otherwise.
+func KeyBytes(key interface{}) int64 {
+	if s, ok := key.(string); ok {
+		return int64(len(s))
+	}
+	return NonStringKeyBytes
+}
+
+// Pin protects the key value from being discarded by the cache replacement policy,
+// until the key unpinned. if the capacity still exceeded after discarding all unpinned
+// entries, the new entries stored anyway, growing the cache beyond its capacity.
+// Pinned entries still removed when their TTL elapsed.
+// Pinned entries stay in the replacement policy, so they keep their access history,
+// e.g. LFU frequency, and an eviction walks past a pinned eviction candidate in O(n).
+//
+// Pin reports whether the key exist.
+func (c *Cache) Pin(key interface{}) bool {
+	id := c.keyFunc.Key(key)
+
+	// Run GC inline before pin the entry.
+	c.gcKey(id)
+
+	e, ok := c.entries[id]
+	if !ok {
+		return false
+	}
+
+	c.pinned[e.id] = e
+	return true
+}
+
+// Pinned reports whether the key pinned.
+func (c *Cache) Pinned(key interface{}) bool {
+	_, ok := c.pinned[c.keyFunc.Key(key)]
+	return ok
+}
+
+// Unpin returns a pinned key value back to the cache replacement policy.
+// Unpin reports whether the key was pinned.
+func (c *Cache) Unpin(key interface{}) bool {
+	e, ok := c.pinned[c.keyFunc.Key(key)]
+	if !ok {
+		return false
+	}
+
+	delete(c.pinned, e.id)
+	return true
+}
+
+// DelSilently the key value silently without call onEvicted.
+func (c *Cache) DelSilently(key interface{}) {
+	if e, ok := c.entries[c.keyFunc.Key(key)]; ok {
+		c.removeEntry(e)
+		release(e)
+	}
+}
+
+// Delete deletes the key value.
+func (c *Cache) Delete(key interface{}) {
+	if e, ok := c.entries[c.keyFunc.Key(key)]; ok {
+		c.evict(e)
+	}
+}
+
+// GetAndDelete deletes the key value and returns its value if any.
+func (c *Cache) GetAndDelete(key interface{}) (value interface{}, loaded bool) {
+	id := c.keyFunc.Key(key)
+
+	// Run GC inline before delete the entry.
+	c.gcKey(id)
+
+	e, ok := c.entries[id]
+	if !ok {
+		return nil, false
+	}
+
+	value, loaded = e.Value, !IsNegative(e.Value)
+	c.evict(e)
+	if !loaded {
+		value = nil
+	}
+	return value, loaded
+}
+
+// DeleteMany deletes the keys value.
+func (c *Cache) DeleteMany(keys ...interface{}) {
+	for _, k := range keys {
+		c.Delete(k)
+	}
+}
+
+// Contains Checks if a key exists in cache, negative entries excluded.
+func (c *Cache) Contains(key interface{}) bool {
+	e, ok := c.GetEntry(key)
+	return ok && !IsNegative(e.Value)
+}
+
+// Has reports whether the key entry resident, negative entries included,
+// without emitting events, so caches composed of several caches find the key placement.
+func (c *Cache) Has(key interface{}) bool {
+	id := c.keyFunc.Key(key)
+	if !c.mayContain(id) {
+		return false
+	}
+
+	// Run GC inline before lookup the entry.
+	c.gcKey(id)
+
+	_, ok := c.entries[id]
+	return ok
+}
+
+// ContainsNoGC Checks if a key exists in cache, negative entries excluded,
+// by looking up the key entry and checking its own expiry,
+// without running GC, emitting events or recording metrics.
+//
+// Unlike Contains, expired entries are left resident until the next sweep,
+// so they still count in Len and memory while ContainsNoGC reports them absent.
+func (c *Cache) ContainsNoGC(key interface{}) bool {
+	id := c.keyFunc.Key(key)
+	if !c.mayContain(id) {
+		return false
+	}
+
+	e, ok := c.entries[id]
+	if !ok || IsNegative(e.Value) {
+		return false
+	}
+
+	deadline := c.deadline(e)
+	return deadline.IsZero() || c.clock.Now().Before(deadline)
+}
+
+// ContainsMany Checks if the keys exists in cache,
+// and returns presence flags in the same order of the given keys.
+func (c *Cache) ContainsMany(keys []interface{}) []bool {
+	// Run GC once before checking the entries.
+	c.gc()
+
+	flags := make([]bool, len(keys))
+	for i, k := range keys {
+		e, ok := c.entries[c.keyFunc.Key(k)]
+		flags[i] = ok && !IsNegative(e.Value)
+	}
+	return flags
+}
+
+// Frequency returns the key access frequency as tracked by the collection,
+// zero frequency returned when the collection does not track it.
+func (c *Cache) Frequency(key interface{}) (int, bool) {
+	id := c.keyFunc.Key(key)
+
+	// Run GC inline before return the entry frequency.
+	c.gcKey(id)
+
+	e, ok := c.entries[id]
+	if !ok {
+		return 0, false
+	}
+
+	if f, ok := c.coll.(frequencer); ok {
+		return f.Frequency(e), true
+	}
+
+	return 0, true
+}
+
+// Snapshot returns a shallow copy of the cache live entries keys and values,
+// negative entries excluded.
+func (c *Cache) Snapshot() map[interface{}]interface{} {
+	// Run GC inline before copy the entries.
+	c.gc()
+
+	m := make(map[interface{}]interface{}, len(c.entries))
+	for k, e := range c.entries {
+		if !IsNegative(e.Value) {
+			m[k] = e.Value
+		}
+	}
+	return m
+}
+
+// Iterator returns an iterator over a snapshot of the cache entries,
+// negative entries excluded.
+func (c *Cache) Iterator() *Iterator {
+	// Run GC inline before snapshot the entries.
+	c.gc()
+
+	items := make([]item, 0, len(c.entries))
+	c.each(func(e *Entry) {
+		if !IsNegative(e.Value) {
+			items = append(items, item{key: e.Key, value: e.Value, exp: e.Exp})
+		}
+	})
+
+	return &Iterator{items: items, clock: c.clock}
+}
+
+// Keys return cache records keys, negative entries excluded.
+func (c *Cache) Keys() (keys []interface{}) {
+	c.each(func(e *Entry) {
+		if !IsNegative(e.Value) {
+			keys = append(keys, e.Key)
+		}
+	})
+	return
+}
+
+// StoreWithTags sets the key value and tags it with the given tags,
+// replacing the tags of its previous value if any.
+func (c *Cache) StoreWithTags(key, value interface{}, tags ...string) {
+	c.Store(key, value)
+	c.Tag(key, tags...)
+}
+
+// SetCreated sets the key entry creation time,
+// to keep the entry age when it moved between caches.
+// SetCreated reports whether the key exist.
+func (c *Cache) SetCreated(key interface{}, t time.Time) bool {
+	e, ok := c.entries[c.keyFunc.Key(key)]
+	if ok {
+		e.Created = t
+	}
+	return ok
+}
+
+// SetDelta sets the duration of the loader call that computed the key value.
+// SetDelta reports whether the key exist.
+func (c *Cache) SetDelta(key interface{}, d time.Duration) bool {
+	e, ok := c.entries[c.keyFunc.Key(key)]
+	if ok {
+		e.delta = d
+	}
+	return ok
+}
+
+// Delta returns the duration of the loader call that computed the key value,
+// and the key expiry, without running GC, firing events or updating the underlying "rank".
+func (c *Cache) Delta(key interface{}) (d time.Duration, exp time.Time, ok bool) {
+	e, ok := c.entries[c.keyFunc.Key(key)]
+	if ok {
+		d, exp = e.delta, e.Exp
+	}
+	return d, exp, ok
+}
+
+// Tag replaces the key tags with the given tags,
+// Tag without tags removes all the key tags.
+// Tag reports whether the key exist.
+func (c *Cache) Tag(key interface{}, tags ...string) bool {
+	e, ok := c.entries[c.keyFunc.Key(key)]
+	if !ok {
+		return false
+	}
+
+	c.untag(e)
+
+	if len(tags) == 0 {
+		return true
+	}
+
+	e.tags = append([]string(nil), tags...)
+	for _, tag := range tags {
+		keys, ok := c.tags[tag]
+		if !ok {
+			keys = make(map[interface{}]struct{})
+			c.tags[tag] = keys
+		}
+		keys[e.id] = struct{}{}
+	}
+
+	return true
+}
+
+// Tags returns the key tags.
+func (c *Cache) Tags(key interface{}) []string {
+	if e, ok := c.entries[c.keyFunc.Key(key)]; ok {
+		return e.tags
+	}
+	return nil
+}
+
+// InvalidateTag deletes all keys bearing the given tag,
+// and returns the number of deleted keys.
+func (c *Cache) InvalidateTag(tag string) int {
+	// Run GC inline before delete the entries,
+	// so expired entries are not counted.
+	c.gc()
+
+	n := 0
+	for id := range c.tags[tag] {
+		c.evict(c.entries[id])
+		n++
+	}
+
+	return n
+}
+
+// untag removes the entry key from its tags index.
+func (c *Cache) untag(e *Entry) {
+	for _, tag := range e.tags {
+		keys := c.tags[tag]
+		delete(keys, e.id)
+		if len(keys) == 0 {
+			delete(c.tags, tag)
+		}
+	}
+	e.tags = nil
+}
+
+// KeysWithPrefix returns the string keys starting with prefix,
+// keys of other types ignored.
+// KeysWithPrefix scans all the cache entries, therefore it runs in O(n).
+func (c *Cache) KeysWithPrefix(prefix string) (keys []interface{}) {
+	// Run GC inline before scan the entries.
+	c.gc()
+
+	c.each(func(e *Entry) {
+		if s, ok := e.Key.(string); ok && strings.HasPrefix(s, prefix) && !IsNegative(e.Value) {
+			keys = append(keys, e.Key)
+		}
+	})
+	return
+}
+
+// DeleteWithPrefix deletes the string keys starting with prefix,
+// and returns the number of deleted keys.
+// DeleteWithPrefix scans all the cache entries, therefore it runs in O(n).
+func (c *Cache) DeleteWithPrefix(prefix string) int {
+	keys := c.KeysWithPrefix(prefix)
+	c.DeleteMany(keys...)
+	return len(keys)
+}
+
+// DeleteFunc deletes the live entries satisfying pred,
+// and returns the number of deleted keys.
+// DeleteFunc scans all the cache entries, therefore it runs in O(n).
+func (c *Cache) DeleteFunc(pred func(key, value interface{}) bool) int {
+	// Run GC inline before scan the entries.
+	c.gc()
+
+	var keys []interface{}
+	c.each(func(e *Entry) {
+		if !IsNegative(e.Value) && pred(e.Key, e.Value) {
+			keys = append(keys, e.Key)
+		}
+	})
+
+	c.DeleteMany(keys...)
+	return len(keys)
+}
+
+// Len Returns the number of items in the cache.
+func (c *Cache) Len() int {
+	return c.coll.Len()
+}
+
+func (c *Cache) isPinned(e *Entry) bool {
+	_, ok := c.pinned[e.id]
+	return ok
+}
+
+// evictable returns the number of entries the replacement policy may discard.
+func (c *Cache) evictable() int {
+	return c.coll.Len() - len(c.pinned)
+}
+
+// PeekOldest returns the key value of the eviction candidate, without running GC
+// or updating the underlying "rank".
+func (c *Cache) PeekOldest() (key, value interface{}, ok bool) {
+	return peek(c.victim())
+}
+
+// PeekNewest returns the key value of the last entry in the eviction order,
+// without running GC or updating the underlying "rank".
+func (c *Cache) PeekNewest() (key, value interface{}, ok bool) {
+	back := c.coll.Back()
+	if back == nil || !c.isPinned(back) {
+		return peek(back)
+	}
+
+	entries := c.unpinned()
+	if len(entries) == 0 {
+		return
+	}
+	return peek(entries[len(entries)-1])
+}
+
+// OrderedKeys returns the keys in eviction order, from the eviction candidate
+// to the most protected entry, followed by the pinned keys, without running GC
+// or updating the underlying "rank". The order is policy-specific,
+// e.g. LRU returns the oldest to the newest key, and LFU the least to the most frequent key.
+//
+// Collections that can not walk their entries
+// return the eviction candidate first, then the rest in unspecified order.
+func (c *Cache) OrderedKeys() []interface{} {
+	entries := c.ordered()
+	keys := make([]interface{}, 0, len(entries))
+	for _, e := range entries {
+		if !IsNegative(e.Value) {
+			keys = append(keys, e.Key)
+		}
+	}
+	return keys
+}
+
+// ColdestN returns up to n entries in eviction order, starting from the eviction candidate,
+// without running GC or updating the underlying "rank".
+// It returns all the entries if n exceeds the cache length, and nil if n is not positive.
+// ColdestN walks all the cache entries, therefore it runs in O(n).
+func (c *Cache) ColdestN(n int) []EntryInfo {
+	if n <= 0 {
+		return nil
+	}
+
+	entries := c.ordered()
+	if n > len(entries) {
+		n = len(entries)
+	}
+
+	infos := make([]EntryInfo, 0, n)
+	for i := 0; i < n; i++ {
+		infos = append(infos, c.info(entries[i]))
+	}
+	return infos
+}
+
+// HottestN returns up to n entries in reverse eviction order, starting from the pinned
+// and the most protected entries, without running GC or updating the underlying "rank".
+// It returns all the entries if n exceeds the cache length, and nil if n is not positive.
+// HottestN walks all the cache entries, therefore it runs in O(n).
+func (c *Cache) HottestN(n int) []EntryInfo {
+	if n <= 0 {
+		return nil
+	}
+
+	entries := c.ordered()
+	if n > len(entries) {
+		n = len(entries)
+	}
+
+	infos := make([]EntryInfo, 0, n)
+	for i := 0; i < n; i++ {
+		infos = append(infos, c.info(entries[len(entries)-1-i]))
+	}
+	return infos
+}
+
+// ordered returns the entries in eviction order, followed by the pinned entries.
+func (c *Cache) ordered() []*Entry {
+	entries := c.unpinned()
+	for _, e := range c.pinned {
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// unpinned returns the unpinned entries in eviction order.
+func (c *Cache) unpinned() []*Entry {
+	entries := make([]*Entry, 0, c.Len())
+
+	if w, ok := c.coll.(walker); ok {
+		w.Walk(func(e *Entry) {
+			if !c.isPinned(e) {
+				entries = append(entries, e)
+			}
+		})
+		return entries
+	}
+
+	front := c.coll.Front()
+	if front != nil && !c.isPinned(front) {
+		entries = append(entries, front)
+	}
+
+	for id, e := range c.entries {
+		if _, ok := c.pinned[id]; !ok && e != front {
+			entries = append(entries, e)
+		}
+	}
+
+	return entries
+}
+
+// victim returns the eviction candidate, the first unpinned entry in eviction order.
+func (c *Cache) victim() *Entry {
+	front := c.coll.Front()
+	if front == nil {
+		return nil
+	}
+
+	if !c.isPinned(front) {
+		return front
+	}
+
+	if entries := c.unpinned(); len(entries) > 0 {
+		return entries[0]
+	}
+
+	return nil
+}
+
+func peek(e *Entry) (key, value interface{}, ok bool) {
+	if e == nil {
+		return
+	}
+	return e.Key, e.Value, true
+}
+
+// Discard oldest entry from cache to make room for the new ones.
+func (c *Cache) Discard() (key, value interface{}) {
+	key, value, _ = c.discard()
+	return
+}
+
+// discard evicts the oldest entry, and reports whether an entry evicted.
+func (c *Cache) discard() (key, value interface{}, ok bool) {
+	e := c.victim()
+	if e == nil {
+		return
+	}
+
+	key, value, ok = e.Key, e.Value, true
+	c.evictEntry(e)
+	return
+}
+
+// evictEntry evicts the given unpinned entry, and counts it as an eviction.
+func (c *Cache) evictEntry(e *Entry) {
+	// The collection discards its front entry, and may update its state doing so,
+	// while other entries removed past the pinned front.
+	if e == c.coll.Front() {
+		c.coll.Discard()
+	} else {
+		c.coll.Remove(e)
+	}
+
+	c.counters.Evict(c.clock.Now())
+	c.evict(e)
+}
+
+func (c *Cache) removeEntry(e *Entry) {
+	delete(c.pinned, e.id)
+	c.coll.Remove(e)
+
+	c.untag(e)
+
+	delete(c.entries, e.id)
+	if e.ordered != nil {
+		c.order.Remove(e.ordered)
+	}
+	if c.bloom != nil {
+		c.bloom.remove(Hash(e.id))
+	}
+
+	c.cost -= e.Cost
+	c.keyBytes -= KeyBytes(e.Key)
+	// Remove entry from the heap, the entry may does not exist because
+	// it has zero ttl or already popped up by gc
+	if e.index >= 0 {
+		heap.Remove(&c.heap, e.index)
+	}
+}
+
+// setValue sets the entry value, and updates its cost.
+func (c *Cache) setValue(e *Entry, v interface{}) {
+	c.cost -= e.Cost
+	e.Value = v
+	e.Cost = 1
+	if c.weigher != nil {
+		e.Cost = c.weigher(e.Key, v)
+	}
+	c.cost += e.Cost
+}
+
+// setExp sets entry expiry to now plus the given ttl,
+// and fix the entry position in the expiring heap.
+// zero or negative ttl means the entry never expires.
+func (c *Cache) setExp(e *Entry, ttl time.Duration) {
+	e.Exp = time.Time{}
+	if ttl > 0 {
+		e.Exp = c.clock.Now().UTC().Add(ttl)
+	}
+
+	c.schedule(e)
+}
+
+// schedule sets the entry deadline to the earliest of its expiry and max idle time,
+// and fix the entry position in the expiring heap.
+func (c *Cache) schedule(e *Entry) {
+	e.deadline = c.deadline(e)
+
+	ok := e.index >= 0
+
+	switch {
+	case e.deadline.IsZero() && ok:
+		heap.Remove(&c.heap, e.index)
+	case e.deadline.IsZero():
+	case ok:
+		heap.Fix(&c.heap, e.index)
+	default:
+		heap.Push(&c.heap, e)
+	}
+}
+
+// deadline returns the earliest of the entry expiry and max idle time.
+func (c *Cache) deadline(e *Entry) time.Time {
+	deadline := e.Exp
+	if c.maxIdle > 0 {
+		idle := e.Accessed.Add(c.maxIdle)
+		if deadline.IsZero() || idle.Before(deadline) {
+			deadline = idle
+		}
+	}
+	return deadline
+}
+
+// evict remove entry and fire on evicted callback.
+func (c *Cache) evict(e *Entry) {
+	c.removeEntry(e)
+	c.emit(Remove, e.Key, e.Value, e.Exp, e.Cost, false)
+	release(e)
+}
+
+// expire remove entry and fire on expired event.
+func (c *Cache) expire(e *Entry) {
+	c.counters.Expire()
+	c.removeEntry(e)
+	c.emit(Expire, e.Key, e.Value, e.Exp, e.Cost, false)
+	release(e)
+}
+
+func (c *Cache) emit(op Op, k, v interface{}, exp time.Time, cost int64, ok bool) {
+	c.notify(Event{
+		Op:     op,
 		Key:    k,
 		Value:  v,
 		Expiry: exp,
+		Cost:   cost,
 		Ok:     ok,
+	})
+}
+
+// emitWrite emits a write event of the given entry,
+// along with the key previous value if any.
+func (c *Cache) emitWrite(e *Entry, old interface{}, hadOld bool) {
+	if c.silent {
+		return
 	}
 
-	for c, h := range c.handlers {
-		if h.want(op) {
-			// send but do not block for it
-			select {
-			case c <- e:
-			default:
-			}
-		}
+	c.notify(Event{
+		Op:     Write,
+		Key:    e.Key,
+		Value:  e.Value,
+		Expiry: e.Exp,
+		Old:    old,
+		HadOld: hadOld,
+		Cost:   e.Cost,
+	})
+}
+
+func (c *Cache) notify(e Event) {
+	if c.relay != nil {
+		c.relay(e)
+		return
 	}
+
+	c.emitter.Emit(e)
+}
+
+// Relay causes cache to pass all its events to fn synchronously,
+// instead of the channels registered by Notify.
+// It used by composite caches to decide which of the underlying caches
+// events surface to the users as their own.
+//
+// fn invoked synchronously, so a composite cache serializing its own operations
+// serializes its underlying caches emission as well.
+func (c *Cache) Relay(fn func(Event)) {
+	c.relay = fn
 }
 
 // GC returns the remaining time duration for the next gc cycle if there any,
 // Otherwise, it return 0.
 //
 // Calling GC without waits for the duration to elapsed considered a no-op.
+//
+// GC records the number of swept entries and the sweep duration in the metrics,
+// while the sweeps run inline by the other operations are not recorded.
 func (c *Cache) GC() time.Duration {
-	now := time.Now()
-	for {
+	start := time.Now()
+	n := c.counters.Expirations()
+	next := c.sweep(c.gcBatch)
+	c.counters.Sweep(c.counters.Expirations()-n, time.Since(start))
+	return next
+}
+
+// GCBatchPause is returned by GC when its batch left expired entries,
+// so the caller comes back promptly, while the other operations take turns.
+const GCBatchPause = time.Millisecond
+
+// SetGCBatchSize sets the max number of expired entries a GC call sweeps,
+// and the operations on a single key sweep inline, zero or negative means no limit.
+// The scans, such as Snapshot and Iterator, still sweep all the elapsed entries.
+func (c *Cache) SetGCBatchSize(n int) {
+	if n < 0 {
+		n = 0
+	}
+	c.gcBatch = n
+}
+
+// gc expires all the elapsed entries, and returns the remaining time duration
+// for the next gc cycle if there any, Otherwise, it return 0.
+// It runs inline by the operations scanning the entries.
+func (c *Cache) gc() time.Duration {
+	return c.sweep(0)
+}
+
+// gcKey runs inline by the operations on a single key, given its normalized form.
+// It sweeps up to the GC batch size elapsed entries, leaving the remainder to the next sweeps,
+// and expires the key entry if elapsed, so the operation never sees it.
+func (c *Cache) gcKey(id interface{}) {
+	c.sweep(c.gcBatch)
+
+	if e, ok := c.entries[id]; ok && e.index >= 0 && !c.clock.Now().Before(e.deadline) {
+		c.expire(e)
+	}
+}
+
+// sweep expires up to batch elapsed entries, zero means all of them,
+// and returns the remaining time duration for the next gc cycle if there any,
+// Otherwise, it return 0.
+func (c *Cache) sweep(batch int) time.Duration {
+	now := c.clock.Now()
+	for n := 0; ; n++ {
 
 		// Return from gc if the heap is empty or the next element is not yet
 		// expired.
@@ -301,13 +2034,36 @@ func (c *Cache) GC() time.Duration {
 			return 0
 		}
 
-		if now.Before(c.heap[0].Exp) {
-			return c.heap[0].Exp.Sub(now)
+		if now.Before(c.heap[0].deadline) {
+			return c.heap[0].deadline.Sub(now)
+		}
+
+		if batch > 0 && n == batch {
+			return GCBatchPause
 		}
 
 		e := heap.Pop(&c.heap).(*Entry)
-		c.evict(e)
+		c.expire(e)
+	}
+}
+
+// applyJitter returns ttl plus a random duration in range [-jitter, +jitter].
+// The jitter never makes a positive ttl non-positive.
+func (c *Cache) applyJitter(ttl, jitter time.Duration) time.Duration {
+	if ttl <= 0 || jitter <= 0 {
+		return ttl
+	}
+
+	if jitter >= ttl {
+		jitter = ttl - 1
+	}
+
+	n := int64(2*jitter) + 1
+	if c.rand != nil {
+		return ttl + time.Duration(c.rand.Int63n(n)) - jitter
 	}
+
+	return ttl + time.Duration(rand.Int63n(n)) - jitter
 }
 
 // TTL returns entries default TTL.
@@ -320,6 +2076,188 @@ func (c *Cache) SetTTL(ttl time.Duration) {
 	c.ttl = ttl
 }
 
+// SetClock sets the clock used to compute and check entries expiry.
+func (c *Cache) SetClock(clock Clock) {
+	c.clock = clock
+}
+
+// SetJitter sets entries default TTL jitter.
+func (c *Cache) SetJitter(jitter time.Duration) {
+	c.jitter = jitter
+}
+
+// SetRandSource sets the random source used to compute TTL jitter.
+// By default, the top-level math/rand functions are used.
+func (c *Cache) SetRandSource(src rand.Source) {
+	c.rand = rand.New(src) //nolint:gosec
+}
+
+// DeterministicSeed is the seed of the random sources of deterministic caches.
+const DeterministicSeed = 1
+
+// SetDeterministic sets whether the cache scans visit the entries in store order,
+// and seeds the TTL jitter random source with DeterministicSeed, if not already set,
+// so identically populated caches behave the same. The entries stored before
+// enabling it indexed in unspecified order.
+func (c *Cache) SetDeterministic(enabled bool) {
+	if !enabled {
+		c.each(func(e *Entry) {
+			e.ordered = nil
+		})
+		c.order = nil
+		return
+	}
+
+	if c.order != nil {
+		return
+	}
+
+	c.order = list.New()
+	for _, e := range c.entries {
+		e.ordered = c.order.PushBack(e)
+	}
+
+	if c.rand == nil {
+		c.SetRandSource(rand.NewSource(DeterministicSeed))
+	}
+}
+
+// each calls fn for each entry, in store order if the cache deterministic.
+// fn may remove the given entry.
+func (c *Cache) each(fn func(*Entry)) {
+	if c.order == nil {
+		for _, e := range c.entries {
+			fn(e)
+		}
+		return
+	}
+
+	for le := c.order.Front(); le != nil; {
+		next := le.Next()
+		fn(le.Value.(*Entry))
+		le = next
+	}
+}
+
+// SetKeyFunc sets the function used to normalize keys into a comparable form,
+// nil means keys used as is. SetKeyFunc purges the cache entries,
+// as they indexed by the previous function.
+func (c *Cache) SetKeyFunc(fn KeyFunc) {
+	c.Purge()
+	c.keyFunc = fn
+	c.emitter.SetKeyFunc(fn)
+}
+
+// SetMaxIdle sets the max duration an entry lives without being loaded,
+// regardless of its TTL, the earliest of the entry expiry and max idle time wins.
+// Zero or negative duration disables it.
+func (c *Cache) SetMaxIdle(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+
+	c.maxIdle = d
+	for _, e := range c.entries {
+		c.schedule(e)
+	}
+}
+
+// SetBloomFilter fronts the cache lookups with a counting bloom filter
+// sized for expectedN keys at the given false positive rate,
+// so lookups of absent keys return without touching the entries.
+// Zero or negative expectedN disables it.
+func (c *Cache) SetBloomFilter(expectedN int, fpRate float64) {
+	if expectedN <= 0 {
+		c.bloom = nil
+		return
+	}
+
+	c.bloom = newBloomFilter(expectedN, fpRate)
+	for id := range c.entries {
+		c.bloom.add(Hash(id))
+	}
+}
+
+// mayContain reports whether the bloom filter may contain the key id,
+// it always true when the bloom filter disabled.
+func (c *Cache) mayContain(id interface{}) bool {
+	return c.bloom == nil || c.bloom.mayContain(Hash(id))
+}
+
+// SetPurgeRemoveEvents sets whether Purge fires a Remove event for each entry,
+// instead of a single Purge event.
+func (c *Cache) SetPurgeRemoveEvents(enabled bool) {
+	c.purgeRemoves = enabled
+}
+
+// SetUpdateRefreshesTTL sets whether Update resets the entry expiry
+// to now plus the default TTL.
+func (c *Cache) SetUpdateRefreshesTTL(refresh bool) {
+	c.refresh = refresh
+}
+
+// Metrics returns a snapshot of the cache counters and size.
+func (c *Cache) Metrics() Metrics {
+	m := c.counters.Metrics()
+	m.Len = c.Len()
+	m.Cap = c.Cap()
+	m.Cost = c.cost
+	m.RecentEvictions = c.counters.RecentEvictions(c.clock.Now())
+	return m
+}
+
+// Utilization returns how full the cache is, as the ratio of the total cost
+// to the cost ceiling if any, otherwise the ratio of the length to the capacity.
+// Utilization returns 0 for unbounded caches.
+func (c *Cache) Utilization() float64 {
+	if c.maxCost > 0 {
+		return float64(c.cost) / float64(c.maxCost)
+	}
+	if c.Cap() == 0 {
+		return 0
+	}
+	return float64(c.Len()) / float64(c.Cap())
+}
+
+// Cost returns the total cost of the cache entries.
+func (c *Cache) Cost() int64 {
+	return c.cost
+}
+
+// CostOf returns the key value cost.
+func (c *Cache) CostOf(key interface{}) (int64, bool) {
+	if e, ok := c.entries[c.keyFunc.Key(key)]; ok {
+		return e.Cost, ok
+	}
+	return 0, false
+}
+
+// ExpiryOf returns the key value expiry, without running GC or emitting events.
+func (c *Cache) ExpiryOf(key interface{}) (time.Time, bool) {
+	if e, ok := c.entries[c.keyFunc.Key(key)]; ok {
+		return e.Exp, ok
+	}
+	return time.Time{}, false
+}
+
+// SetWeigher sets the function used to compute the cost of entries stored afterwards,
+// nil weigher means each entry costs 1.
+func (c *Cache) SetWeigher(w Weigher) {
+	c.weigher = w
+}
+
+// SetAdmissionFunc sets the function deciding whether new keys stored,
+// nil means all of them. Stores of existing keys and negative entries always admitted.
+func (c *Cache) SetAdmissionFunc(fn AdmissionFunc) {
+	c.admit = fn
+}
+
+// Admits reports whether fn admits the new key value,
+// nil fn and negative entries always admitted.
+func Admits(fn AdmissionFunc, key, value interface{}) bool {
+	return fn == nil || IsNegative(value) || fn(key, value)
+}
+
 // Cap Returns the cache capacity.
 func (c *Cache) Cap() int {
 	return c.capacity
@@ -329,42 +2267,113 @@ func (c *Cache) Cap() int {
 // If no operations are provided, all incoming operations will be relayed to ch.
 // Otherwise, just the provided operations will.
 func (c *Cache) Notify(ch chan<- Event, ops ...Op) {
-	if ch == nil {
-		panic("libcache: Notify using nil channel")
+	c.emitter.Notify(ch, ops...)
+}
+
+// NotifyBlocking causes cache to relay events to ch,
+// and waits for ch receiver, instead of dropping events when ch is full.
+func (c *Cache) NotifyBlocking(ch chan<- Event, ops ...Op) {
+	c.emitter.NotifyBlocking(ch, ops...)
+}
+
+// NotifyWithReplay causes cache to send a synthetic Write event
+// for each live entry to ch, if the provided operations include Write,
+// then to relay events to ch like Notify.
+// The replay waits for ch receiver.
+func (c *Cache) NotifyWithReplay(ch chan<- Event, ops ...Op) {
+	c.Replay(ch, ops...)
+	c.emitter.Notify(ch, ops...)
+}
+
+// Replay sends a synthetic Write event for each live entry to ch,
+// if the provided operations include Write. Replay waits for ch receiver.
+func (c *Cache) Replay(ch chan<- Event, ops ...Op) {
+	if !Wants(ops, Write) {
+		return
 	}
 
-	h := new(handler)
-	c.handlers[ch] = h
+	// Run GC inline before replay the entries.
+	c.gc()
 
-	if len(ops) == 0 {
-		for i := 1; i <= int(maxOp); i++ {
-			h.set(Op(i))
+	c.each(func(e *Entry) {
+		ch <- Event{
+			Op:     Write,
+			Key:    e.Key,
+			Value:  e.Value,
+			Expiry: e.Exp,
+			Cost:   e.Cost,
 		}
-		return
+	})
+}
+
+// Subscribe allocates a channel and causes cache to relay events to it.
+// It returns the channel along with a function that unsubscribe and closes the channel.
+func (c *Cache) Subscribe(ops ...Op) (<-chan Event, func()) {
+	if c.closed {
+		ch := make(chan Event)
+		close(ch)
+		return ch, func() {}
 	}
 
-	for _, op := range ops {
-		h.set(op)
+	ch, cancel := Subscribe(c, ops...)
+	c.subs[ch] = cancel
+
+	return ch, func() {
+		delete(c.subs, ch)
+		cancel()
 	}
 }
 
-// Ignore causes the provided ops to be ignored. Ignore undoes the effect
-// of any prior calls to Notify for the provided ops.
-// If no ops are provided, ch removed.
-func (c *Cache) Ignore(ch chan<- Event, ops ...Op) {
-	if len(ops) == 0 {
-		delete(c.handlers, ch)
-		return
+// Watch allocates a channel and causes cache to relay the key
+// Write, Remove and Expire events to it.
+// It returns the channel along with a function that unwatch and closes the channel.
+func (c *Cache) Watch(key interface{}) (<-chan Event, func()) {
+	if c.closed {
+		ch := make(chan Event)
+		close(ch)
+		return ch, func() {}
 	}
 
-	h, ok := c.handlers[ch]
-	if !ok {
-		return
+	ch, cancel := c.emitter.Watch(key)
+	c.subs[ch] = cancel
+
+	return ch, func() {
+		delete(c.subs, ch)
+		cancel()
 	}
+}
 
-	for _, op := range ops {
-		h.clear(op)
+// Close purges the cache entries, unsubscribe all channels allocated by Subscribe and Watch,
+// and removes all Notify channels.
+// After Close, the cache stores nothing and every lookup returns not-found.
+func (c *Cache) Close() error {
+	c.unsubscribe()
+	c.emitter.Clear()
+	c.Purge()
+	c.closed = true
+	return nil
+}
+
+// unsubscribe closes the channels allocated by Subscribe and Watch.
+func (c *Cache) unsubscribe() {
+	for _, cancel := range c.subs {
+		cancel()
 	}
+
+	c.subs = make(map[<-chan Event]func())
+}
+
+// NotifyFunc causes cache to call fn synchronously for each event of the provided ops,
+// see Emitter.NotifyFunc.
+func (c *Cache) NotifyFunc(fn func(Event), ops ...Op) {
+	c.emitter.NotifyFunc(fn, ops...)
+}
+
+// Ignore causes the provided ops to be ignored. Ignore undoes the effect
+// of any prior calls to Notify for the provided ops.
+// If no ops are provided, ch removed.
+func (c *Cache) Ignore(ch chan<- Event, ops ...Op) {
+	c.emitter.Ignore(ch, ops...)
 }
 
 // RegisterOnEvicted registers a function,
@@ -380,13 +2389,48 @@ func (c *Cache) RegisterOnExpired(fn func(key, value interface{})) {
 }
 
 // New return new abstracted cache.
+// Zero capacity means unbounded, and negative capacity clamped to zero.
 func New(c Collection, cap int) *Cache {
+	if cap < 0 {
+		cap = 0
+	}
+
 	return &Cache{
 		coll:     c,
+		clock:    realClock{},
 		capacity: cap,
+		initCap:  cap,
 		entries:  make(map[interface{}]*Entry),
-		handlers: make(map[chan<- Event]*handler),
+		pinned:   make(map[interface{}]*Entry),
+		tags:     make(map[string]map[interface{}]struct{}),
+		subs:     make(map[<-chan Event]func()),
+	}
+}
+
+// Unwrap returns the cache itself, so the policy packages wrappers,
+// that report their replacement policy, reach the cache through the embedded method.
+func (c *Cache) Unwrap() *Cache {
+	return c
+}
+
+// Unwrap returns the internal cache of c, nil if c is neither an internal cache nor wraps one.
+func Unwrap(c interface{}) *Cache {
+	u, ok := c.(interface{ Unwrap() *Cache })
+	if !ok {
+		return nil
 	}
+	return u.Unwrap()
+}
+
+// Equal reports whether x and y are equal,
+// it returns false instead of panic when x and y are not comparable.
+func Equal(x, y interface{}) (ok bool) {
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+	return x == y
 }
 
 // expiringHeap is a min-heap ordered by expiration time of its entries. The
@@ -401,7 +2445,7 @@ func (cq expiringHeap) Len() int {
 }
 
 func (cq expiringHeap) Less(i, j int) bool {
-	return cq[i].Exp.Before(cq[j].Exp)
+	return cq[i].deadline.Before(cq[j].deadline)
 }
 
 func (cq expiringHeap) Swap(i, j int) {
@@ -415,7 +2459,10 @@ func (cq *expiringHeap) Push(c interface{}) {
 }
 
 func (cq *expiringHeap) Pop() interface{} {
-	c := (*cq)[cq.Len()-1]
-	*cq = (*cq)[:cq.Len()-1]
+	n := cq.Len()
+	c := (*cq)[n-1]
+	(*cq)[n-1] = nil
+	c.index = -1
+	*cq = (*cq)[:n-1]
 	return c
 }
@@ -0,0 +1,167 @@
+package internal_test
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/shaj13/libcache/internal"
+	"github.com/shaj13/libcache/lru"
+)
+
+func TestCacheJitter(t *testing.T) {
+	newCache := func() *internal.Cache {
+		cache := internal.Unwrap(lru.New(0))
+		cache.SetRandSource(rand.NewSource(1))
+		cache.SetJitter(time.Second)
+		return cache
+	}
+
+	x, y := newCache(), newCache()
+
+	for i := 0; i < 10; i++ {
+		x.StoreWithTTL(i, i, time.Minute)
+		y.StoreWithTTL(i, i, time.Minute)
+
+		xexp, _ := x.Expiry(i)
+		yexp, _ := y.Expiry(i)
+
+		assert.WithinDuration(t, xexp, yexp, time.Millisecond)
+		assert.WithinDuration(t, time.Now().Add(time.Minute), xexp, time.Second+time.Millisecond)
+	}
+}
+
+func TestCacheSetCapacity(t *testing.T) {
+	ch := make(chan internal.Event, 10)
+	cache := internal.Unwrap(lru.New(10))
+	cache.Notify(ch, internal.Remove)
+
+	for i := 0; i < 10; i++ {
+		cache.Store(i, i)
+	}
+
+	assert.Equal(t, 6, cache.SetCapacity(4))
+	assert.Equal(t, 4, cache.Len())
+	assert.Len(t, ch, 6)
+
+	for i := 0; i < 6; i++ {
+		assert.Equal(t, i, (<-ch).Key)
+	}
+
+	// Zero capacity means unbounded.
+	assert.Equal(t, 0, cache.SetCapacity(0))
+	assert.Equal(t, 4, cache.Len())
+	assert.Len(t, ch, 0)
+
+	cache.Store(10, 10)
+	assert.Equal(t, 5, cache.Len())
+}
+
+func TestCacheResizeCostOversizedFirst(t *testing.T) {
+	cache := internal.Unwrap(lru.New(0))
+	cache.SetWeigher(func(key, value interface{}) int64 {
+		return int64(len(value.(string)))
+	})
+
+	cache.Store(1, "a")
+	cache.Store(2, "bbbbb")
+	cache.Store(3, "c")
+
+	// The oversized entry never fits, so discarded before the older entries.
+	assert.Equal(t, int64(5), cache.ResizeCost(3))
+	assert.ElementsMatch(t, []interface{}{1, 3}, cache.Keys())
+	assert.Equal(t, int64(2), cache.Cost())
+}
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (f *fakeClock) Now() time.Time {
+	return f.now
+}
+
+func TestCacheExpiringHeapStress(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	cache := internal.Unwrap(lru.New(0))
+	cache.SetClock(clock)
+
+	r := rand.New(rand.NewSource(1))
+	want := make(map[interface{}]time.Time)
+
+	live := func() []interface{} {
+		keys := []interface{}{}
+		for k, exp := range want {
+			if exp.IsZero() || clock.now.Before(exp) {
+				keys = append(keys, k)
+			}
+		}
+		return keys
+	}
+
+	for i := 0; i < 10000; i++ {
+		k := r.Intn(500)
+
+		switch r.Intn(4) {
+		case 0:
+			cache.Delete(k)
+			delete(want, k)
+		case 1:
+			cache.Store(k, i)
+			want[k] = time.Time{}
+		default:
+			ttl := time.Duration(r.Intn(1000)+1) * time.Millisecond
+			cache.StoreWithTTL(k, i, ttl)
+			want[k] = clock.now.Add(ttl)
+		}
+
+		if i%100 == 0 {
+			clock.now = clock.now.Add(time.Duration(r.Intn(50)) * time.Millisecond)
+			cache.GC()
+			assert.ElementsMatch(t, live(), cache.Keys())
+		}
+	}
+
+	for i := 0; i < 100; i++ {
+		clock.now = clock.now.Add(10 * time.Millisecond)
+		cache.GC()
+		assert.ElementsMatch(t, live(), cache.Keys())
+	}
+}
+
+func BenchmarkCacheBloomFilterMiss(b *testing.B) {
+	run := func(b *testing.B, cache *internal.Cache) {
+		for i := 0; i < 10000; i++ {
+			cache.StoreWithTTL(i, i, time.Minute)
+		}
+
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			_, _ = cache.Load(10000 + i)
+		}
+	}
+
+	b.Run("Disabled", func(b *testing.B) {
+		run(b, internal.Unwrap(lru.New(0)))
+	})
+
+	b.Run("Enabled", func(b *testing.B) {
+		cache := internal.Unwrap(lru.New(0))
+		cache.SetBloomFilter(10000, 0.01)
+		run(b, cache)
+	})
+}
+
+func BenchmarkCacheChurn(b *testing.B) {
+	cache := internal.Unwrap(lru.New(100))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cache.StoreWithTTL(i%200, i, time.Minute)
+		cache.Delete((i + 50) % 200)
+	}
+}
@@ -0,0 +1,75 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// entryRecord is the serialized form of EntryInfo.
+type entryRecord struct {
+	Key   interface{}
+	Value interface{}
+	// Expiry is the entry absolute expiry time, zero for entries never expires.
+	Expiry time.Time
+}
+
+func (i EntryInfo) record() entryRecord {
+	return entryRecord{Key: i.Key, Value: i.Value, Expiry: i.Expiry}
+}
+
+func (i *EntryInfo) setRecord(r entryRecord) {
+	*i = EntryInfo{Key: r.Key, Value: r.Value, Expiry: r.Expiry}
+}
+
+// MarshalBinary returns the gob encoding of the entry key, value and absolute expiry.
+//
+// Keys and values are encoded as interfaces, therefore the caller must
+// register their concrete types using gob.Register.
+func (i EntryInfo) MarshalBinary() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	err := gob.NewEncoder(buf).Encode(i.record())
+	return buf.Bytes(), err
+}
+
+// UnmarshalBinary parses the gob encoded entry key, value and absolute expiry,
+// the other entry metadata zeroed.
+func (i *EntryInfo) UnmarshalBinary(data []byte) error {
+	r := entryRecord{}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&r); err != nil {
+		return err
+	}
+
+	i.setRecord(r)
+	return nil
+}
+
+// MarshalJSON returns the json encoding of the entry key, value and absolute expiry.
+func (i EntryInfo) MarshalJSON() ([]byte, error) {
+	return json.Marshal(i.record())
+}
+
+// UnmarshalJSON parses the json encoded entry key, value and absolute expiry,
+// the other entry metadata zeroed.
+//
+// Keys and values are decoded as the encoding/json generic types,
+// so numbers decoded as float64, and objects and arrays decoded as
+// map[string]interface{} and []interface{}. Hence, keys must be strings,
+// numbers or booleans to round trip, and UnmarshalJSON returns an error
+// for objects and arrays keys as they are not comparable.
+func (i *EntryInfo) UnmarshalJSON(data []byte) error {
+	r := entryRecord{}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return err
+	}
+
+	switch r.Key.(type) {
+	case map[string]interface{}, []interface{}:
+		return fmt.Errorf("libcache: json entry key %v is not comparable", r.Key)
+	}
+
+	i.setRecord(r)
+	return nil
+}
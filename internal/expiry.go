@@ -0,0 +1,76 @@
+package internal
+
+import (
+	"sort"
+	"time"
+)
+
+// KeyExpiry pairs a cache key with its expiry, zero expiry means never expires.
+type KeyExpiry struct {
+	Key    interface{}
+	Expiry time.Time
+}
+
+// SortByExpiry sorts the pairs by ascending expiry and returns their keys,
+// the keys that never expire sorted last in their given order.
+func SortByExpiry(pairs []KeyExpiry) []interface{} {
+	sort.SliceStable(pairs, func(i, j int) bool {
+		return expiresBefore(pairs[i].Expiry, pairs[j].Expiry)
+	})
+
+	keys := make([]interface{}, len(pairs))
+	for i, p := range pairs {
+		keys[i] = p.Key
+	}
+	return keys
+}
+
+// expiresBefore reports whether expiry a is before expiry b,
+// zero expiry means never expires.
+func expiresBefore(a, b time.Time) bool {
+	return !a.IsZero() && (b.IsZero() || a.Before(b))
+}
+
+// KeysByExpiry returns the keys ordered by ascending expiry, followed by the keys that never expire.
+// KeysByExpiry scans all the cache entries, therefore it runs in O(n log n).
+func (c *Cache) KeysByExpiry() []interface{} {
+	return SortByExpiry(c.ExpiryPairs())
+}
+
+// ExpiringWithin returns the keys expiring within d from now, ordered by ascending expiry.
+// ExpiringWithin scans all the cache entries, therefore it runs in O(n log n).
+func (c *Cache) ExpiringWithin(d time.Duration) []interface{} {
+	return SortByExpiry(c.ExpiryPairsWithin(d))
+}
+
+// ExpiryPairs returns all the keys paired with their expiry, in unspecified order,
+// so callers composing several caches sort them once, without looking up each key.
+func (c *Cache) ExpiryPairs() []KeyExpiry {
+	// Run GC inline before scan the entries.
+	c.gc()
+	return c.expiryPairs(time.Time{})
+}
+
+// ExpiryPairsWithin returns the keys expiring within d from now paired with their expiry,
+// in unspecified order.
+func (c *Cache) ExpiryPairsWithin(d time.Duration) []KeyExpiry {
+	// Run GC inline before scan the entries.
+	c.gc()
+	return c.expiryPairs(c.clock.Now().Add(d))
+}
+
+// expiryPairs returns the keys expiring by the given time paired with their expiry,
+// zero time means all the keys.
+func (c *Cache) expiryPairs(by time.Time) []KeyExpiry {
+	pairs := []KeyExpiry{}
+	c.each(func(e *Entry) {
+		if IsNegative(e.Value) {
+			return
+		}
+
+		if by.IsZero() || (!e.Exp.IsZero() && !e.Exp.After(by)) {
+			pairs = append(pairs, KeyExpiry{Key: e.Key, Expiry: e.Exp})
+		}
+	})
+	return pairs
+}
@@ -0,0 +1,133 @@
+package internal
+
+import (
+	"hash/fnv"
+	"math"
+	"reflect"
+)
+
+// Hash returns a 64-bit hash of the key, used by the probabilistic data structures
+// such as sketches and filters, and to route keys across sets.
+//
+// Hash agrees with the Go map equality, so equal keys always hash alike:
+// strings, byte slices, integers, floats and booleans hashed by their value,
+// with the negative zero hashed as zero, pointers, channels and other references
+// hashed by their identity, and structs and arrays hashed field by field.
+func Hash(key interface{}) uint64 {
+	switch k := key.(type) {
+	case string:
+		return hashString(k)
+	case []byte:
+		h := fnv.New64a()
+		_, _ = h.Write(k)
+		return h.Sum64()
+	case int:
+		return mix(uint64(k))
+	case int8:
+		return mix(uint64(k))
+	case int16:
+		return mix(uint64(k))
+	case int32:
+		return mix(uint64(k))
+	case int64:
+		return mix(uint64(k))
+	case uint:
+		return mix(uint64(k))
+	case uint8:
+		return mix(uint64(k))
+	case uint16:
+		return mix(uint64(k))
+	case uint32:
+		return mix(uint64(k))
+	case uint64:
+		return mix(k)
+	case uintptr:
+		return mix(uint64(k))
+	case float32:
+		return hashFloat(float64(k))
+	case float64:
+		return hashFloat(k)
+	case bool:
+		if k {
+			return mix(1)
+		}
+		return mix(0)
+	case nil:
+		return mix(0)
+	default:
+		return hashValue(reflect.ValueOf(key))
+	}
+}
+
+// hashValue hashes the value like Hash, walking its kind.
+func hashValue(v reflect.Value) uint64 {
+	switch v.Kind() {
+	case reflect.String:
+		return hashString(v.String())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return mix(uint64(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return mix(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return hashFloat(v.Float())
+	case reflect.Complex64, reflect.Complex128:
+		c := v.Complex()
+		return combine(hashFloat(real(c)), hashFloat(imag(c)))
+	case reflect.Bool:
+		if v.Bool() {
+			return mix(1)
+		}
+		return mix(0)
+	case reflect.Ptr, reflect.Chan, reflect.UnsafePointer, reflect.Func, reflect.Map, reflect.Slice:
+		// Hashed by identity, as map equality compares the references, not their target.
+		return mix(uint64(v.Pointer()))
+	case reflect.Interface:
+		if v.IsNil() {
+			return mix(0)
+		}
+		return hashValue(v.Elem())
+	case reflect.Struct:
+		h := mix(uint64(v.NumField()))
+		for i := 0; i < v.NumField(); i++ {
+			h = combine(h, hashValue(v.Field(i)))
+		}
+		return h
+	case reflect.Array:
+		h := mix(uint64(v.Len()))
+		for i := 0; i < v.Len(); i++ {
+			h = combine(h, hashValue(v.Index(i)))
+		}
+		return h
+	default:
+		return mix(0)
+	}
+}
+
+// hashFloat hashes f by its bits, the negative zero hashed as zero, as they are equal.
+func hashFloat(f float64) uint64 {
+	if f == 0 {
+		f = 0
+	}
+	return mix(math.Float64bits(f))
+}
+
+// combine mixes the hash x into the hash h.
+func combine(h, x uint64) uint64 {
+	return mix(h ^ (x + 0x9e3779b97f4a7c15 + h<<6 + h>>2))
+}
+
+func hashString(s string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(s))
+	return h.Sum64()
+}
+
+// mix is the splitmix64 finalizer, it spreads the bits of x.
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
@@ -0,0 +1,65 @@
+package internal
+
+import "time"
+
+type item struct {
+	key   interface{}
+	value interface{}
+	exp   time.Time
+}
+
+// Iterator iterates over a snapshot of cache entries,
+// so it stays valid regardless of the cache mutations after its creation.
+// Entries expired by the time Next reach them are skipped.
+//
+// The zero value is an empty iterator.
+type Iterator struct {
+	items []item
+	clock Clock
+	cur   item
+	next  *Iterator
+}
+
+// Next advances the iterator to the next entry,
+// it reports whether there is an entry.
+func (it *Iterator) Next() bool {
+	for {
+		if len(it.items) == 0 {
+			if it.next == nil {
+				return false
+			}
+			*it = *it.next
+			continue
+		}
+
+		it.cur, it.items = it.items[0], it.items[1:]
+		if it.cur.exp.IsZero() || it.clock.Now().Before(it.cur.exp) {
+			return true
+		}
+	}
+}
+
+// Key returns the current entry key.
+func (it *Iterator) Key() interface{} {
+	return it.cur.key
+}
+
+// Value returns the current entry value.
+func (it *Iterator) Value() interface{} {
+	return it.cur.value
+}
+
+// Expiry returns the current entry absolute expiry time, zero for entries never expires.
+func (it *Iterator) Expiry() time.Time {
+	return it.cur.exp
+}
+
+// Chain returns an iterator that iterates over it entries, followed by next entries.
+func Chain(it, next *Iterator) *Iterator {
+	last := it
+	for last.next != nil {
+		last = last.next
+	}
+	last.next = next
+	return it
+}
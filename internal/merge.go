@@ -0,0 +1,45 @@
+package internal
+
+import "time"
+
+// EntrySource is the subset of the cache methods used to read the entries of a cache merged into another.
+type EntrySource interface {
+	Keys() []interface{}
+	GetEntry(key interface{}) (EntryInfo, bool)
+}
+
+// mergeTarget is the subset of the cache methods used to merge entries into a cache.
+type mergeTarget interface {
+	Peek(key interface{}) (interface{}, bool)
+	StoreWithDeadline(key, value interface{}, deadline time.Time)
+}
+
+// LiveEntries returns a snapshot of src live entries, negative entries excluded.
+func LiveEntries(src EntrySource) []EntryInfo {
+	keys := src.Keys()
+	entries := make([]EntryInfo, 0, len(keys))
+
+	for _, k := range keys {
+		if e, ok := src.GetEntry(k); ok && !IsNegative(e.Value) {
+			entries = append(entries, e)
+		}
+	}
+
+	return entries
+}
+
+// MergeEntries stores the entries into dst keeping their absolute expiry,
+// and resolves the value of the keys already in dst using onConflict,
+// nil onConflict means the incoming value wins.
+func MergeEntries(dst mergeTarget, entries []EntryInfo, onConflict func(existing, incoming interface{}) interface{}) {
+	for _, e := range entries {
+		v := e.Value
+		if onConflict != nil {
+			if existing, ok := dst.Peek(e.Key); ok {
+				v = onConflict(existing, v)
+			}
+		}
+
+		dst.StoreWithDeadline(e.Key, v, e.Expiry)
+	}
+}
@@ -0,0 +1,135 @@
+package internal
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// evictionWindow is the number of one second buckets,
+// counting the recent evictions.
+const evictionWindow = 60
+
+// Metrics is a point in time snapshot of the cache counters and size.
+type Metrics struct {
+	// Hits is the number of lookups that found the key.
+	Hits uint64
+	// Misses is the number of lookups that did not find the key.
+	Misses uint64
+	// Evictions is the number of entries discarded by the replacement policy,
+	// to make room for new entries or to fit the capacity or cost ceiling.
+	Evictions uint64
+	// Expirations is the number of entries removed since their expiry elapsed.
+	Expirations uint64
+	// RecentEvictions is the number of evictions in the last minute,
+	// a rolling rate of the cache pressure.
+	RecentEvictions uint64
+	// Len is the number of cache entries.
+	Len int
+	// Cap is the cache capacity, zero means unlimited.
+	Cap int
+	// Cost is the total cost of the cache entries.
+	Cost int64
+	// LastGCEvicted is the number of expired entries swept by the last GC call.
+	LastGCEvicted uint64
+	// LastGCDuration is the time the last GC call took to sweep the expired entries.
+	LastGCDuration time.Duration
+}
+
+// Counters counts the cache operations, it safe to read while it updated.
+type Counters struct {
+	hits        uint64
+	misses      uint64
+	evictions   uint64
+	expirations uint64
+	// gcEvicted and gcDuration hold the last GC sweep.
+	gcEvicted  uint64
+	gcDuration int64
+
+	mu     sync.Mutex
+	recent [evictionWindow]bucket
+}
+
+// bucket counts the evictions within a one second.
+type bucket struct {
+	sec int64
+	n   uint64
+}
+
+// Lookup counts a cache lookup as hit or miss.
+func (c *Counters) Lookup(hit bool) {
+	if hit {
+		atomic.AddUint64(&c.hits, 1)
+		return
+	}
+	atomic.AddUint64(&c.misses, 1)
+}
+
+// Evict counts an eviction occurred at now.
+func (c *Counters) Evict(now time.Time) {
+	atomic.AddUint64(&c.evictions, 1)
+
+	sec := now.Unix()
+	c.mu.Lock()
+	b := &c.recent[uint64(sec)%evictionWindow]
+	if b.sec != sec {
+		b.sec, b.n = sec, 0
+	}
+	b.n++
+	c.mu.Unlock()
+}
+
+// RecentEvictions returns the number of evictions in the minute before now.
+func (c *Counters) RecentEvictions(now time.Time) (n uint64) {
+	sec := now.Unix()
+	c.mu.Lock()
+	for _, b := range c.recent {
+		if b.sec <= sec && sec-b.sec < evictionWindow {
+			n += b.n
+		}
+	}
+	c.mu.Unlock()
+	return
+}
+
+// Expire counts an expiration.
+func (c *Counters) Expire() {
+	atomic.AddUint64(&c.expirations, 1)
+}
+
+// Expirations returns the number of expirations.
+func (c *Counters) Expirations() uint64 {
+	return atomic.LoadUint64(&c.expirations)
+}
+
+// Sweep records a GC sweep expired n entries in d.
+func (c *Counters) Sweep(n uint64, d time.Duration) {
+	atomic.StoreUint64(&c.gcEvicted, n)
+	atomic.StoreInt64(&c.gcDuration, int64(d))
+}
+
+// Metrics returns a snapshot of the counters, without the cache size.
+func (c *Counters) Metrics() Metrics {
+	return Metrics{
+		Hits:           atomic.LoadUint64(&c.hits),
+		Misses:         atomic.LoadUint64(&c.misses),
+		Evictions:      atomic.LoadUint64(&c.evictions),
+		Expirations:    atomic.LoadUint64(&c.expirations),
+		LastGCEvicted:  atomic.LoadUint64(&c.gcEvicted),
+		LastGCDuration: time.Duration(atomic.LoadInt64(&c.gcDuration)),
+	}
+}
+
+// Reset zeroes the counters.
+func (c *Counters) Reset() {
+	atomic.StoreUint64(&c.hits, 0)
+	atomic.StoreUint64(&c.misses, 0)
+	atomic.StoreUint64(&c.evictions, 0)
+	atomic.StoreUint64(&c.expirations, 0)
+	atomic.StoreUint64(&c.gcEvicted, 0)
+	atomic.StoreInt64(&c.gcDuration, 0)
+
+	c.mu.Lock()
+	c.recent = [evictionWindow]bucket{}
+	c.mu.Unlock()
+}
@@ -0,0 +1,74 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrNotFound is returned by GetOrCompute,
+// when the key known to be absent from the underlying data source.
+var ErrNotFound = errors.New("libcache: key not found")
+
+// Negative is the value of a negative entry,
+// that marks a key as known to be absent from the underlying data source.
+type Negative struct{}
+
+// IsNegative reports whether v is a negative entry value.
+func IsNegative(v interface{}) bool {
+	_, ok := v.(Negative)
+	return ok
+}
+
+type notFound struct {
+	ttl time.Duration
+}
+
+func (notFound) Error() string { return ErrNotFound.Error() }
+
+func (notFound) Is(target error) bool { return target == ErrNotFound }
+
+// NotFound returns an error for a loader to report the key absent,
+// the key then cached as negative entry for ttl.
+func NotFound(ttl time.Duration) error {
+	return notFound{ttl: ttl}
+}
+
+// storer is the subset of cache methods used by StoreLoaded.
+type storer interface {
+	Store(key, value interface{})
+	StoreWithTTL(key, value interface{}, ttl time.Duration)
+}
+
+// StoreLoaded stores the key value returned by a loader in s,
+// or a negative entry if the loader returned NotFound with a positive ttl.
+// It returns the GetOrCompute result.
+func StoreLoaded(s storer, key, v interface{}, err error) (interface{}, error) {
+	var nf notFound
+	if errors.As(err, &nf) {
+		if nf.ttl > 0 {
+			s.StoreWithTTL(key, Negative{}, nf.ttl)
+		}
+		return nil, ErrNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	s.Store(key, v)
+	return v, nil
+}
+
+// ContextLoader adapts the context aware loader fn to a Loader,
+// that returns ctx error instead of the loaded value if ctx done by the time fn returned,
+// so a partial result never cached.
+func ContextLoader(ctx context.Context, fn func(context.Context) (interface{}, error)) Loader {
+	return func(interface{}) (interface{}, error) {
+		v, err := fn(ctx)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return v, err
+	}
+}
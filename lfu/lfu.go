@@ -2,70 +2,185 @@
 package lfu
 
 import (
-	"container/heap"
+	"container/list"
 
 	"github.com/shaj13/libcache"
 	"github.com/shaj13/libcache/internal"
 )
 
 func init() {
-	libcache.LFU.Register(New)
+	libcache.LFU.Register(func(cap int) libcache.Cache {
+		return New(cap)
+	})
+}
+
+// Option configures the LFU cache using the functional options paradigm.
+type Option func(*collection)
+
+// DecayEvery halves every entry access frequency after each n accesses,
+// so formerly hot entries that went cold eventually become evictable.
+// Entries ending up with equal frequency keep their relative order,
+// the more frequent ones before the decay considered more recently used.
+func DecayEvery(n int) Option {
+	return func(f *collection) {
+		f.decayEvery = n
+	}
 }
 
 // New returns a new non-thread safe cache.
-func New(cap int) libcache.Cache {
-	f := &collection{}
+func New(cap int, opts ...Option) libcache.Cache {
+	f := &collection{freqs: list.New()}
+	for _, opt := range opts {
+		opt(f)
+	}
 	f.Init()
-	return internal.New(f, cap)
+	return cache{internal.New(f, cap)}
 }
 
-type element struct {
-	value *internal.Entry
-	index int
-	count int
+// cache is the internal cache, that reports the LFU replacement policy.
+type cache struct {
+	*internal.Cache
 }
 
-type collection []*element
-
-func (f collection) Len() int {
-	return len(f)
+// Policy returns libcache.LFU.
+func (cache) Policy() libcache.ReplacementPolicy {
+	return libcache.LFU
 }
 
-func (f collection) Less(i, j int) bool {
-	return f[i].count < f[j].count
+// bucket holds the elements accessed the same number of times,
+// ordered from the most to the least recently used.
+type bucket struct {
+	count int
+	ll    *list.List
 }
 
-func (f collection) Swap(i, j int) {
-	f[i], f[j] = f[j], f[i]
-	f[i].index = i
-	f[j].index = j
+type element struct {
+	value *internal.Entry
+	count int
+	// node is the element bucket node in the frequencies list.
+	node *list.Element
+	// le is the element node in its bucket list,
+	// nil once the element removed from the collection.
+	le *list.Element
 }
 
-func (f *collection) Push(v interface{}) {
-	e := v.(*element)
-	e.index = f.Len()
-	*f = append(*f, e)
+// collection is an O(1) LFU, it holds a list of buckets ordered by their
+// access count, the front bucket has the minimum frequency.
+// entries with equal frequency discarded in least recently used order.
+type collection struct {
+	freqs *list.List
+	len   int
+	// decayEvery is the number of accesses between each decay, zero disables decay.
+	decayEvery int
+	accesses   int
 }
 
-func (f *collection) Pop() interface{} {
-	e := (*f)[f.Len()-1]
-	*f = (*f)[:f.Len()-1]
-	return e
+func (f *collection) Len() int {
+	return f.len
 }
 
 func (f *collection) Discard() (e *internal.Entry) {
-	return heap.Pop(f).(*element).value
+	node := f.freqs.Front()
+	if node == nil {
+		return nil
+	}
+
+	ele := node.Value.(*bucket).ll.Back().Value.(*element)
+	f.remove(ele)
+	return ele.value
 }
 
 func (f *collection) Move(e *internal.Entry) {
 	ele := e.Element.(*element)
+	if ele.le == nil {
+		return
+	}
+
+	node := ele.node
+	next := node.Next()
+	if next == nil || next.Value.(*bucket).count != ele.count+1 {
+		next = f.freqs.InsertAfter(&bucket{count: ele.count + 1, ll: list.New()}, node)
+	}
+
+	f.remove(ele)
 	ele.count++
-	heap.Fix(f, ele.index)
+	f.push(ele, next)
+
+	if f.decayEvery > 0 {
+		f.accesses++
+		if f.accesses >= f.decayEvery {
+			f.accesses = 0
+			f.decay()
+		}
+	}
+}
+
+// decay halves the frequency of all elements,
+// merging the buckets that end up with the same frequency.
+func (f *collection) decay() {
+	var prev *list.Element
+	for node := f.freqs.Front(); node != nil; {
+		next := node.Next()
+		b := node.Value.(*bucket)
+		b.count /= 2
+
+		for le := b.ll.Front(); le != nil; le = le.Next() {
+			le.Value.(*element).count = b.count
+		}
+
+		if prev == nil || prev.Value.(*bucket).count != b.count {
+			prev = node
+			node = next
+			continue
+		}
+
+		pb := prev.Value.(*bucket)
+		for le := b.ll.Back(); le != nil; le = b.ll.Back() {
+			ele := b.ll.Remove(le).(*element)
+			ele.node = prev
+			ele.le = pb.ll.PushFront(ele)
+		}
+
+		f.freqs.Remove(node)
+		node = next
+	}
+}
+
+func (f *collection) Front() *internal.Entry {
+	if node := f.freqs.Front(); node != nil {
+		return node.Value.(*bucket).ll.Back().Value.(*element).value
+	}
+	return nil
+}
+
+func (f *collection) Back() *internal.Entry {
+	if node := f.freqs.Back(); node != nil {
+		return node.Value.(*bucket).ll.Front().Value.(*element).value
+	}
+	return nil
+}
+
+// Walk walks the entries from the least to the most frequently used,
+// the least recently used first within the same frequency.
+func (f *collection) Walk(fn func(*internal.Entry)) {
+	for node := f.freqs.Front(); node != nil; node = node.Next() {
+		for le := node.Value.(*bucket).ll.Back(); le != nil; le = le.Prev() {
+			fn(le.Value.(*element).value)
+		}
+	}
+}
+
+// Frequency returns the number of times the entry accessed.
+func (f *collection) Frequency(e *internal.Entry) int {
+	if ele, ok := e.Element.(*element); ok {
+		return ele.count
+	}
+	return 0
 }
 
 func (f *collection) Remove(e *internal.Entry) {
-	if e.Element.(*element).index < f.Len() {
-		heap.Remove(f, e.Element.(*element).index)
+	if ele := e.Element.(*element); ele.le != nil {
+		f.remove(ele)
 	}
 }
 
@@ -73,10 +188,37 @@ func (f *collection) Add(e *internal.Entry) {
 	ele := new(element)
 	ele.value = e
 	e.Element = ele
-	heap.Push(f, ele)
+
+	node := f.freqs.Front()
+	if node == nil || node.Value.(*bucket).count != 0 {
+		node = f.freqs.PushFront(&bucket{ll: list.New()})
+	}
+
+	f.push(ele, node)
 }
 
 func (f *collection) Init() {
-	*f = collection{}
-	heap.Init(f)
+	f.freqs.Init()
+	f.len = 0
+	f.accesses = 0
+}
+
+// push adds the element to the front of the given bucket node.
+func (f *collection) push(ele *element, node *list.Element) {
+	ele.node = node
+	ele.le = node.Value.(*bucket).ll.PushFront(ele)
+	f.len++
+}
+
+// remove removes the element from its bucket,
+// and drops the bucket once it becomes empty.
+func (f *collection) remove(ele *element) {
+	b := ele.node.Value.(*bucket)
+	b.ll.Remove(ele.le)
+	ele.le = nil
+	f.len--
+
+	if b.ll.Len() == 0 {
+		f.freqs.Remove(ele.node)
+	}
 }
@@ -1,6 +1,7 @@
 package lfu
 
 import (
+	"container/list"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -14,7 +15,7 @@ func TestCollection(t *testing.T) {
 	entries = append(entries, &internal.Entry{Key: 2})
 	entries = append(entries, &internal.Entry{Key: 3})
 
-	f := &collection{}
+	f := &collection{freqs: list.New()}
 	f.Init()
 
 	for _, e := range entries {
@@ -32,5 +33,84 @@ func TestCollection(t *testing.T) {
 
 	assert.Equal(t, oldest.Key, 1)
 	assert.Equal(t, f.Len(), 1)
-	assert.Equal(t, (*f)[0].value.Key, 2)
+	assert.Equal(t, f.Discard().Key, 2)
+}
+
+func TestCollectionTieBreak(t *testing.T) {
+	entries := []*internal.Entry{}
+	for i := 0; i < 4; i++ {
+		entries = append(entries, &internal.Entry{Key: i})
+	}
+
+	f := &collection{freqs: list.New()}
+	f.Init()
+
+	for _, e := range entries {
+		f.Add(e)
+	}
+
+	// 0 and 2 accessed once, 2 most recently.
+	f.Move(entries[0])
+	f.Move(entries[2])
+	f.Move(entries[3])
+	f.Move(entries[3])
+
+	assert.Equal(t, 1, f.Discard().Key)
+	assert.Equal(t, 0, f.Discard().Key)
+	assert.Equal(t, 2, f.Discard().Key)
+	assert.Equal(t, 3, f.Discard().Key)
+	assert.Nil(t, f.Discard())
+	assert.Equal(t, 0, f.Len())
+}
+
+func TestCollectionWalk(t *testing.T) {
+	f := &collection{freqs: list.New()}
+	f.Init()
+
+	for i := 1; i <= 3; i++ {
+		e := &internal.Entry{Key: i}
+		f.Add(e)
+		for j := 0; j < 4-i; j++ {
+			f.Move(e)
+		}
+	}
+	f.Add(&internal.Entry{Key: 4})
+
+	keys := []interface{}{}
+	f.Walk(func(e *internal.Entry) {
+		keys = append(keys, e.Key)
+	})
+
+	assert.Equal(t, []interface{}{4, 3, 2, 1}, keys)
+	assert.Equal(t, f.Front().Key, keys[0])
+	assert.Equal(t, f.Back().Key, keys[3])
+}
+
+func TestDecayEvery(t *testing.T) {
+	table := []struct {
+		opts    []Option
+		evicted int
+	}{
+		{evicted: 2},
+		{opts: []Option{DecayEvery(2)}, evicted: 1},
+	}
+
+	for _, tt := range table {
+		cache := New(2, tt.opts...)
+
+		cache.Store(1, 1)
+		for i := 0; i < 10; i++ {
+			cache.Load(1)
+		}
+
+		cache.Store(2, 2)
+		for i := 0; i < 6; i++ {
+			cache.Load(2)
+		}
+
+		cache.Store(3, 3)
+
+		assert.False(t, cache.Contains(tt.evicted))
+		assert.Equal(t, 2, cache.Len())
+	}
 }
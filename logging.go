@@ -0,0 +1,291 @@
+package libcache
+
+import (
+	"context"
+	"time"
+
+	"github.com/shaj13/libcache/internal"
+)
+
+// Logging returns a cache that delegates to c, and calls logger after each
+// read, write or delete with its event op, key, and whether it hit.
+// Operations on several keys call logger once per key.
+//
+// Lookups logged as Read and hit when the key found, writes logged as Write
+// and hit when the value written, and deletes logged as Remove and hit when the key deleted.
+// Writes and deletes that do not report their outcome always hit.
+// LoadMany loads the keys one at a time to tell their hits apart.
+// Merge logs the other cache live entries keys, and DeleteWithPrefix, DeleteFunc and InvalidateTag
+// log the keys gone after the delete, as they do not report the deleted keys.
+//
+// Enumerations, e.g. Keys and Snapshot, whole cache operations, e.g. Purge, Reset and RefreshAllTTL,
+// and Pin and Unpin delegated as is without logging.
+//
+// Logging does not alter c semantics or the underlying "recent-ness",
+// therefore it works with any replacement policy.
+func Logging(c Cache, logger func(op Op, key interface{}, hit bool)) Cache {
+	return &logging{Cache: c, logger: logger}
+}
+
+type logging struct {
+	Cache
+	logger func(op Op, key interface{}, hit bool)
+}
+
+func (l *logging) Load(key interface{}) (interface{}, bool) {
+	v, ok := l.Cache.Load(key)
+	l.logger(Read, key, ok)
+	return v, ok
+}
+
+func (l *logging) LoadWithExpiry(key interface{}) (interface{}, time.Time, bool) {
+	v, exp, ok := l.Cache.LoadWithExpiry(key)
+	l.logger(Read, key, ok)
+	return v, exp, ok
+}
+
+func (l *logging) LoadMany(keys ...interface{}) map[interface{}]interface{} {
+	items := make(map[interface{}]interface{})
+	for _, k := range keys {
+		found := l.Cache.LoadMany(k)
+		for id, v := range found {
+			items[id] = v
+		}
+		l.logger(Read, k, len(found) > 0)
+	}
+	return items
+}
+
+func (l *logging) Peek(key interface{}) (interface{}, bool) {
+	v, ok := l.Cache.Peek(key)
+	l.logger(Read, key, ok)
+	return v, ok
+}
+
+func (l *logging) Test(key interface{}, pred func(value interface{}) bool) (bool, bool) {
+	ok, found := l.Cache.Test(key, pred)
+	l.logger(Read, key, found)
+	return ok, found
+}
+
+func (l *logging) GetEntry(key interface{}) (EntryInfo, bool) {
+	e, ok := l.Cache.GetEntry(key)
+	l.logger(Read, key, ok)
+	return e, ok
+}
+
+func (l *logging) Contains(key interface{}) bool {
+	ok := l.Cache.Contains(key)
+	l.logger(Read, key, ok)
+	return ok
+}
+
+func (l *logging) ContainsNoGC(key interface{}) bool {
+	ok := l.Cache.ContainsNoGC(key)
+	l.logger(Read, key, ok)
+	return ok
+}
+
+func (l *logging) ContainsMany(keys []interface{}) []bool {
+	flags := l.Cache.ContainsMany(keys)
+	for i, k := range keys {
+		l.logger(Read, k, flags[i])
+	}
+	return flags
+}
+
+func (l *logging) Expiry(key interface{}) (time.Time, bool) {
+	exp, ok := l.Cache.Expiry(key)
+	l.logger(Read, key, ok)
+	return exp, ok
+}
+
+func (l *logging) RemainingTTL(key interface{}) (time.Duration, bool) {
+	ttl, ok := l.Cache.RemainingTTL(key)
+	l.logger(Read, key, ok)
+	return ttl, ok
+}
+
+func (l *logging) GetOrCompute(key interface{}, loader Loader) (interface{}, error) {
+	v, err := l.Cache.GetOrCompute(key, loader)
+	l.logger(Read, key, err == nil)
+	return v, err
+}
+
+func (l *logging) LoadCtx(ctx context.Context, key interface{}, loader func(context.Context) (interface{}, error)) (interface{}, error) {
+	v, err := l.Cache.LoadCtx(ctx, key, loader)
+	l.logger(Read, key, err == nil)
+	return v, err
+}
+
+func (l *logging) Update(key interface{}, value interface{}) {
+	l.Cache.Update(key, value)
+	l.logger(Write, key, true)
+}
+
+func (l *logging) CompareAndSwap(key, old, new interface{}) bool {
+	swapped := l.Cache.CompareAndSwap(key, old, new)
+	l.logger(Write, key, swapped)
+	return swapped
+}
+
+func (l *logging) Increment(key interface{}, delta int64) (int64, error) {
+	n, err := l.Cache.Increment(key, delta)
+	l.logger(Write, key, err == nil)
+	return n, err
+}
+
+func (l *logging) Decrement(key interface{}, delta int64) (int64, error) {
+	n, err := l.Cache.Decrement(key, delta)
+	l.logger(Write, key, err == nil)
+	return n, err
+}
+
+func (l *logging) Store(key interface{}, value interface{}) {
+	l.Cache.Store(key, value)
+	l.logger(Write, key, true)
+}
+
+func (l *logging) StoreWithTTL(key interface{}, value interface{}, ttl time.Duration) {
+	l.Cache.StoreWithTTL(key, value, ttl)
+	l.logger(Write, key, true)
+}
+
+func (l *logging) StoreWithDeadline(key interface{}, value interface{}, deadline time.Time) {
+	l.Cache.StoreWithDeadline(key, value, deadline)
+	l.logger(Write, key, true)
+}
+
+func (l *logging) StoreEvicting(key, value interface{}) (interface{}, interface{}, bool) {
+	k, v, evicted := l.Cache.StoreEvicting(key, value)
+	l.logger(Write, key, true)
+	return k, v, evicted
+}
+
+func (l *logging) StoreWithTTLEvicting(key, value interface{}, ttl time.Duration) (interface{}, interface{}, bool) {
+	k, v, evicted := l.Cache.StoreWithTTLEvicting(key, value, ttl)
+	l.logger(Write, key, true)
+	return k, v, evicted
+}
+
+func (l *logging) StoreWithDeadlineEvicting(key, value interface{}, deadline time.Time) (interface{}, interface{}, bool) {
+	k, v, evicted := l.Cache.StoreWithDeadlineEvicting(key, value, deadline)
+	l.logger(Write, key, true)
+	return k, v, evicted
+}
+
+func (l *logging) StoreMany(items map[interface{}]interface{}) {
+	l.Cache.StoreMany(items)
+	l.logWrites(items)
+}
+
+func (l *logging) Warm(items map[interface{}]interface{}) {
+	l.Cache.Warm(items)
+	l.logWrites(items)
+}
+
+func (l *logging) WarmWithTTL(items map[interface{}]interface{}, ttl time.Duration) {
+	l.Cache.WarmWithTTL(items, ttl)
+	l.logWrites(items)
+}
+
+func (l *logging) logWrites(items map[interface{}]interface{}) {
+	for k := range items {
+		l.logger(Write, k, true)
+	}
+}
+
+func (l *logging) Merge(other EntrySource, onConflict func(existing, incoming interface{}) interface{}) {
+	entries := internal.LiveEntries(other)
+	l.Cache.Merge(other, onConflict)
+	for _, e := range entries {
+		l.logger(Write, e.Key, true)
+	}
+}
+
+func (l *logging) StoreNegative(key interface{}, ttl time.Duration) {
+	l.Cache.StoreNegative(key, ttl)
+	l.logger(Write, key, true)
+}
+
+func (l *logging) StoreWithTTLJitter(key interface{}, value interface{}, ttl, jitter time.Duration) {
+	l.Cache.StoreWithTTLJitter(key, value, ttl, jitter)
+	l.logger(Write, key, true)
+}
+
+func (l *logging) StoreWithTags(key, value interface{}, tags ...string) {
+	l.Cache.StoreWithTags(key, value, tags...)
+	l.logger(Write, key, true)
+}
+
+func (l *logging) Touch(key interface{}, ttl time.Duration) bool {
+	ok := l.Cache.Touch(key, ttl)
+	l.logger(Write, key, ok)
+	return ok
+}
+
+func (l *logging) SetExpiry(key interface{}, t time.Time) bool {
+	ok := l.Cache.SetExpiry(key, t)
+	l.logger(Write, key, ok)
+	return ok
+}
+
+func (l *logging) Delete(key interface{}) {
+	l.Cache.Delete(key)
+	l.logger(Remove, key, true)
+}
+
+func (l *logging) GetAndDelete(key interface{}) (interface{}, bool) {
+	v, ok := l.Cache.GetAndDelete(key)
+	l.logger(Remove, key, ok)
+	return v, ok
+}
+
+func (l *logging) CompareAndDelete(key, old interface{}) bool {
+	deleted := l.Cache.CompareAndDelete(key, old)
+	l.logger(Remove, key, deleted)
+	return deleted
+}
+
+func (l *logging) DeleteMany(keys ...interface{}) {
+	l.Cache.DeleteMany(keys...)
+	for _, k := range keys {
+		l.logger(Remove, k, true)
+	}
+}
+
+func (l *logging) DeleteWithPrefix(prefix string) int {
+	keys := l.Cache.KeysWithPrefix(prefix)
+	n := l.Cache.DeleteWithPrefix(prefix)
+	l.logRemoved(keys)
+	return n
+}
+
+func (l *logging) DeleteFunc(pred func(key, value interface{}) bool) int {
+	keys := []interface{}{}
+	n := l.Cache.DeleteFunc(func(key, value interface{}) bool {
+		if pred(key, value) {
+			keys = append(keys, key)
+			return true
+		}
+		return false
+	})
+	l.logRemoved(keys)
+	return n
+}
+
+func (l *logging) InvalidateTag(tag string) int {
+	keys := l.Cache.Keys()
+	n := l.Cache.InvalidateTag(tag)
+	l.logRemoved(keys)
+	return n
+}
+
+// logRemoved logs the given keys the cache no longer has as deleted.
+func (l *logging) logRemoved(keys []interface{}) {
+	for _, k := range keys {
+		if !l.Cache.ContainsNoGC(k) {
+			l.logger(Remove, k, true)
+		}
+	}
+}
@@ -15,7 +15,17 @@ func init() {
 // New returns a new non-thread safe cache.
 func New(cap int) libcache.Cache {
 	col := &collection{list.New()}
-	return internal.New(col, cap)
+	return cache{internal.New(col, cap)}
+}
+
+// cache is the internal cache, that reports the LRU replacement policy.
+type cache struct {
+	*internal.Cache
+}
+
+// Policy returns libcache.LRU.
+func (cache) Policy() libcache.ReplacementPolicy {
+	return libcache.LRU
 }
 
 type collection struct {
@@ -45,6 +55,21 @@ func (c *collection) Discard() (e *internal.Entry) {
 	return
 }
 
+func (c *collection) Front() *internal.Entry {
+	return entry(c.ll.Back())
+}
+
+func (c *collection) Back() *internal.Entry {
+	return entry(c.ll.Front())
+}
+
+// Walk walks the entries in discard order.
+func (c *collection) Walk(fn func(*internal.Entry)) {
+	for le := c.ll.Back(); le != nil; le = le.Prev() {
+		fn(le.Value.(*internal.Entry))
+	}
+}
+
 func (c *collection) Len() int {
 	return c.ll.Len()
 }
@@ -52,3 +77,10 @@ func (c *collection) Len() int {
 func (c *collection) Init() {
 	c.ll.Init()
 }
+
+func entry(le *list.Element) *internal.Entry {
+	if le == nil {
+		return nil
+	}
+	return le.Value.(*internal.Entry)
+}
@@ -0,0 +1,251 @@
+// Package lruk implements an LRU-K cache.
+package lruk
+
+import (
+	"container/heap"
+	"container/list"
+	"sort"
+
+	"github.com/shaj13/libcache"
+	"github.com/shaj13/libcache/internal"
+)
+
+func init() {
+	libcache.LRUK.Register(func(cap int) libcache.Cache {
+		return New(cap)
+	})
+}
+
+// Option configures the LRU-K cache using the functional options paradigm.
+type Option func(*collection)
+
+// K sets the number of references tracked per entry, Default 2.
+// K of one makes the cache behave as an LRU cache.
+func K(k int) Option {
+	return func(c *collection) {
+		c.k = k
+	}
+}
+
+// New returns a new non-thread safe cache.
+func New(cap int, opts ...Option) libcache.Cache {
+	c := &collection{k: 2}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.k < 1 {
+		c.k = 1
+	}
+
+	c.Init()
+	return cache{internal.New(c, cap)}
+}
+
+// cache is the internal cache, that reports the LRUK replacement policy.
+type cache struct {
+	*internal.Cache
+}
+
+// Policy returns libcache.LRUK.
+func (cache) Policy() libcache.ReplacementPolicy {
+	return libcache.LRUK
+}
+
+type element struct {
+	value *internal.Entry
+	// hist holds the element last k references time, oldest first.
+	hist []uint64
+	// le is the element node in the cold list,
+	// nil if the element is hot or removed from the collection.
+	le *list.Element
+	// index is the element index in the hot heap,
+	// -1 if the element is cold or removed from the collection.
+	index int
+}
+
+// kth returns the time of the element k-th most recent reference.
+func (e *element) kth() uint64 {
+	return e.hist[0]
+}
+
+// collection is an LRU-K, it tracks the last k references of each entry.
+// entries referenced less than k times are cold, and discarded first in FIFO order.
+// Otherwise, entries are hot, and discarded by their oldest k-th most recent reference,
+// so entries referenced once by a scan never evict the frequently referenced ones.
+type collection struct {
+	k int
+	// tick is a logical clock, advanced on each reference.
+	tick uint64
+	cold *list.List
+	hot  hotHeap
+}
+
+func (c *collection) Move(e *internal.Entry) {
+	ele := e.Element.(*element)
+	if ele.le == nil && ele.index < 0 {
+		return
+	}
+
+	c.tick++
+	if len(ele.hist) < c.k {
+		ele.hist = append(ele.hist, c.tick)
+	} else {
+		copy(ele.hist, ele.hist[1:])
+		ele.hist[c.k-1] = c.tick
+	}
+
+	if ele.le == nil {
+		heap.Fix(&c.hot, ele.index)
+		return
+	}
+
+	if len(ele.hist) == c.k {
+		c.cold.Remove(ele.le)
+		ele.le = nil
+		heap.Push(&c.hot, ele)
+	}
+}
+
+func (c *collection) Add(e *internal.Entry) {
+	c.tick++
+	ele := &element{value: e, index: -1}
+	ele.hist = make([]uint64, 1, c.k)
+	ele.hist[0] = c.tick
+	e.Element = ele
+
+	if c.k == 1 {
+		heap.Push(&c.hot, ele)
+		return
+	}
+
+	ele.le = c.cold.PushBack(ele)
+}
+
+func (c *collection) Remove(e *internal.Entry) {
+	c.remove(e.Element.(*element))
+}
+
+func (c *collection) remove(ele *element) {
+	if ele.le != nil {
+		c.cold.Remove(ele.le)
+		ele.le = nil
+		return
+	}
+
+	if ele.index >= 0 {
+		heap.Remove(&c.hot, ele.index)
+	}
+}
+
+func (c *collection) Discard() *internal.Entry {
+	ele := c.front()
+	if ele == nil {
+		return nil
+	}
+
+	c.remove(ele)
+	return ele.value
+}
+
+func (c *collection) front() *element {
+	if le := c.cold.Front(); le != nil {
+		return le.Value.(*element)
+	}
+
+	if len(c.hot) > 0 {
+		return c.hot[0]
+	}
+
+	return nil
+}
+
+func (c *collection) Front() *internal.Entry {
+	if ele := c.front(); ele != nil {
+		return ele.value
+	}
+	return nil
+}
+
+// Back returns the hot entry with the most recent k-th reference, in O(n),
+// or the last cold entry if no hot entries.
+func (c *collection) Back() *internal.Entry {
+	var last *element
+	for _, ele := range c.hot {
+		if last == nil || ele.kth() > last.kth() {
+			last = ele
+		}
+	}
+
+	if last == nil {
+		if le := c.cold.Back(); le != nil {
+			last = le.Value.(*element)
+		}
+	}
+
+	if last == nil {
+		return nil
+	}
+
+	return last.value
+}
+
+// Walk walks the cold entries in FIFO order,
+// then the hot entries by their k-th most recent reference, in O(n log n).
+func (c *collection) Walk(fn func(*internal.Entry)) {
+	for le := c.cold.Front(); le != nil; le = le.Next() {
+		fn(le.Value.(*element).value)
+	}
+
+	hot := make([]*element, len(c.hot))
+	copy(hot, c.hot)
+	sort.Slice(hot, func(i, j int) bool {
+		return hot[i].kth() < hot[j].kth()
+	})
+
+	for _, ele := range hot {
+		fn(ele.value)
+	}
+}
+
+func (c *collection) Len() int {
+	return c.cold.Len() + len(c.hot)
+}
+
+func (c *collection) Init() {
+	c.cold = list.New()
+	c.hot = nil
+}
+
+// hotHeap is a min-heap of hot elements ordered by their k-th most recent reference.
+type hotHeap []*element
+
+func (h hotHeap) Len() int {
+	return len(h)
+}
+
+func (h hotHeap) Less(i, j int) bool {
+	return h[i].kth() < h[j].kth()
+}
+
+func (h hotHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *hotHeap) Push(x interface{}) {
+	ele := x.(*element)
+	ele.index = len(*h)
+	*h = append(*h, ele)
+}
+
+func (h *hotHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	ele := old[n-1]
+	old[n-1] = nil
+	ele.index = -1
+	*h = old[:n-1]
+	return ele
+}
@@ -0,0 +1,82 @@
+package lruk
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/shaj13/libcache/internal"
+	"github.com/shaj13/libcache/lru"
+)
+
+func TestCollection(t *testing.T) {
+	entries := []*internal.Entry{}
+	for i := 0; i < 4; i++ {
+		entries = append(entries, &internal.Entry{Key: i})
+	}
+
+	c := &collection{k: 2}
+	c.Init()
+
+	for _, e := range entries {
+		c.Add(e)
+	}
+
+	// 2 referenced before 0, so its 2nd most recent reference is older.
+	c.Move(entries[2])
+	c.Move(entries[0])
+	c.Move(entries[0])
+
+	assert.Equal(t, 4, c.Len())
+	assert.Equal(t, 1, c.Front().Key)
+	assert.Equal(t, 0, c.Back().Key)
+
+	c.Remove(entries[3])
+
+	assert.Equal(t, 1, c.Discard().Key)
+	assert.Equal(t, 2, c.Discard().Key)
+	assert.Equal(t, 0, c.Discard().Key)
+	assert.Nil(t, c.Discard())
+}
+
+func TestK(t *testing.T) {
+	cache := New(2, K(1))
+	cache.Store(1, 1)
+	cache.Store(2, 2)
+	cache.Load(1)
+	cache.Store(3, 3)
+
+	// K of one behaves as LRU.
+	assert.True(t, cache.Contains(1))
+	assert.False(t, cache.Contains(2))
+}
+
+func TestScanResistance(t *testing.T) {
+	scan := func(cache interface {
+		Store(key, value interface{})
+		Load(key interface{}) (interface{}, bool)
+	}) (hits int) {
+		for i := 0; i < 3; i++ {
+			for k := 0; k < 3; k++ {
+				cache.Store(k, k)
+				cache.Load(k)
+			}
+		}
+
+		// a scan of keys referenced once.
+		for k := 100; k < 110; k++ {
+			cache.Store(k, k)
+		}
+
+		for k := 0; k < 3; k++ {
+			if _, ok := cache.Load(k); ok {
+				hits++
+			}
+		}
+
+		return hits
+	}
+
+	assert.Equal(t, 3, scan(New(5)))
+	assert.Equal(t, 0, scan(lru.New(5)))
+}
@@ -0,0 +1,43 @@
+package libcache
+
+// Memoize returns fn wrapped to cache its results in c by argument,
+// using c default TTL. Concurrent calls of the same argument
+// wait for a single fn call, and fn errors are not cached,
+// see Cache.GetOrCompute.
+//
+// The argument used as the cache key, therefore it must be comparable,
+// or normalized by the cache KeyFunc.
+func Memoize(c Cache, fn func(arg interface{}) (interface{}, error)) func(arg interface{}) (interface{}, error) {
+	return func(arg interface{}) (interface{}, error) {
+		return c.GetOrCompute(arg, fn)
+	}
+}
+
+// MemoizeN returns fn wrapped to cache its results in c by arguments like Memoize,
+// the arguments combined into a composite key, so they must be comparable.
+func MemoizeN(c Cache, fn func(args ...interface{}) (interface{}, error)) func(args ...interface{}) (interface{}, error) {
+	return func(args ...interface{}) (interface{}, error) {
+		return c.GetOrCompute(compositeKey(args), func(interface{}) (interface{}, error) {
+			return fn(args...)
+		})
+	}
+}
+
+// argsKey is a link of a composite key, holding an argument and the next link.
+type argsKey struct {
+	arg  interface{}
+	next interface{}
+}
+
+// argsEnd terminates a composite key.
+type argsEnd struct{}
+
+// compositeKey returns a comparable key of args,
+// that equals another composite key only if they have equal args in the same order.
+func compositeKey(args []interface{}) interface{} {
+	var key interface{} = argsEnd{}
+	for i := len(args) - 1; i >= 0; i-- {
+		key = argsKey{arg: args[i], next: key}
+	}
+	return key
+}
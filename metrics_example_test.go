@@ -0,0 +1,72 @@
+package libcache_test
+
+import (
+	"fmt"
+
+	"github.com/shaj13/libcache"
+	_ "github.com/shaj13/libcache/lru"
+)
+
+// sample stands in for prometheus.Metric, so the example runs without
+// importing the Prometheus client, which libcache never depends on.
+type sample struct {
+	name  string
+	value float64
+}
+
+// collector implements the prometheus.Collector Collect method shape,
+// with the prometheus package it reads:
+//
+//	func (c collector) Describe(ch chan<- *prometheus.Desc) {
+//		prometheus.DescribeByCollect(c, ch)
+//	}
+//
+//	func (c collector) Collect(ch chan<- prometheus.Metric) {
+//		m := c.cache.Metrics()
+//		ch <- prometheus.MustNewConstMetric(hitsDesc, prometheus.CounterValue, float64(m.Hits))
+//		ch <- prometheus.MustNewConstMetric(lenDesc, prometheus.GaugeValue, float64(m.Len))
+//		// ...
+//	}
+//
+// and registered using prometheus.MustRegister(collector{cache}).
+type collector struct {
+	cache libcache.Cache
+}
+
+func (c collector) Collect(ch chan<- sample) {
+	// Metrics is cheap, so it read on each scrape.
+	m := c.cache.Metrics()
+	ch <- sample{"cache_hits_total", float64(m.Hits)}
+	ch <- sample{"cache_misses_total", float64(m.Misses)}
+	ch <- sample{"cache_evictions_total", float64(m.Evictions)}
+	ch <- sample{"cache_expirations_total", float64(m.Expirations)}
+	ch <- sample{"cache_entries", float64(m.Len)}
+	ch <- sample{"cache_capacity", float64(m.Cap)}
+	ch <- sample{"cache_cost", float64(m.Cost)}
+	close(ch)
+}
+
+func ExampleCache_Metrics() {
+	cache := libcache.LRU.New(2)
+	cache.Store(1, 1)
+	cache.Store(2, 2)
+	cache.Store(3, 3)
+	cache.Load(3)
+	cache.Load(1)
+
+	ch := make(chan sample, 7)
+	collector{cache}.Collect(ch)
+
+	for s := range ch {
+		fmt.Println(s.name, s.value)
+	}
+
+	// Output:
+	// cache_hits_total 1
+	// cache_misses_total 1
+	// cache_evictions_total 1
+	// cache_expirations_total 0
+	// cache_entries 2
+	// cache_capacity 2
+	// cache_cost 2
+}
@@ -0,0 +1,138 @@
+package libcache
+
+import "time"
+
+// Option configures a cache using the functional options paradigm.
+type Option func(Cache)
+
+// reconfigurer is the cache the options re-applied to by Reset, while the thread safe cache lock held,
+// it forwards the configuration to the unsafe cache and records the thread safe cache state.
+// The options registering listeners, e.g. WithOnEvicted, skip it, as their listeners kept.
+type reconfigurer struct {
+	Cache
+	c *cache
+}
+
+func (r reconfigurer) SetKeyFunc(fn KeyFunc) {
+	r.Cache.SetKeyFunc(fn)
+	r.c.keyFunc = fn
+}
+
+func (r reconfigurer) SetClock(clock Clock) {
+	r.Cache.SetClock(clock)
+	r.c.clock = clock
+}
+
+// WithTTL sets the cache entries default TTL.
+func WithTTL(ttl time.Duration) Option {
+	return func(c Cache) {
+		c.SetTTL(ttl)
+	}
+}
+
+// WithCapacity sets the cache capacity, overriding the capacity passed to the constructor.
+func WithCapacity(cap int) Option {
+	return func(c Cache) {
+		c.Resize(cap)
+	}
+}
+
+// WithClock sets the clock used to compute and check entries expiry.
+func WithClock(clock Clock) Option {
+	return func(c Cache) {
+		c.SetClock(clock)
+	}
+}
+
+// WithKeyFunc sets the function used to normalize keys into a comparable form.
+func WithKeyFunc(fn KeyFunc) Option {
+	return func(c Cache) {
+		c.SetKeyFunc(fn)
+	}
+}
+
+// WithBloomFilter fronts the cache lookups with a counting bloom filter
+// sized for expectedN keys at the given false positive rate.
+func WithBloomFilter(expectedN int, fpRate float64) Option {
+	return func(c Cache) {
+		c.SetBloomFilter(expectedN, fpRate)
+	}
+}
+
+// WithDeterministic sets whether the cache is reproducible, for golden tests,
+// see Cache.SetDeterministic.
+func WithDeterministic(enabled bool) Option {
+	return func(c Cache) {
+		c.SetDeterministic(enabled)
+	}
+}
+
+// WithAdmissionFunc sets the function deciding whether the stores of new keys insert them,
+// see Cache.SetAdmissionFunc.
+func WithAdmissionFunc(fn AdmissionFunc) Option {
+	return func(c Cache) {
+		c.SetAdmissionFunc(fn)
+	}
+}
+
+// WithGCBatchSize sets the max number of expired entries a GC call,
+// or an operation on a single key, sweeps, see Cache.SetGCBatchSize.
+func WithGCBatchSize(n int) Option {
+	return func(c Cache) {
+		c.SetGCBatchSize(n)
+	}
+}
+
+// WithMaxKeyBytes sets the entries total key bytes budget,
+// see Cache.SetMaxKeyBytes.
+func WithMaxKeyBytes(n int64) Option {
+	return func(c Cache) {
+		c.SetMaxKeyBytes(n)
+	}
+}
+
+// WithPurgeRemoveEvents sets whether Purge fires a Remove event for each entry,
+// see Cache.SetPurgeRemoveEvents.
+func WithPurgeRemoveEvents(enabled bool) Option {
+	return func(c Cache) {
+		c.SetPurgeRemoveEvents(enabled)
+	}
+}
+
+// WithOnEvicted registers fn to be called with the key and value of each removed or expired entry,
+// it has effect only on caches returned by the ReplacementPolicy New methods.
+//
+// Purge fires a single Purge event instead of a Remove event per entry,
+// so fn not called for the purged entries, unless WithPurgeRemoveEvents enabled.
+//
+// fn called asynchronously from a goroutine fed by NotifyBlocking,
+// so removals queued in order while fn is slow instead of dropped, and fn may call the cache.
+// The goroutine kept registered once the cache reset, so fn keeps firing,
+// and exits once the cache closed, dropping the removals still queued,
+// therefore the cache must be closed to release the goroutine.
+// fn panics routed to the handler set by WithPanicHandler.
+func WithOnEvicted(fn func(key, value interface{})) Option {
+	return func(c Cache) {
+		// Reset re-applies the options to a reconfigurer, and registers the listener again itself.
+		sc, ok := c.(*cache)
+		if !ok {
+			return
+		}
+
+		ch := make(chan Event)
+		done := sc.listen(ch, Remove, Expire)
+		go func() {
+			defer sc.Ignore(ch)
+			for {
+				select {
+				case e := <-ch:
+					sc.guard(func() {
+						fn(e.Key, e.Value)
+					})
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+}
@@ -0,0 +1,40 @@
+package libcache
+
+// PanicHandler handles the value recovered from a panicking user callback,
+// e.g. to log it, so a buggy callback can't take down the process.
+type PanicHandler func(recovered interface{})
+
+// WithPanicHandler routes the panics of the user callbacks, i.e. the WithOnEvicted function
+// and the Hooks functions, to h and keeps the cache working.
+// By default, and if h is nil, the panics propagate as is.
+// It has effect only on caches returned by the ReplacementPolicy New methods.
+func WithPanicHandler(h PanicHandler) Option {
+	return func(c Cache) {
+		sc, ok := c.(*cache)
+		if !ok {
+			return
+		}
+
+		sc.mu.Lock()
+		sc.panicHandler = h
+		sc.mu.Unlock()
+	}
+}
+
+// guard calls fn, and routes its panic to the cache panic handler if set,
+// guard must be called without holding the cache lock.
+func (c *cache) guard(fn func()) {
+	c.mu.RLock()
+	h := c.panicHandler
+	c.mu.RUnlock()
+
+	if h != nil {
+		defer func() {
+			if r := recover(); r != nil {
+				h(r)
+			}
+		}()
+	}
+
+	fn()
+}
@@ -1,8 +1,11 @@
 package libcache
 
 import (
+	"context"
+	"log"
 	"strconv"
 	"sync"
+	"time"
 )
 
 const (
@@ -20,6 +23,12 @@ const (
 	MRU
 	// ARC cache replacement policy.
 	ARC
+	// LRUK cache replacement policy.
+	LRUK
+	// GDSF cache replacement policy.
+	GDSF
+	// WTinyLFU cache replacement policy.
+	WTinyLFU
 	max
 )
 
@@ -46,15 +55,65 @@ func (c ReplacementPolicy) Available() bool {
 }
 
 // New returns a new thread safe cache.
+// Zero capacity means unbounded, and negative capacity clamped to zero.
 // New panics if the cache replacement policy function is not linked into the binary.
 func (c ReplacementPolicy) New(cap int) Cache {
 	cache := new(cache)
-	cache.mu = sync.Mutex{}
+	cache.mu = sync.RWMutex{}
+	cache.done = make(chan struct{})
 	cache.unsafe = c.NewUnsafe(cap)
+	cache.policy = c
 	return cache
 }
 
+// NewWithOptions returns a new thread safe cache, configured by the given options.
+// Reset re-applies the options, so the cache keeps the configuration it constructed with.
+func (c ReplacementPolicy) NewWithOptions(cap int, opts ...Option) Cache {
+	sc := c.New(cap).(*cache)
+	for _, opt := range opts {
+		opt(sc)
+	}
+	sc.opts = opts
+	return sc
+}
+
+// NewWithJanitor returns a new thread safe cache, that owns a janitor goroutine
+// evicting expired entries on time, the janitor stopped by calling the cache Close method.
+//
+// The janitor wakes up when the nearest entry expires,
+// and every interval as a fallback, zero interval means wake up on expiry only.
+func (c ReplacementPolicy) NewWithJanitor(cap int, interval time.Duration) Cache {
+	cache := c.New(cap)
+	go gc(context.Background(), cache, interval)
+	return cache
+}
+
+// NewWithClock returns a new thread safe cache, that uses the given clock
+// to compute and check entries expiry.
+// Reset keeps the clock, like the options of NewWithOptions.
+func (c ReplacementPolicy) NewWithClock(cap int, clock Clock) Cache {
+	return c.NewWithOptions(cap, WithClock(clock))
+}
+
+// SafeNew returns a new thread safe cache of the given cache replacement policy.
+// SafeNew recovers from a panic raised while constructing the cache,
+// logs the failure and falls back to an IDLE cache instead,
+// which is always available as libcache registers it.
+func SafeNew(c ReplacementPolicy, cap int) (cache Cache) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("libcache: %s cache construction failed, falling back to IDLE: %v", c, r)
+			cache = IDLE.New(0)
+		}
+	}()
+
+	return c.New(cap)
+}
+
 // NewUnsafe returns a new non-thread safe cache.
+// All the unsafe cache methods, Notify, Ignore and the functions returned by Subscribe
+// and Watch included, must be serialized by the caller,
+// as the cache operations emit events to the registered channels.
 // NewUnsafe panics if the cache replacement policy function is not linked into the binary.
 func (c ReplacementPolicy) NewUnsafe(cap int) Cache {
 	if !c.Available() {
@@ -81,6 +140,12 @@ func (c ReplacementPolicy) String() string {
 		return "MRU"
 	case ARC:
 		return "ARC"
+	case LRUK:
+		return "LRUK"
+	case GDSF:
+		return "GDSF"
+	case WTinyLFU:
+		return "WTinyLFU"
 	default:
 		return "unknown cache replacement policy value " + strconv.Itoa(int(c))
 	}
@@ -0,0 +1,256 @@
+package libcache
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/shaj13/libcache/internal"
+)
+
+// Loader loads the key value from the underlying data source.
+type Loader = internal.Loader
+
+// EnableAutoRefresh starts a background refresher, that proactively reloads
+// the cache entries expiring within the given lead time using the loader
+// and stores them again with their original TTL, so reads never hit a cold miss.
+// The loader passed explicitly, as the caches hold no loader of their own.
+//
+// The refresher scans the cache entries every half lead time,
+// and reloads the soon-to-expire entries using the given number of workers,
+// a key reloaded at most once at a time.
+//
+// The soon-to-expire entries are found against the cache clock,
+// while the scans scheduled by the wall clock.
+//
+// Loader errors are ignored and the entry left to expire.
+// The reloaded value stored only if the key still in the cache,
+// so the keys deleted while reloading are not brought back.
+//
+// The returned io.Closer stops the refresher, and closing the cache stops it as well,
+// while resetting the cache does not.
+// The cache must be a thread safe cache. The caches returned by the ReplacementPolicy New methods
+// dedupe the keys by their normalized form and store the reloaded value under their lock,
+// while other caches dedupe the keys as is, which must be comparable.
+//
+// EnableAutoRefresh panics if lead or workers is not positive.
+func EnableAutoRefresh(cache Cache, loader Loader, lead time.Duration, workers int) io.Closer {
+	if lead <= 0 {
+		panic("libcache: EnableAutoRefresh non-positive lead")
+	}
+
+	if workers <= 0 {
+		panic("libcache: EnableAutoRefresh non-positive workers")
+	}
+
+	// The subscription channel closed once the cache closed.
+	closed, cancel := cache.Subscribe(Purge)
+	id, store := refreshOps(cache)
+
+	r := &refresher{
+		cache:    cache,
+		loader:   loader,
+		id:       id,
+		store:    store,
+		lead:     lead,
+		keys:     make(chan interface{}),
+		done:     make(chan struct{}),
+		closed:   closed,
+		cancel:   cancel,
+		inflight: make(map[interface{}]struct{}),
+	}
+
+	for i := 0; i < workers; i++ {
+		go r.work()
+	}
+
+	go r.scan()
+
+	return r
+}
+
+type refresher struct {
+	cache  Cache
+	loader Loader
+	// id returns the normalized key, the inflight keys deduped by.
+	id func(key interface{}) interface{}
+	// store stores the reloaded value with ttl, if the key still in the cache.
+	store func(key, value interface{}, ttl time.Duration)
+	lead  time.Duration
+	keys  chan interface{}
+	done  chan struct{}
+	once  sync.Once
+	// closed is the cache subscription channel, that closed with the cache,
+	// and cancel cancels it.
+	closed <-chan Event
+	cancel func()
+	// mu guards inflight keys and cancel.
+	mu       sync.Mutex
+	inflight map[interface{}]struct{}
+}
+
+func (r *refresher) scan() {
+	interval := r.lead / 2
+	if interval <= 0 {
+		interval = r.lead
+	}
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-t.C:
+			if !r.refresh() {
+				return
+			}
+		case _, ok := <-r.closed:
+			// Purge events ignored, only the channel close matters.
+			if ok {
+				continue
+			}
+
+			if !r.resubscribe() {
+				_ = r.Close()
+				return
+			}
+		case <-r.done:
+			return
+		}
+	}
+}
+
+// resubscribe subscribes to the cache again once Reset closed the subscription,
+// it reports false if the cache closed instead.
+func (r *refresher) resubscribe() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var ok bool
+	r.closed, r.cancel, ok = resubscribe(r.cache, Purge)
+	return ok
+}
+
+// refresh hands the keys expiring within the lead time to the workers,
+// it reports false if the refresher stopped meanwhile.
+func (r *refresher) refresh() bool {
+	for _, k := range r.cache.ExpiringWithin(r.lead) {
+		if !r.acquire(k) {
+			continue
+		}
+
+		select {
+		case r.keys <- k:
+		case <-r.done:
+			return false
+		}
+	}
+
+	return true
+}
+
+func (r *refresher) work() {
+	for {
+		select {
+		case k := <-r.keys:
+			r.reload(k)
+			r.release(k)
+		case <-r.done:
+			return
+		}
+	}
+}
+
+// reload reloads the key value, and stores it with the entry original TTL,
+// observed before the loader called.
+func (r *refresher) reload(key interface{}) {
+	e, ok := r.cache.GetEntry(key)
+	if !ok || e.Expiry.IsZero() {
+		return
+	}
+
+	ttl := e.Expiry.Sub(e.Created)
+	if ttl <= 0 {
+		return
+	}
+
+	if v, err := r.loader(key); err == nil {
+		r.store(key, v, ttl)
+	}
+}
+
+// acquire reports whether the key is not already being reloaded,
+// and marks it as inflight.
+func (r *refresher) acquire(key interface{}) bool {
+	id := r.id(key)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.inflight[id]; ok {
+		return false
+	}
+
+	r.inflight[id] = struct{}{}
+	return true
+}
+
+func (r *refresher) release(key interface{}) {
+	id := r.id(key)
+
+	r.mu.Lock()
+	delete(r.inflight, id)
+	r.mu.Unlock()
+}
+
+// refreshOps returns the refresher key normalization and conditional store of the given cache.
+// Other caches than the thread safe cache keep the keys as is,
+// and check and store the keys through their methods, therefore not atomically.
+func refreshOps(c Cache) (func(key interface{}) interface{}, func(key, value interface{}, ttl time.Duration)) {
+	if sc, ok := c.(*cache); ok {
+		return sc.keyID, sc.storeIfPresent
+	}
+
+	id := func(key interface{}) interface{} {
+		return key
+	}
+
+	store := func(key, value interface{}, ttl time.Duration) {
+		if c.Contains(key) {
+			c.StoreWithTTL(key, value, ttl)
+		}
+	}
+
+	return id, store
+}
+
+// keyID returns the normalized key.
+func (c *cache) keyID(key interface{}) interface{} {
+	c.mu.RLock()
+	id := c.keyFunc.Key(key)
+	c.mu.RUnlock()
+	return id
+}
+
+// storeIfPresent stores the key value with ttl, if the key in the cache,
+// the check and the store done under the cache lock.
+func (c *cache) storeIfPresent(key, value interface{}, ttl time.Duration) {
+	c.beforeStore(key)
+	c.mu.Lock()
+	if c.unsafe.Contains(key) {
+		c.unsafe.StoreWithTTL(key, value, ttl)
+	}
+	c.mu.Unlock()
+	c.afterStore(key)
+}
+
+// Close stops the refresher.
+func (r *refresher) Close() error {
+	r.once.Do(func() {
+		close(r.done)
+		r.mu.Lock()
+		r.cancel()
+		r.mu.Unlock()
+	})
+	return nil
+}
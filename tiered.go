@@ -0,0 +1,1053 @@
+package libcache
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/shaj13/libcache/internal"
+)
+
+// Tiered returns a thread safe two-tier cache, composed of a small fast l1
+// and a larger l2, entries evicted from l1 demoted into l2, and a miss in l1
+// promotes the entry back from l2.
+//
+// Entries written to l1 and l2 directly bypass the tiered cache,
+// and l1 must not be used on its own while used as a tier.
+//
+// Only l1 capacity evictions demoted, expired entries have nothing to serve,
+// and deleted entries must not come back. Entries tags are not carried to l2.
+//
+// The l1 removals observed synchronously when l1 is a cache returned by this package,
+// otherwise through a Notify channel, that drops the removals beyond its buffer
+// when a single operation, e.g. Resize, evicts many entries at once.
+//
+// Cap and Resize refer to l1, the l2 capacity is configured on its own.
+// Notify, Subscribe and Ignore relay l1 events, as l1 serves the users operations.
+// Every operation touching l1 or l2, the channels registration and configuration included,
+// holds the tiered lock, so l1 and l2 may be non-thread safe caches.
+func Tiered(l1, l2 Cache) Cache {
+	t := &tiered{
+		l1: l1,
+		l2: l2,
+	}
+
+	t.observe()
+	return t
+}
+
+// funcNotifier is implemented by the caches that call a function synchronously
+// for each event, instead of sending it to a channel that may be full.
+type funcNotifier interface {
+	NotifyFunc(fn func(Event), ops ...Op)
+}
+
+// expiryPairer is implemented by the caches that pair their keys with their expiry
+// in a single scan, so composed caches sort their keys without looking up each key.
+type expiryPairer interface {
+	ExpiryPairs() []internal.KeyExpiry
+	ExpiryPairsWithin(d time.Duration) []internal.KeyExpiry
+}
+
+// expiryPairs returns all c keys paired with their expiry,
+// looking up each key expiry if c is not an expiryPairer.
+func expiryPairs(c Cache) []internal.KeyExpiry {
+	if p, ok := c.(expiryPairer); ok {
+		return p.ExpiryPairs()
+	}
+	return lookupExpiry(c, c.KeysByExpiry())
+}
+
+// expiryPairsWithin returns c keys expiring within d paired with their expiry,
+// looking up each key expiry if c is not an expiryPairer.
+func expiryPairsWithin(c Cache, d time.Duration) []internal.KeyExpiry {
+	if p, ok := c.(expiryPairer); ok {
+		return p.ExpiryPairsWithin(d)
+	}
+	return lookupExpiry(c, c.ExpiringWithin(d))
+}
+
+func lookupExpiry(c Cache, keys []interface{}) []internal.KeyExpiry {
+	pairs := make([]internal.KeyExpiry, len(keys))
+	for i, k := range keys {
+		pairs[i].Key = k
+		pairs[i].Expiry, _ = c.Expiry(k)
+	}
+	return pairs
+}
+
+type tiered struct {
+	// counters kept first to be 64-bit aligned for atomic operations,
+	// and counts lookups only, as l1 and l2 count evictions and expirations.
+	counters internal.Counters
+	// mu serializes the tiered operations, so l1 removals
+	// are drained by the operation that caused them.
+	mu sync.Mutex
+	l1 Cache
+	l2 Cache
+	// removed holds the l1 removals not yet drained.
+	removed []Event
+	// evicted receives the l1 removals when l1 is not a funcNotifier.
+	evicted chan Event
+	// keyFunc normalizes the keys to deduplicate them.
+	keyFunc KeyFunc
+	// admit reports whether new keys stored, nil means all of them,
+	// it is not set on l1 and l2, as demotions and promotions are not stores.
+	admit AdmissionFunc
+}
+
+// observe registers t to record the l1 removals,
+// synchronously if l1 is a funcNotifier, and through the evicted channel otherwise.
+func (t *tiered) observe() {
+	if n, ok := t.l1.(funcNotifier); ok {
+		n.NotifyFunc(func(e Event) {
+			t.removed = append(t.removed, e)
+		}, Remove)
+		return
+	}
+
+	if t.evicted == nil {
+		t.evicted = make(chan Event, internal.SubscriptionBuffer)
+	}
+
+	t.l1.Notify(t.evicted, Remove)
+}
+
+// drain consumes the pending l1 removals,
+// and demotes them into l2 when caused by capacity eviction.
+// drain returns the first entry evicted from l2 by the demotions, if any.
+func (t *tiered) drain(demote bool) (key, value interface{}, evicted bool) {
+	for t.evicted != nil && len(t.evicted) > 0 {
+		t.removed = append(t.removed, <-t.evicted)
+	}
+
+	for i, e := range t.removed {
+		t.removed[i] = Event{}
+		if !demote {
+			continue
+		}
+
+		if k, v, ok := t.demote(e); ok && !evicted {
+			key, value, evicted = k, v, ok
+		}
+	}
+
+	t.removed = t.removed[:0]
+	return
+}
+
+// demote stores the l1 evicted entry into l2, keeping its absolute expiry.
+// l1 collects its expired entries before evicting, so the entry is live.
+func (t *tiered) demote(e Event) (key, value interface{}, evicted bool) {
+	return t.l2.StoreWithDeadlineEvicting(e.Key, e.Value, e.Expiry)
+}
+
+// promote moves the key from l2 into l1, if l1 does not have it.
+func (t *tiered) promote(key interface{}) {
+	if t.l1.Contains(key) {
+		return
+	}
+
+	exp, ok := t.l2.Expiry(key)
+	if !ok {
+		return
+	}
+
+	v, ok := t.l2.GetAndDelete(key)
+	if !ok {
+		return
+	}
+
+	t.l1.StoreWithDeadline(key, v, exp)
+	t.drain(true)
+}
+
+func (t *tiered) load(key interface{}) (interface{}, bool) {
+	t.promote(key)
+	return t.l1.Load(key)
+}
+
+func (t *tiered) store(key, value interface{}, ttl, jitter time.Duration) {
+	if !t.admits(key, value) {
+		return
+	}
+
+	t.l1.StoreWithTTLJitter(key, value, ttl, jitter)
+	t.l2.Delete(key)
+	t.drain(true)
+}
+
+func (t *tiered) delete(key interface{}) {
+	t.l1.Delete(key)
+	t.l2.Delete(key)
+	t.drain(false)
+}
+
+func (t *tiered) contains(key interface{}) bool {
+	return t.l1.Contains(key) || t.l2.Contains(key)
+}
+
+// resident reports whether either tier has the key entry, negative entries included.
+func (t *tiered) resident(key interface{}) bool {
+	if _, ok := t.l1.GetEntry(key); ok {
+		return true
+	}
+	_, ok := t.l2.GetEntry(key)
+	return ok
+}
+
+// admits reports whether the key value stored, as the key exists or admitted.
+func (t *tiered) admits(key, value interface{}) bool {
+	return t.admit == nil || t.resident(key) || internal.Admits(t.admit, key, value)
+}
+
+// union returns the given keys deduplicated.
+func (t *tiered) union(keys ...[]interface{}) []interface{} {
+	seen := make(map[interface{}]struct{})
+	u := []interface{}{}
+
+	for _, ks := range keys {
+		for _, k := range ks {
+			id := t.keyFunc.Key(k)
+			if _, ok := seen[id]; ok {
+				continue
+			}
+			seen[id] = struct{}{}
+			u = append(u, k)
+		}
+	}
+
+	return u
+}
+
+func (t *tiered) Load(key interface{}) (interface{}, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	v, ok := t.load(key)
+	t.counters.Lookup(ok)
+	return v, ok
+}
+
+func (t *tiered) LoadWithExpiry(key interface{}) (interface{}, time.Time, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	v, ok := t.load(key)
+	t.counters.Lookup(ok)
+	if !ok {
+		return nil, time.Time{}, false
+	}
+
+	exp, found := t.l1.Expiry(key)
+	if !found {
+		exp, _ = t.l2.Expiry(key)
+	}
+	return v, exp, true
+}
+
+func (t *tiered) Peek(key interface{}) (interface{}, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	v, ok := t.l1.Peek(key)
+	if !ok {
+		v, ok = t.l2.Peek(key)
+	}
+
+	t.counters.Lookup(ok)
+	return v, ok
+}
+
+func (t *tiered) Test(key interface{}, pred func(value interface{}) bool) (bool, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.promote(key)
+	ok, found := t.l1.Test(key, pred)
+	t.counters.Lookup(found)
+	return ok, found
+}
+
+func (t *tiered) Update(key interface{}, value interface{}) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.l1.Update(key, value)
+	t.l2.Update(key, value)
+}
+
+func (t *tiered) CompareAndSwap(key, old, new interface{}) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.promote(key)
+	return t.l1.CompareAndSwap(key, old, new)
+}
+
+func (t *tiered) CompareAndDelete(key, old interface{}) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.promote(key)
+	ok := t.l1.CompareAndDelete(key, old)
+	t.drain(false)
+	return ok
+}
+
+func (t *tiered) Increment(key interface{}, delta int64) (int64, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.admits(key, delta) {
+		return 0, ErrNotStored
+	}
+
+	t.promote(key)
+	n, err := t.l1.Increment(key, delta)
+	t.drain(true)
+	return n, err
+}
+
+func (t *tiered) Decrement(key interface{}, delta int64) (int64, error) {
+	return t.Increment(key, -delta)
+}
+
+func (t *tiered) Store(key interface{}, value interface{}) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.admits(key, value) {
+		return
+	}
+
+	t.l1.Store(key, value)
+	t.l2.Delete(key)
+	t.drain(true)
+}
+
+func (t *tiered) StoreWithTTL(key interface{}, value interface{}, ttl time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.admits(key, value) {
+		return
+	}
+
+	t.l1.StoreWithTTL(key, value, ttl)
+	t.l2.Delete(key)
+	t.drain(true)
+}
+
+// StoreEvicting returns the entry evicted from l2, as l1 evictions demoted into l2.
+func (t *tiered) StoreEvicting(key, value interface{}) (interface{}, interface{}, bool) {
+	return t.StoreWithTTLEvicting(key, value, t.TTL())
+}
+
+// StoreWithTTLEvicting returns the entry evicted from l2, as l1 evictions demoted into l2.
+func (t *tiered) StoreWithTTLEvicting(key, value interface{}, ttl time.Duration) (interface{}, interface{}, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.admits(key, value) {
+		return nil, nil, false
+	}
+
+	t.l1.StoreWithTTL(key, value, ttl)
+	t.l2.Delete(key)
+	return t.drain(true)
+}
+
+func (t *tiered) StoreWithDeadline(key interface{}, value interface{}, deadline time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.admits(key, value) {
+		return
+	}
+
+	t.l1.StoreWithDeadline(key, value, deadline)
+	t.l2.Delete(key)
+	t.drain(true)
+}
+
+// StoreWithDeadlineEvicting returns the entry evicted from l2, as l1 evictions demoted into l2.
+func (t *tiered) StoreWithDeadlineEvicting(key, value interface{}, deadline time.Time) (interface{}, interface{}, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.admits(key, value) {
+		return nil, nil, false
+	}
+
+	t.l1.StoreWithDeadline(key, value, deadline)
+	t.l2.Delete(key)
+	return t.drain(true)
+}
+
+func (t *tiered) StoreWithTTLJitter(key interface{}, value interface{}, ttl, jitter time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.store(key, value, ttl, jitter)
+}
+
+func (t *tiered) StoreMany(items map[interface{}]interface{}) {
+	for k, v := range items {
+		t.Store(k, v)
+	}
+}
+
+func (t *tiered) Warm(items map[interface{}]interface{}) {
+	t.WarmWithTTL(items, t.TTL())
+}
+
+func (t *tiered) WarmWithTTL(items map[interface{}]interface{}, ttl time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// Warm l1 item by item, so its evictions never exceed the drain buffer.
+	for k, v := range items {
+		if !t.admits(k, v) {
+			continue
+		}
+
+		t.l1.WarmWithTTL(map[interface{}]interface{}{k: v}, ttl)
+		t.l2.Delete(k)
+		t.drain(true)
+	}
+}
+
+func (t *tiered) LoadMany(keys ...interface{}) map[interface{}]interface{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	items := make(map[interface{}]interface{})
+	for _, k := range keys {
+		if v, ok := t.load(k); ok {
+			items[t.keyFunc.Key(k)] = v
+		}
+	}
+	return items
+}
+
+func (t *tiered) Delete(key interface{}) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.delete(key)
+}
+
+func (t *tiered) GetAndDelete(key interface{}) (interface{}, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	defer t.drain(false)
+
+	if v, ok := t.l1.GetAndDelete(key); ok {
+		return v, ok
+	}
+	return t.l2.GetAndDelete(key)
+}
+
+func (t *tiered) DeleteMany(keys ...interface{}) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, k := range keys {
+		t.delete(k)
+	}
+}
+
+func (t *tiered) GetEntry(key interface{}) (EntryInfo, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if info, ok := t.l1.GetEntry(key); ok {
+		return info, ok
+	}
+	return t.l2.GetEntry(key)
+}
+
+func (t *tiered) Expiry(key interface{}) (time.Time, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.expiry(key)
+}
+
+func (t *tiered) Touch(key interface{}, ttl time.Duration) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.l1.Touch(key, ttl) || t.l2.Touch(key, ttl)
+}
+
+func (t *tiered) RefreshAllTTL(ttl time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.l1.RefreshAllTTL(ttl)
+	t.l2.RefreshAllTTL(ttl)
+}
+
+func (t *tiered) ClearTTL() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.l1.ClearTTL()
+	t.l2.ClearTTL()
+}
+
+func (t *tiered) SetExpiry(key interface{}, exp time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.l1.SetExpiry(key, exp) || t.l2.SetExpiry(key, exp)
+}
+
+func (t *tiered) RemainingTTL(key interface{}) (time.Duration, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if ttl, ok := t.l1.RemainingTTL(key); ok {
+		return ttl, ok
+	}
+	return t.l2.RemainingTTL(key)
+}
+
+func (t *tiered) Keys() []interface{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.union(t.l1.Keys(), t.l2.Keys())
+}
+
+func (t *tiered) KeysByExpiry() []interface{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return internal.SortByExpiry(t.unionPairs(expiryPairs(t.l1), expiryPairs(t.l2)))
+}
+
+func (t *tiered) ExpiringWithin(d time.Duration) []interface{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return internal.SortByExpiry(t.unionPairs(expiryPairsWithin(t.l1, d), expiryPairsWithin(t.l2, d)))
+}
+
+// ExpiryPairs returns all the keys paired with their expiry, in unspecified order.
+func (t *tiered) ExpiryPairs() []internal.KeyExpiry {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.unionPairs(expiryPairs(t.l1), expiryPairs(t.l2))
+}
+
+// ExpiryPairsWithin returns the keys expiring within d from now paired with their expiry,
+// in unspecified order.
+func (t *tiered) ExpiryPairsWithin(d time.Duration) []internal.KeyExpiry {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.unionPairs(expiryPairsWithin(t.l1, d), expiryPairsWithin(t.l2, d))
+}
+
+// expiry returns the key expiry from l1, or from l2 if l1 does not have it.
+func (t *tiered) expiry(key interface{}) (time.Time, bool) {
+	if exp, ok := t.l1.Expiry(key); ok {
+		return exp, ok
+	}
+	return t.l2.Expiry(key)
+}
+
+// unionPairs returns the pairs deduplicated by their normalized key, the l1 pair first.
+func (t *tiered) unionPairs(l1, l2 []internal.KeyExpiry) []internal.KeyExpiry {
+	seen := make(map[interface{}]struct{}, len(l1))
+	for _, p := range l1 {
+		seen[t.keyFunc.Key(p.Key)] = struct{}{}
+	}
+
+	for _, p := range l2 {
+		if _, ok := seen[t.keyFunc.Key(p.Key)]; !ok {
+			l1 = append(l1, p)
+		}
+	}
+
+	return l1
+}
+
+func (t *tiered) GetOrCompute(key interface{}, loader Loader) (interface{}, error) {
+	if v, ok := t.Load(key); ok {
+		return v, nil
+	}
+
+	if e, ok := t.GetEntry(key); ok && internal.IsNegative(e.Value) {
+		return nil, ErrNotFound
+	}
+
+	v, err := loader(key)
+	return internal.StoreLoaded(t, key, v, err)
+}
+
+// Merge snapshots the other cache entries before merging them,
+// so the other cache may be the tiered cache itself.
+func (t *tiered) Merge(other EntrySource, onConflict func(existing, incoming interface{}) interface{}) {
+	internal.MergeEntries(t, internal.LiveEntries(other), onConflict)
+}
+
+func (t *tiered) LoadCtx(ctx context.Context, key interface{}, loader func(context.Context) (interface{}, error)) (interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return t.GetOrCompute(key, internal.ContextLoader(ctx, loader))
+}
+
+func (t *tiered) StoreNegative(key interface{}, ttl time.Duration) {
+	t.StoreWithTTL(key, Negative{}, ttl)
+}
+
+func (t *tiered) StoreWithTags(key, value interface{}, tags ...string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.admits(key, value) {
+		return
+	}
+
+	t.l1.StoreWithTags(key, value, tags...)
+	t.l2.Delete(key)
+	t.drain(true)
+}
+
+func (t *tiered) InvalidateTag(tag string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	n := t.l1.InvalidateTag(tag) + t.l2.InvalidateTag(tag)
+	t.drain(false)
+	return n
+}
+
+func (t *tiered) KeysWithPrefix(prefix string) []interface{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.union(t.l1.KeysWithPrefix(prefix), t.l2.KeysWithPrefix(prefix))
+}
+
+func (t *tiered) DeleteWithPrefix(prefix string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	keys := t.union(t.l1.KeysWithPrefix(prefix), t.l2.KeysWithPrefix(prefix))
+	for _, k := range keys {
+		t.delete(k)
+	}
+	return len(keys)
+}
+
+func (t *tiered) DeleteFunc(pred func(key, value interface{}) bool) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	m := t.l2.Snapshot()
+	for k, v := range t.l1.Snapshot() {
+		m[k] = v
+	}
+
+	n := 0
+	for k, v := range m {
+		if !internal.IsNegative(v) && pred(k, v) {
+			t.delete(k)
+			n++
+		}
+	}
+	return n
+}
+
+func (t *tiered) Snapshot() map[interface{}]interface{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	m := t.l2.Snapshot()
+	for k, v := range t.l1.Snapshot() {
+		m[k] = v
+	}
+	return m
+}
+
+func (t *tiered) Iterator() *Iterator {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return internal.Chain(t.l1.Iterator(), t.l2.Iterator())
+}
+
+func (t *tiered) Pin(key interface{}) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.promote(key)
+	return t.l1.Pin(key)
+}
+
+func (t *tiered) Unpin(key interface{}) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.l1.Unpin(key)
+}
+
+func (t *tiered) Contains(key interface{}) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.contains(key)
+}
+
+func (t *tiered) ContainsNoGC(key interface{}) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.l1.ContainsNoGC(key) || t.l2.ContainsNoGC(key)
+}
+
+func (t *tiered) ContainsMany(keys []interface{}) []bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	flags := make([]bool, len(keys))
+	for i, k := range keys {
+		flags[i] = t.contains(k)
+	}
+	return flags
+}
+
+func (t *tiered) Purge() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.l1.Purge()
+	t.l2.Purge()
+	t.drain(false)
+}
+
+func (t *tiered) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.l1.Reset()
+	t.l2.Reset()
+	t.drain(false)
+	t.counters.Reset()
+	t.keyFunc = nil
+	t.admit = nil
+	// Reset removes all l1 Notify channels and functions.
+	t.observe()
+}
+
+func (t *tiered) PauseEviction() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.l1.PauseEviction()
+	t.l2.PauseEviction()
+}
+
+func (t *tiered) ResumeEviction() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.l1.ResumeEviction()
+	t.drain(true)
+	t.l2.ResumeEviction()
+}
+
+func (t *tiered) Resize(size int) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	n := t.l1.Resize(size)
+	t.drain(true)
+	return n
+}
+
+func (t *tiered) ResizeCost(maxCost int64) int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	n := t.l1.ResizeCost(maxCost)
+	t.drain(true)
+	return n
+}
+
+func (t *tiered) SetMaxKeyBytes(n int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.l1.SetMaxKeyBytes(n)
+	t.drain(true)
+}
+
+func (t *tiered) Len() int {
+	return len(t.Keys())
+}
+
+func (t *tiered) PeekOldest() (key, value interface{}, ok bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.l1.PeekOldest()
+}
+
+func (t *tiered) PeekNewest() (key, value interface{}, ok bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.l1.PeekNewest()
+}
+
+// OrderedKeys returns l1 keys in eviction order, followed by l2 keys in eviction order.
+func (t *tiered) OrderedKeys() []interface{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.union(t.l1.OrderedKeys(), t.l2.OrderedKeys())
+}
+
+// ColdestN returns l1 coldest entries, followed by l2 coldest entries.
+func (t *tiered) ColdestN(n int) []EntryInfo {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.firstN(n, t.l1.ColdestN(n), t.l2.ColdestN(n))
+}
+
+// HottestN returns l2 hottest entries, followed by l1 hottest entries.
+func (t *tiered) HottestN(n int) []EntryInfo {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.firstN(n, t.l2.HottestN(n), t.l1.HottestN(n))
+}
+
+// firstN returns up to n of the given entries deduplicated by key.
+func (t *tiered) firstN(n int, infos ...[]EntryInfo) []EntryInfo {
+	seen := make(map[interface{}]struct{})
+	u := []EntryInfo{}
+
+	for _, is := range infos {
+		for _, e := range is {
+			if len(u) == n {
+				return u
+			}
+			if _, ok := seen[e.Key]; !ok {
+				seen[e.Key] = struct{}{}
+				u = append(u, e)
+			}
+		}
+	}
+
+	return u
+}
+
+// Metrics returns l2 evictions only, as l1 evictions demoted into l2.
+func (t *tiered) Metrics() Metrics {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	m := t.counters.Metrics()
+	m1, m2 := t.l1.Metrics(), t.l2.Metrics()
+	m.Evictions = m2.Evictions
+	m.RecentEvictions = m2.RecentEvictions
+	m.Expirations = m1.Expirations + m2.Expirations
+	m.LastGCEvicted = m1.LastGCEvicted + m2.LastGCEvicted
+	m.LastGCDuration = m1.LastGCDuration + m2.LastGCDuration
+	m.Len = len(t.union(t.l1.Keys(), t.l2.Keys()))
+	m.Cap = t.l1.Cap()
+	m.Cost = t.l1.Cost() + t.l2.Cost()
+	return m
+}
+
+// Utilization returns l1 utilization, as l1 holds the cache capacity.
+func (t *tiered) Utilization() float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.l1.Utilization()
+}
+
+func (t *tiered) Cost() int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.l1.Cost() + t.l2.Cost()
+}
+
+func (t *tiered) SetWeigher(w Weigher) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.l1.SetWeigher(w)
+	t.l2.SetWeigher(w)
+}
+
+func (t *tiered) Cap() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.l1.Cap()
+}
+
+// Policy returns the first tier replacement policy.
+func (t *tiered) Policy() ReplacementPolicy {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.l1.Policy()
+}
+
+func (t *tiered) TTL() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.l1.TTL()
+}
+
+func (t *tiered) SetTTL(ttl time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.l1.SetTTL(ttl)
+	t.l2.SetTTL(ttl)
+}
+
+func (t *tiered) SetClock(clock Clock) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.l1.SetClock(clock)
+	t.l2.SetClock(clock)
+}
+
+func (t *tiered) SetJitter(jitter time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.l1.SetJitter(jitter)
+	t.l2.SetJitter(jitter)
+}
+
+func (t *tiered) SetAdmissionFunc(fn AdmissionFunc) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.admit = fn
+}
+
+func (t *tiered) SetKeyFunc(fn KeyFunc) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.l1.SetKeyFunc(fn)
+	t.l2.SetKeyFunc(fn)
+	t.drain(false)
+	t.keyFunc = fn
+}
+
+func (t *tiered) SetMaxIdle(d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.l1.SetMaxIdle(d)
+	t.l2.SetMaxIdle(d)
+}
+
+func (t *tiered) SetBloomFilter(expectedN int, fpRate float64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.l1.SetBloomFilter(expectedN, fpRate)
+	t.l2.SetBloomFilter(expectedN, fpRate)
+}
+
+func (t *tiered) SetGCBatchSize(n int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.l1.SetGCBatchSize(n)
+	t.l2.SetGCBatchSize(n)
+}
+
+func (t *tiered) SetPurgeRemoveEvents(enabled bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.l1.SetPurgeRemoveEvents(enabled)
+	t.l2.SetPurgeRemoveEvents(enabled)
+}
+
+func (t *tiered) SetDeterministic(enabled bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.l1.SetDeterministic(enabled)
+	t.l2.SetDeterministic(enabled)
+}
+
+func (t *tiered) SetUpdateRefreshesTTL(refresh bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.l1.SetUpdateRefreshesTTL(refresh)
+	t.l2.SetUpdateRefreshesTTL(refresh)
+}
+
+func (t *tiered) RegisterOnEvicted(f func(key, value interface{})) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.l1.RegisterOnEvicted(f)
+}
+
+func (t *tiered) RegisterOnExpired(f func(key, value interface{})) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.l1.RegisterOnExpired(f)
+}
+
+func (t *tiered) Notify(ch chan<- Event, ops ...Op) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.l1.Notify(ch, ops...)
+}
+
+// NotifyFunc relays l1 events to fn, if l1 is a funcNotifier.
+func (t *tiered) NotifyFunc(fn func(Event), ops ...Op) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if n, ok := t.l1.(funcNotifier); ok {
+		n.NotifyFunc(fn, ops...)
+	}
+}
+
+func (t *tiered) NotifyBlocking(ch chan<- Event, ops ...Op) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.l1.NotifyBlocking(ch, ops...)
+}
+
+func (t *tiered) NotifyWithReplay(ch chan<- Event, ops ...Op) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if internal.Wants(ops, Write) {
+		for _, k := range t.l2.Keys() {
+			if info, ok := t.l2.GetEntry(k); ok {
+				ch <- Event{
+					Op:     Write,
+					Key:    info.Key,
+					Value:  info.Value,
+					Expiry: info.Expiry,
+					Cost:   info.Cost,
+				}
+			}
+		}
+	}
+
+	t.l1.NotifyWithReplay(ch, ops...)
+}
+
+func (t *tiered) Subscribe(ops ...Op) (<-chan Event, func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.locked(t.l1.Subscribe(ops...))
+}
+
+func (t *tiered) Watch(key interface{}) (<-chan Event, func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.locked(t.l1.Watch(key))
+}
+
+// locked returns ch along with cancel holding the tiered lock.
+func (t *tiered) locked(ch <-chan Event, cancel func()) (<-chan Event, func()) {
+	return ch, func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		cancel()
+	}
+}
+
+func (t *tiered) Ignore(ch chan<- Event, ops ...Op) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.l1.Ignore(ch, ops...)
+}
+
+func (t *tiered) GC() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	x := t.l1.GC()
+	y := t.l2.GC()
+
+	// return the next nearer gc cycle.
+	if y == 0 || (x != 0 && x < y) {
+		return x
+	}
+	return y
+}
+
+func (t *tiered) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.l1.Ignore(t.evicted)
+	err := t.l1.Close()
+	if err2 := t.l2.Close(); err == nil {
+		err = err2
+	}
+	return err
+}
@@ -0,0 +1,100 @@
+package tinylfu
+
+// sketchDepth is the number of the sketch rows.
+const sketchDepth = 4
+
+// maxCount is the maximum value of a sketch counter.
+const maxCount = 15
+
+var seeds = [sketchDepth]uint64{
+	0xc3a5c85c97cb3127,
+	0xb492b66fbe98f273,
+	0x9ae16a3b2f90404f,
+	0xcbf29ce484222325,
+}
+
+// Sketch is a count-min sketch, that estimates keys access frequency
+// in a fixed amount of memory. The estimate never under counts,
+// but may over count due to hash collisions.
+//
+// Sketch keeps the frequencies fresh by halving all counters,
+// once the number of increments reaches ten times its width,
+// so keys that were popular in the past but no longer accessed age out.
+//
+// Sketch is not safe for concurrent use.
+type Sketch struct {
+	rows      [sketchDepth][]uint8
+	mask      uint64
+	additions int
+	sample    int
+}
+
+// NewSketch returns a new sketch with the given width rounded up to a power of two.
+func NewSketch(width int) *Sketch {
+	w := 16
+	for w < width {
+		w *= 2
+	}
+
+	s := &Sketch{
+		mask:   uint64(w - 1),
+		sample: w * 10,
+	}
+
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, w)
+	}
+
+	return s
+}
+
+func (s *Sketch) index(h uint64, i int) uint64 {
+	h = (h ^ seeds[i]) * seeds[i]
+	h ^= h >> 32
+	return h & s.mask
+}
+
+// Increment increments the frequency of the key hash h.
+func (s *Sketch) Increment(h uint64) {
+	for i := range s.rows {
+		if c := &s.rows[i][s.index(h, i)]; *c < maxCount {
+			*c++
+		}
+	}
+
+	s.additions++
+	if s.additions >= s.sample {
+		s.halve()
+	}
+}
+
+// Estimate returns the estimated frequency of the key hash h.
+func (s *Sketch) Estimate(h uint64) int {
+	min := uint8(maxCount)
+	for i := range s.rows {
+		if c := s.rows[i][s.index(h, i)]; c < min {
+			min = c
+		}
+	}
+	return int(min)
+}
+
+// Reset clears all the sketch counters.
+func (s *Sketch) Reset() {
+	for i := range s.rows {
+		for j := range s.rows[i] {
+			s.rows[i][j] = 0
+		}
+	}
+	s.additions = 0
+}
+
+// halve halves all the sketch counters.
+func (s *Sketch) halve() {
+	for i := range s.rows {
+		for j := range s.rows[i] {
+			s.rows[i][j] /= 2
+		}
+	}
+	s.additions /= 2
+}
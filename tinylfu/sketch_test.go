@@ -0,0 +1,49 @@
+package tinylfu
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSketch(t *testing.T) {
+	s := NewSketch(16)
+
+	for i := 0; i < 5; i++ {
+		s.Increment(1)
+	}
+	s.Increment(2)
+
+	assert.Equal(t, 5, s.Estimate(1))
+	assert.Equal(t, 1, s.Estimate(2))
+	assert.Equal(t, 0, s.Estimate(3))
+
+	s.Reset()
+	assert.Equal(t, 0, s.Estimate(1))
+}
+
+func TestSketchSaturation(t *testing.T) {
+	s := NewSketch(1024)
+
+	for i := 0; i < 100; i++ {
+		s.Increment(1)
+	}
+
+	assert.Equal(t, 15, s.Estimate(1))
+}
+
+func TestSketchHalving(t *testing.T) {
+	s := NewSketch(16)
+
+	for i := 0; i < 8; i++ {
+		s.Increment(1)
+	}
+
+	// 16 * 10 additions trigger the halving,
+	// keep incrementing other keys up to the limit.
+	for i := 8; i < 16*10; i++ {
+		s.Increment(uint64(1000 + i))
+	}
+
+	assert.Less(t, s.Estimate(1), 8)
+}
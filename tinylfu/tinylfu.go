@@ -0,0 +1,262 @@
+// Package tinylfu implements a W-TinyLFU (Window Tiny Least Frequently Used) cache.
+package tinylfu
+
+import (
+	"container/list"
+
+	"github.com/shaj13/libcache"
+	"github.com/shaj13/libcache/internal"
+)
+
+func init() {
+	libcache.WTinyLFU.Register(New)
+}
+
+// New returns a new non-thread safe cache.
+func New(cap int) libcache.Cache {
+	col := newCollection(cap)
+	return cache{internal.New(col, cap)}
+}
+
+// cache is the internal cache, that reports the WTinyLFU replacement policy.
+type cache struct {
+	*internal.Cache
+}
+
+// Policy returns libcache.WTinyLFU.
+func (cache) Policy() libcache.ReplacementPolicy {
+	return libcache.WTinyLFU
+}
+
+// segment identifies the list holding an element.
+type segment uint8
+
+const (
+	removed segment = iota
+	window
+	probation
+	protected
+)
+
+type element struct {
+	value *internal.Entry
+	hash  uint64
+	seg   segment
+	le    *list.Element
+}
+
+// collection is a W-TinyLFU, new entries admitted into a small window LRU,
+// that holds 1% of the capacity. Once the window is full, its least recently used entry
+// is a candidate for the main SLRU, and admitted only if its estimated frequency
+// is greater than the frequency of the main SLRU victim, otherwise, it discarded.
+//
+// The main SLRU split into probation and protected segments, admitted entries
+// start in probation and promoted to protected, that holds 80% of the main capacity,
+// when accessed again, so a burst of one-hit wonders never flushes the frequently used entries.
+//
+// The entries frequency estimated by a count-min sketch,
+// that remembers the keys history even after they discarded.
+type collection struct {
+	sketch       *Sketch
+	windowCap    int
+	protectedCap int
+	window       *list.List
+	probation    *list.List
+	protected    *list.List
+}
+
+func newCollection(cap int) *collection {
+	c := &collection{
+		windowCap: 1,
+		sketch:    NewSketch(4096),
+	}
+
+	if cap > 0 {
+		c.sketch = NewSketch(cap)
+		if w := cap / 100; w > 1 {
+			c.windowCap = w
+		}
+		if p := (cap - c.windowCap) * 80 / 100; p > 1 {
+			c.protectedCap = p
+		} else {
+			c.protectedCap = 1
+		}
+	}
+
+	c.Init()
+	return c
+}
+
+func (c *collection) list(seg segment) *list.List {
+	switch seg {
+	case window:
+		return c.window
+	case probation:
+		return c.probation
+	case protected:
+		return c.protected
+	default:
+		return nil
+	}
+}
+
+// push pushes the element to the front of the given segment.
+func (c *collection) push(ele *element, seg segment) {
+	ele.seg = seg
+	ele.le = c.list(seg).PushFront(ele)
+}
+
+func (c *collection) remove(ele *element) {
+	if l := c.list(ele.seg); l != nil {
+		l.Remove(ele.le)
+	}
+	ele.seg = removed
+	ele.le = nil
+}
+
+func (c *collection) Move(e *internal.Entry) {
+	ele := e.Element.(*element)
+	if ele.seg == removed {
+		return
+	}
+
+	c.sketch.Increment(ele.hash)
+
+	switch ele.seg {
+	case window:
+		c.window.MoveToFront(ele.le)
+	case probation:
+		c.remove(ele)
+		c.push(ele, protected)
+		if c.protectedCap > 0 && c.protected.Len() > c.protectedCap {
+			demoted := c.protected.Back().Value.(*element)
+			c.remove(demoted)
+			c.push(demoted, probation)
+		}
+	case protected:
+		c.protected.MoveToFront(ele.le)
+	}
+}
+
+func (c *collection) Add(e *internal.Entry) {
+	ele := &element{value: e, hash: internal.Hash(e.ID())}
+	e.Element = ele
+	c.sketch.Increment(ele.hash)
+	c.push(ele, window)
+
+	// The cache discards before adding once full,
+	// so the window overflows only while the main SLRU has room.
+	if c.window.Len() > c.windowCap {
+		candidate := c.window.Back().Value.(*element)
+		c.remove(candidate)
+		c.push(candidate, probation)
+	}
+}
+
+func (c *collection) Remove(e *internal.Entry) {
+	c.remove(e.Element.(*element))
+}
+
+// victim returns the main SLRU least recently used element, probation first.
+func (c *collection) victim() *element {
+	if le := c.probation.Back(); le != nil {
+		return le.Value.(*element)
+	}
+
+	if le := c.protected.Back(); le != nil {
+		return le.Value.(*element)
+	}
+
+	return nil
+}
+
+// next returns the element to discard,
+// and the window candidate to admit into the main SLRU if any.
+func (c *collection) next() (discard, admit *element) {
+	var candidate *element
+	if le := c.window.Back(); le != nil {
+		candidate = le.Value.(*element)
+	}
+
+	victim := c.victim()
+
+	switch {
+	case victim == nil:
+		return candidate, nil
+	case candidate == nil || c.window.Len() < c.windowCap:
+		// The window still has room for the new entry.
+		return victim, nil
+	case c.sketch.Estimate(candidate.hash) > c.sketch.Estimate(victim.hash):
+		return victim, candidate
+	default:
+		return candidate, nil
+	}
+}
+
+func (c *collection) Discard() *internal.Entry {
+	discard, admit := c.next()
+	if discard == nil {
+		return nil
+	}
+
+	c.remove(discard)
+
+	if admit != nil {
+		c.remove(admit)
+		c.push(admit, probation)
+	}
+
+	return discard.value
+}
+
+func (c *collection) Front() *internal.Entry {
+	if discard, _ := c.next(); discard != nil {
+		return discard.value
+	}
+	return nil
+}
+
+// Back returns the most recently used entry of the most protected segment.
+func (c *collection) Back() *internal.Entry {
+	for _, l := range []*list.List{c.protected, c.probation, c.window} {
+		if le := l.Front(); le != nil {
+			return le.Value.(*element).value
+		}
+	}
+	return nil
+}
+
+// Walk walks the next entry to discard first, then the window, probation
+// and protected entries in least recently used order.
+func (c *collection) Walk(fn func(*internal.Entry)) {
+	discard, _ := c.next()
+	if discard == nil {
+		return
+	}
+
+	fn(discard.value)
+
+	for _, l := range []*list.List{c.window, c.probation, c.protected} {
+		for le := l.Back(); le != nil; le = le.Prev() {
+			if ele := le.Value.(*element); ele != discard {
+				fn(ele.value)
+			}
+		}
+	}
+}
+
+// Frequency returns the entry estimated access frequency.
+func (c *collection) Frequency(e *internal.Entry) int {
+	return c.sketch.Estimate(internal.Hash(e.ID()))
+}
+
+func (c *collection) Len() int {
+	return c.window.Len() + c.probation.Len() + c.protected.Len()
+}
+
+func (c *collection) Init() {
+	c.window = list.New()
+	c.probation = list.New()
+	c.protected = list.New()
+	c.sketch.Reset()
+}
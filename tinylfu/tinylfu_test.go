@@ -0,0 +1,105 @@
+package tinylfu
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/shaj13/libcache/internal"
+)
+
+func TestCollection(t *testing.T) {
+	entries := []*internal.Entry{}
+	for i := 0; i < 4; i++ {
+		entries = append(entries, &internal.Entry{Key: i})
+	}
+
+	c := newCollection(4)
+
+	for _, e := range entries[:3] {
+		c.Add(e)
+	}
+
+	// 0 and 1 admitted into probation, 2 held by the window.
+	c.Move(entries[0])
+
+	assert.Equal(t, 3, c.Len())
+	assert.Equal(t, 2, c.Front().Key)
+	assert.Equal(t, 0, c.Back().Key)
+	assert.True(t, c.Frequency(entries[0]) >= 2)
+
+	c.Add(entries[3])
+	c.Remove(entries[1])
+
+	assert.Equal(t, 3, c.Len())
+	assert.Equal(t, 3, c.Discard().Key)
+	assert.Equal(t, 2, c.Discard().Key)
+	assert.Equal(t, 0, c.Discard().Key)
+	assert.Nil(t, c.Discard())
+}
+
+func TestOneHitWonder(t *testing.T) {
+	cache := New(4)
+	cache.Store(1, 1)
+	cache.Store(2, 2)
+	cache.Store(3, 3)
+
+	for i := 0; i < 5; i++ {
+		cache.Load(1)
+		cache.Load(2)
+		cache.Load(3)
+	}
+
+	cache.Store(4, 4)
+	cache.Store(5, 5)
+	cache.Store(6, 6)
+
+	// The one-hit wonders fail admission against the frequent victims.
+	assert.True(t, cache.Contains(1))
+	assert.True(t, cache.Contains(2))
+	assert.True(t, cache.Contains(3))
+	assert.False(t, cache.Contains(4))
+	assert.False(t, cache.Contains(5))
+	assert.True(t, cache.Contains(6))
+}
+
+type keyer struct{ id int }
+
+func (k *keyer) CacheKey() interface{} { return k.id }
+
+func TestAdmissionKeyer(t *testing.T) {
+	cache := New(4)
+	for i := 1; i <= 4; i++ {
+		cache.Store(&keyer{i}, i)
+	}
+
+	// Each store by a new key of the same identity.
+	for i := 0; i < 5; i++ {
+		cache.Store(&keyer{5}, 5)
+	}
+
+	cache.Store(&keyer{6}, 6)
+
+	// The frequency counted by the key identity, so 5 is more frequent than the victim.
+	assert.True(t, cache.Contains(&keyer{5}))
+	assert.True(t, cache.Contains(&keyer{6}))
+}
+
+func TestAdmission(t *testing.T) {
+	cache := New(4)
+	cache.Store(1, 1)
+	cache.Store(2, 2)
+	cache.Store(3, 3)
+	cache.Store(4, 4)
+
+	for i := 0; i < 5; i++ {
+		cache.Load(4)
+	}
+
+	cache.Store(5, 5)
+
+	// 4 is more frequent than the victim, so admitted.
+	assert.True(t, cache.Contains(4))
+	assert.False(t, cache.Contains(1))
+	assert.True(t, cache.Contains(5))
+}
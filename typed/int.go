@@ -0,0 +1,206 @@
+package typed
+
+import (
+	"sync"
+
+	"github.com/shaj13/libcache"
+)
+
+// IntCache mirrors libcache.Cache for int keys and values.
+type IntCache interface {
+	// Load returns key value.
+	Load(key int) (int, bool)
+	// Peek returns key value without updating the underlying "recent-ness".
+	Peek(key int) (int, bool)
+	// Update the key value without updating the underlying "recent-ness".
+	Update(key, value int)
+	// Store sets the key value.
+	Store(key, value int)
+	// Delete deletes the key value.
+	Delete(key int)
+	// Contains Checks if a key exists in cache.
+	Contains(key int) bool
+	// Keys return cache records keys, from the eviction candidate to the newest key.
+	Keys() []int
+	// PeekOldest returns the key and value of the eviction candidate,
+	// without updating the underlying "recent-ness".
+	PeekOldest() (key, value int, ok bool)
+	// Purge Clears all cache entries.
+	Purge()
+	// Resize cache, returning number evicted.
+	// Zero size means unbounded, and never evicts,
+	// and negative size clamped to zero.
+	Resize(int) int
+	// Len Returns the number of items in the cache.
+	Len() int
+	// Cap Returns the cache capacity.
+	Cap() int
+}
+
+// NewIntCache returns a new thread safe int cache,
+// replacing its entries by the given replacement policy, either LRU or FIFO.
+// Zero capacity means unbounded, and negative capacity clamped to zero.
+// NewIntCache panics if the replacement policy is not supported.
+func NewIntCache(p libcache.ReplacementPolicy, cap int) IntCache {
+	if cap < 0 {
+		cap = 0
+	}
+
+	return &intCache{
+		touch:  touchOnLoad(p),
+		cap:    cap,
+		order:  newOrder(),
+		slots:  make(map[int]int),
+		keys:   make([]int, 1),
+		values: make([]int, 1),
+	}
+}
+
+type intCache struct {
+	// mu guards the cache, calls to mu.Unlock are not deferred, as in libcache.
+	mu    sync.Mutex
+	touch bool
+	cap   int
+	order order
+	// slots maps keys to their order slot,
+	// the keys and values stored at the slot index.
+	slots  map[int]int
+	keys   []int
+	values []int
+}
+
+func (c *intCache) Load(key int) (int, bool) {
+	c.mu.Lock()
+	slot, ok := c.slots[key]
+	if ok && c.touch {
+		c.order.touch(slot)
+	}
+	v := c.values[slot]
+	c.mu.Unlock()
+	return v, ok
+}
+
+func (c *intCache) Peek(key int) (int, bool) {
+	c.mu.Lock()
+	slot, ok := c.slots[key]
+	v := c.values[slot]
+	c.mu.Unlock()
+	return v, ok
+}
+
+func (c *intCache) Update(key, value int) {
+	c.mu.Lock()
+	if slot, ok := c.slots[key]; ok {
+		c.values[slot] = value
+	}
+	c.mu.Unlock()
+}
+
+func (c *intCache) Store(key, value int) {
+	c.mu.Lock()
+	c.store(key, value)
+	c.mu.Unlock()
+}
+
+func (c *intCache) store(key, value int) {
+	if slot, ok := c.slots[key]; ok {
+		c.values[slot] = value
+		if c.touch {
+			c.order.touch(slot)
+		}
+		return
+	}
+
+	if c.cap > 0 && len(c.slots) >= c.cap {
+		c.evict(c.order.oldest())
+	}
+
+	slot := c.order.alloc()
+	if slot == len(c.keys) {
+		c.keys = append(c.keys, key)
+		c.values = append(c.values, value)
+	} else {
+		c.keys[slot], c.values[slot] = key, value
+	}
+
+	c.order.push(slot)
+	c.slots[key] = slot
+}
+
+func (c *intCache) evict(slot int) {
+	delete(c.slots, c.keys[slot])
+	c.order.release(slot)
+	c.keys[slot], c.values[slot] = 0, 0
+}
+
+func (c *intCache) Delete(key int) {
+	c.mu.Lock()
+	if slot, ok := c.slots[key]; ok {
+		c.evict(slot)
+	}
+	c.mu.Unlock()
+}
+
+func (c *intCache) Contains(key int) bool {
+	c.mu.Lock()
+	_, ok := c.slots[key]
+	c.mu.Unlock()
+	return ok
+}
+
+func (c *intCache) Keys() []int {
+	c.mu.Lock()
+	keys := make([]int, 0, len(c.slots))
+	c.order.walk(func(slot int) {
+		keys = append(keys, c.keys[slot])
+	})
+	c.mu.Unlock()
+	return keys
+}
+
+func (c *intCache) PeekOldest() (key, value int, ok bool) {
+	c.mu.Lock()
+	slot := c.order.oldest()
+	key, value, ok = c.keys[slot], c.values[slot], slot != 0
+	c.mu.Unlock()
+	return
+}
+
+func (c *intCache) Purge() {
+	c.mu.Lock()
+	c.slots = make(map[int]int)
+	c.keys = make([]int, 1)
+	c.values = make([]int, 1)
+	c.order.reset()
+	c.mu.Unlock()
+}
+
+func (c *intCache) Resize(size int) int {
+	if size < 0 {
+		size = 0
+	}
+
+	c.mu.Lock()
+	c.cap = size
+	evicted := 0
+	for size > 0 && len(c.slots) > size {
+		c.evict(c.order.oldest())
+		evicted++
+	}
+	c.mu.Unlock()
+	return evicted
+}
+
+func (c *intCache) Len() int {
+	c.mu.Lock()
+	n := len(c.slots)
+	c.mu.Unlock()
+	return n
+}
+
+func (c *intCache) Cap() int {
+	c.mu.Lock()
+	n := c.cap
+	c.mu.Unlock()
+	return n
+}
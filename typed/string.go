@@ -0,0 +1,206 @@
+package typed
+
+import (
+	"sync"
+
+	"github.com/shaj13/libcache"
+)
+
+// StringKeyCache mirrors libcache.Cache for string keys and int values.
+type StringKeyCache interface {
+	// Load returns key value.
+	Load(key string) (int, bool)
+	// Peek returns key value without updating the underlying "recent-ness".
+	Peek(key string) (int, bool)
+	// Update the key value without updating the underlying "recent-ness".
+	Update(key string, value int)
+	// Store sets the key value.
+	Store(key string, value int)
+	// Delete deletes the key value.
+	Delete(key string)
+	// Contains Checks if a key exists in cache.
+	Contains(key string) bool
+	// Keys return cache records keys, from the eviction candidate to the newest key.
+	Keys() []string
+	// PeekOldest returns the key and value of the eviction candidate,
+	// without updating the underlying "recent-ness".
+	PeekOldest() (key string, value int, ok bool)
+	// Purge Clears all cache entries.
+	Purge()
+	// Resize cache, returning number evicted.
+	// Zero size means unbounded, and never evicts,
+	// and negative size clamped to zero.
+	Resize(int) int
+	// Len Returns the number of items in the cache.
+	Len() int
+	// Cap Returns the cache capacity.
+	Cap() int
+}
+
+// NewStringKeyCache returns a new thread safe string key cache,
+// replacing its entries by the given replacement policy, either LRU or FIFO.
+// Zero capacity means unbounded, and negative capacity clamped to zero.
+// NewStringKeyCache panics if the replacement policy is not supported.
+func NewStringKeyCache(p libcache.ReplacementPolicy, cap int) StringKeyCache {
+	if cap < 0 {
+		cap = 0
+	}
+
+	return &stringKeyCache{
+		touch:  touchOnLoad(p),
+		cap:    cap,
+		order:  newOrder(),
+		slots:  make(map[string]int),
+		keys:   make([]string, 1),
+		values: make([]int, 1),
+	}
+}
+
+type stringKeyCache struct {
+	// mu guards the cache, calls to mu.Unlock are not deferred, as in libcache.
+	mu    sync.Mutex
+	touch bool
+	cap   int
+	order order
+	// slots maps keys to their order slot,
+	// the keys and values stored at the slot index.
+	slots  map[string]int
+	keys   []string
+	values []int
+}
+
+func (c *stringKeyCache) Load(key string) (int, bool) {
+	c.mu.Lock()
+	slot, ok := c.slots[key]
+	if ok && c.touch {
+		c.order.touch(slot)
+	}
+	v := c.values[slot]
+	c.mu.Unlock()
+	return v, ok
+}
+
+func (c *stringKeyCache) Peek(key string) (int, bool) {
+	c.mu.Lock()
+	slot, ok := c.slots[key]
+	v := c.values[slot]
+	c.mu.Unlock()
+	return v, ok
+}
+
+func (c *stringKeyCache) Update(key string, value int) {
+	c.mu.Lock()
+	if slot, ok := c.slots[key]; ok {
+		c.values[slot] = value
+	}
+	c.mu.Unlock()
+}
+
+func (c *stringKeyCache) Store(key string, value int) {
+	c.mu.Lock()
+	c.store(key, value)
+	c.mu.Unlock()
+}
+
+func (c *stringKeyCache) store(key string, value int) {
+	if slot, ok := c.slots[key]; ok {
+		c.values[slot] = value
+		if c.touch {
+			c.order.touch(slot)
+		}
+		return
+	}
+
+	if c.cap > 0 && len(c.slots) >= c.cap {
+		c.evict(c.order.oldest())
+	}
+
+	slot := c.order.alloc()
+	if slot == len(c.keys) {
+		c.keys = append(c.keys, key)
+		c.values = append(c.values, value)
+	} else {
+		c.keys[slot], c.values[slot] = key, value
+	}
+
+	c.order.push(slot)
+	c.slots[key] = slot
+}
+
+func (c *stringKeyCache) evict(slot int) {
+	delete(c.slots, c.keys[slot])
+	c.order.release(slot)
+	c.keys[slot], c.values[slot] = "", 0
+}
+
+func (c *stringKeyCache) Delete(key string) {
+	c.mu.Lock()
+	if slot, ok := c.slots[key]; ok {
+		c.evict(slot)
+	}
+	c.mu.Unlock()
+}
+
+func (c *stringKeyCache) Contains(key string) bool {
+	c.mu.Lock()
+	_, ok := c.slots[key]
+	c.mu.Unlock()
+	return ok
+}
+
+func (c *stringKeyCache) Keys() []string {
+	c.mu.Lock()
+	keys := make([]string, 0, len(c.slots))
+	c.order.walk(func(slot int) {
+		keys = append(keys, c.keys[slot])
+	})
+	c.mu.Unlock()
+	return keys
+}
+
+func (c *stringKeyCache) PeekOldest() (key string, value int, ok bool) {
+	c.mu.Lock()
+	slot := c.order.oldest()
+	key, value, ok = c.keys[slot], c.values[slot], slot != 0
+	c.mu.Unlock()
+	return
+}
+
+func (c *stringKeyCache) Purge() {
+	c.mu.Lock()
+	c.slots = make(map[string]int)
+	c.keys = make([]string, 1)
+	c.values = make([]int, 1)
+	c.order.reset()
+	c.mu.Unlock()
+}
+
+func (c *stringKeyCache) Resize(size int) int {
+	if size < 0 {
+		size = 0
+	}
+
+	c.mu.Lock()
+	c.cap = size
+	evicted := 0
+	for size > 0 && len(c.slots) > size {
+		c.evict(c.order.oldest())
+		evicted++
+	}
+	c.mu.Unlock()
+	return evicted
+}
+
+func (c *stringKeyCache) Len() int {
+	c.mu.Lock()
+	n := len(c.slots)
+	c.mu.Unlock()
+	return n
+}
+
+func (c *stringKeyCache) Cap() int {
+	c.mu.Lock()
+	n := c.cap
+	c.mu.Unlock()
+	return n
+}
@@ -0,0 +1,98 @@
+// Package typed implements caches specialized for int values,
+// keyed by int or string keys, that store the keys and values unboxed,
+// avoiding the interface{} allocations of the generic libcache caches on the hot path.
+//
+// The typed caches support the LRU and FIFO replacement policies,
+// and trade the generic cache features, e.g. TTL and events, for the lower memory and GC overhead.
+package typed
+
+import (
+	"github.com/shaj13/libcache"
+)
+
+// order tracks the entries replacement order, as a doubly linked list of slots
+// backed by a slice, so it never allocates per entry once grown.
+// The slot zero is the list sentinel, its next slot is the eviction candidate,
+// and its prev slot is the newest entry.
+type order struct {
+	links []link
+	free  []int
+}
+
+type link struct {
+	prev, next int
+}
+
+func newOrder() order {
+	return order{links: make([]link, 1)}
+}
+
+// alloc returns a detached slot, reusing a released slot if any.
+func (o *order) alloc() int {
+	if n := len(o.free); n > 0 {
+		slot := o.free[n-1]
+		o.free = o.free[:n-1]
+		return slot
+	}
+
+	o.links = append(o.links, link{})
+	return len(o.links) - 1
+}
+
+// push links the slot as the newest entry.
+func (o *order) push(slot int) {
+	prev := o.links[0].prev
+	o.links[slot] = link{prev: prev, next: 0}
+	o.links[prev].next = slot
+	o.links[0].prev = slot
+}
+
+func (o *order) unlink(slot int) {
+	l := o.links[slot]
+	o.links[l.prev].next = l.next
+	o.links[l.next].prev = l.prev
+}
+
+// touch moves the slot to the newest entry.
+func (o *order) touch(slot int) {
+	o.unlink(slot)
+	o.push(slot)
+}
+
+// release unlinks the slot and keeps it for reuse.
+func (o *order) release(slot int) {
+	o.unlink(slot)
+	o.free = append(o.free, slot)
+}
+
+// oldest returns the eviction candidate slot, zero if empty.
+func (o *order) oldest() int {
+	return o.links[0].next
+}
+
+// walk calls fn for each slot from the oldest to the newest entry.
+func (o *order) walk(fn func(slot int)) {
+	for slot := o.links[0].next; slot != 0; slot = o.links[slot].next {
+		fn(slot)
+	}
+}
+
+func (o *order) reset() {
+	o.links = o.links[:1]
+	o.links[0] = link{}
+	o.free = o.free[:0]
+}
+
+// touchOnLoad reports whether loads update the entries "recent-ness",
+// under the given replacement policy,
+// it panics if the policy is not supported by the typed caches.
+func touchOnLoad(p libcache.ReplacementPolicy) bool {
+	switch p {
+	case libcache.LRU:
+		return true
+	case libcache.FIFO:
+		return false
+	default:
+		panic("typed: unsupported cache replacement policy " + p.String())
+	}
+}
@@ -0,0 +1,134 @@
+package typed_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/shaj13/libcache"
+	_ "github.com/shaj13/libcache/fifo"
+	_ "github.com/shaj13/libcache/lru"
+	"github.com/shaj13/libcache/typed"
+)
+
+func TestIntCache(t *testing.T) {
+	table := []struct {
+		cont libcache.ReplacementPolicy
+		keys []int
+	}{
+		{cont: libcache.LRU, keys: []int{3, 1, 4}},
+		{cont: libcache.FIFO, keys: []int{2, 3, 4}},
+	}
+
+	for _, tt := range table {
+		t.Run("Test"+tt.cont.String()+"IntCache", func(t *testing.T) {
+			cache := typed.NewIntCache(tt.cont, 3)
+			cache.Store(1, 10)
+			cache.Store(2, 20)
+			cache.Store(3, 30)
+
+			v, ok := cache.Load(1)
+			assert.True(t, ok)
+			assert.Equal(t, 10, v)
+
+			cache.Store(4, 40)
+			assert.Equal(t, tt.keys, cache.Keys())
+			assert.Equal(t, 3, cache.Len())
+
+			key, _, ok := cache.PeekOldest()
+			assert.True(t, ok)
+			assert.Equal(t, tt.keys[0], key)
+
+			cache.Update(4, 41)
+			v, _ = cache.Peek(4)
+			assert.Equal(t, 41, v)
+			assert.Equal(t, tt.keys, cache.Keys())
+
+			cache.Delete(4)
+			assert.False(t, cache.Contains(4))
+			assert.Equal(t, 1, cache.Resize(1))
+			assert.Equal(t, tt.keys[1:2], cache.Keys())
+
+			cache.Purge()
+			_, ok = cache.Load(tt.keys[1])
+			assert.False(t, ok)
+			assert.Equal(t, 0, cache.Len())
+			_, _, ok = cache.PeekOldest()
+			assert.False(t, ok)
+		})
+	}
+}
+
+func TestStringKeyCache(t *testing.T) {
+	cache := typed.NewStringKeyCache(libcache.LRU, 2)
+	cache.Store("a", 1)
+	cache.Store("b", 2)
+	cache.Load("a")
+	cache.Store("c", 3)
+
+	assert.Equal(t, []string{"a", "c"}, cache.Keys())
+	assert.False(t, cache.Contains("b"))
+
+	v, ok := cache.Load("c")
+	assert.True(t, ok)
+	assert.Equal(t, 3, v)
+}
+
+func TestNewIntCacheUnsupportedPolicy(t *testing.T) {
+	assert.Panics(t, func() {
+		typed.NewIntCache(libcache.LFU, 1)
+	})
+}
+
+func BenchmarkIntCache(b *testing.B) {
+	const cap = 1000
+
+	b.Run("Generic", func(b *testing.B) {
+		cache := libcache.LRU.New(cap)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			// Keys and values beyond 255 are boxed on the heap.
+			cache.Store(i+256, i+256)
+			cache.Load(i + 256)
+		}
+	})
+
+	b.Run("Typed", func(b *testing.B) {
+		cache := typed.NewIntCache(libcache.LRU, cap)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			cache.Store(i+256, i+256)
+			cache.Load(i + 256)
+		}
+	})
+}
+
+func BenchmarkStringKeyCache(b *testing.B) {
+	const cap = 1000
+
+	keys := make([]string, cap*2)
+	for i := range keys {
+		keys[i] = strconv.Itoa(i)
+	}
+
+	b.Run("Generic", func(b *testing.B) {
+		cache := libcache.LRU.New(cap)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			key := keys[i%len(keys)]
+			cache.Store(key, i)
+			cache.Load(key)
+		}
+	})
+
+	b.Run("Typed", func(b *testing.B) {
+		cache := typed.NewStringKeyCache(libcache.LRU, cap)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			key := keys[i%len(keys)]
+			cache.Store(key, i)
+			cache.Load(key)
+		}
+	})
+}
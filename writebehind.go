@@ -0,0 +1,384 @@
+package libcache
+
+import (
+	"sync"
+	"time"
+
+	"github.com/shaj13/libcache/internal"
+)
+
+// WriteBehind returns a cache that delegates to c, and lazily writes its stored
+// and updated values to a backing store, by calling flush asynchronously
+// with the dirty keys and their latest value.
+//
+// The dirty keys flushed every interval, and once their number reaches batch,
+// repeated writes of a key coalesced into a single flush of its latest value.
+// Zero interval means flush on batch only, and zero batch means flush on interval only.
+//
+// Every write marks its key dirty: the Store variants, StoreMany, Update,
+// CompareAndSwap, Increment, Decrement and Merge. The values the cache loads,
+// GetOrCompute and LoadCtx, and populates, Warm and WarmWithTTL, are never flushed,
+// as they come from the backing store.
+//
+// Deleting a key before it flushed drops its pending write, as the backing store
+// is never told about deletes, so do Delete, DeleteMany, GetAndDelete, CompareAndDelete,
+// DeleteWithPrefix, InvalidateTag, DeleteFunc, StoreNegative, Purge and Reset.
+// Evicted and expired keys are still flushed.
+// If flush fails, the batch keys that were not written again since are retried by the next flush.
+//
+// The writes and deletes apply to c and the dirty keys under a single lock,
+// so racing operations leave both in the same order. Calls made on c directly bypass it.
+//
+// Close stops the background flusher, flushes the remaining dirty keys,
+// and then closes c. It returns the flush error if any.
+func WriteBehind(
+	c Cache,
+	flush func(map[interface{}]interface{}) error,
+	interval time.Duration,
+	batch int,
+) Cache {
+	w := &writeBehind{
+		Cache: c,
+		flush: flush,
+		batch: batch,
+		dirty: make(map[interface{}]interface{}),
+		kick:  make(chan struct{}, 1),
+		done:  make(chan struct{}),
+		exit:  make(chan struct{}),
+	}
+
+	go w.run(interval)
+
+	return w
+}
+
+type writeBehind struct {
+	Cache
+	flush func(map[interface{}]interface{}) error
+	batch int
+	// mu serializes the writes and deletes with the dirty keys they track, and guards dirty.
+	mu    sync.Mutex
+	dirty map[interface{}]interface{}
+	// flushing serializes the flush calls, so writes flushed in order.
+	flushing sync.Mutex
+	kick     chan struct{}
+	done     chan struct{}
+	exit     chan struct{}
+	once     sync.Once
+}
+
+func (w *writeBehind) run(interval time.Duration) {
+	defer close(w.exit)
+
+	var tick <-chan time.Time
+	if interval > 0 {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		tick = t.C
+	}
+
+	for {
+		select {
+		case <-tick:
+		case <-w.kick:
+		case <-w.done:
+			return
+		}
+
+		_ = w.flushDirty()
+	}
+}
+
+// flushDirty flushes the dirty keys, and keeps the failed ones dirty
+// unless they were written again.
+func (w *writeBehind) flushDirty() error {
+	w.flushing.Lock()
+	defer w.flushing.Unlock()
+
+	w.mu.Lock()
+	items := w.dirty
+	w.dirty = make(map[interface{}]interface{})
+	w.mu.Unlock()
+
+	if len(items) == 0 {
+		return nil
+	}
+
+	err := w.flush(items)
+	if err != nil {
+		w.mu.Lock()
+		for k, v := range items {
+			if _, ok := w.dirty[k]; !ok {
+				w.dirty[k] = v
+			}
+		}
+		w.mu.Unlock()
+	}
+
+	return err
+}
+
+// mutate calls fn while holding w.mu, so fn applies its operation to the cache
+// and to the dirty keys at once, and then kicks the flusher if the batch is full.
+func (w *writeBehind) mutate(fn func()) {
+	w.mu.Lock()
+	fn()
+	full := w.batch > 0 && len(w.dirty) >= w.batch
+	w.mu.Unlock()
+
+	if full {
+		select {
+		case w.kick <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// dropRemoved calls op, and drops the pending writes of the live keys op removed,
+// while keeping the already expired ones, as expired keys are still flushed.
+// It must be called while holding w.mu.
+func (w *writeBehind) dropRemoved(op func()) {
+	live := []interface{}{}
+	for k := range w.dirty {
+		if w.Cache.ContainsNoGC(k) {
+			live = append(live, k)
+		}
+	}
+
+	op()
+
+	for _, k := range live {
+		if !w.Cache.ContainsNoGC(k) {
+			delete(w.dirty, k)
+		}
+	}
+}
+
+func (w *writeBehind) Store(key, value interface{}) {
+	w.mutate(func() {
+		w.Cache.Store(key, value)
+		w.dirty[key] = value
+	})
+}
+
+func (w *writeBehind) StoreWithTTL(key, value interface{}, ttl time.Duration) {
+	w.mutate(func() {
+		w.Cache.StoreWithTTL(key, value, ttl)
+		w.dirty[key] = value
+	})
+}
+
+func (w *writeBehind) StoreWithTTLJitter(key, value interface{}, ttl, jitter time.Duration) {
+	w.mutate(func() {
+		w.Cache.StoreWithTTLJitter(key, value, ttl, jitter)
+		w.dirty[key] = value
+	})
+}
+
+func (w *writeBehind) StoreWithDeadline(key, value interface{}, deadline time.Time) {
+	w.mutate(func() {
+		w.Cache.StoreWithDeadline(key, value, deadline)
+		w.dirty[key] = value
+	})
+}
+
+func (w *writeBehind) StoreWithTags(key, value interface{}, tags ...string) {
+	w.mutate(func() {
+		w.Cache.StoreWithTags(key, value, tags...)
+		w.dirty[key] = value
+	})
+}
+
+func (w *writeBehind) StoreEvicting(key, value interface{}) (ek, ev interface{}, evicted bool) {
+	w.mutate(func() {
+		ek, ev, evicted = w.Cache.StoreEvicting(key, value)
+		w.dirty[key] = value
+	})
+	return
+}
+
+func (w *writeBehind) StoreWithTTLEvicting(key, value interface{}, ttl time.Duration) (ek, ev interface{}, evicted bool) {
+	w.mutate(func() {
+		ek, ev, evicted = w.Cache.StoreWithTTLEvicting(key, value, ttl)
+		w.dirty[key] = value
+	})
+	return
+}
+
+func (w *writeBehind) StoreWithDeadlineEvicting(key, value interface{}, deadline time.Time) (ek, ev interface{}, evicted bool) {
+	w.mutate(func() {
+		ek, ev, evicted = w.Cache.StoreWithDeadlineEvicting(key, value, deadline)
+		w.dirty[key] = value
+	})
+	return
+}
+
+func (w *writeBehind) StoreMany(items map[interface{}]interface{}) {
+	w.mutate(func() {
+		w.Cache.StoreMany(items)
+		for k, v := range items {
+			w.dirty[k] = v
+		}
+	})
+}
+
+// Update marks the key dirty only if it exist, as Update never stores missing keys.
+func (w *writeBehind) Update(key, value interface{}) {
+	w.mutate(func() {
+		if w.Cache.Contains(key) {
+			w.Cache.Update(key, value)
+			w.dirty[key] = value
+		}
+	})
+}
+
+func (w *writeBehind) CompareAndSwap(key, old, new interface{}) (swapped bool) {
+	w.mutate(func() {
+		if swapped = w.Cache.CompareAndSwap(key, old, new); swapped {
+			w.dirty[key] = new
+		}
+	})
+	return
+}
+
+func (w *writeBehind) Increment(key interface{}, delta int64) (n int64, err error) {
+	w.mutate(func() {
+		if n, err = w.Cache.Increment(key, delta); err == nil {
+			w.dirty[key] = n
+		}
+	})
+	return
+}
+
+func (w *writeBehind) Decrement(key interface{}, delta int64) (n int64, err error) {
+	w.mutate(func() {
+		if n, err = w.Cache.Decrement(key, delta); err == nil {
+			w.dirty[key] = n
+		}
+	})
+	return
+}
+
+// Merge snapshots the other cache entries before merging them,
+// so the other cache may be the write behind cache itself.
+func (w *writeBehind) Merge(other EntrySource, onConflict func(existing, incoming interface{}) interface{}) {
+	entries := internal.LiveEntries(other)
+	w.mutate(func() {
+		internal.MergeEntries(dirtyMerge{w}, entries, onConflict)
+	})
+}
+
+// dirtyMerge is the Merge target of a write behind cache,
+// marking the merged keys dirty with their resolved value, while w.mu held.
+type dirtyMerge struct {
+	w *writeBehind
+}
+
+func (d dirtyMerge) Peek(key interface{}) (interface{}, bool) {
+	return d.w.Cache.Peek(key)
+}
+
+func (d dirtyMerge) StoreWithDeadline(key, value interface{}, deadline time.Time) {
+	d.w.Cache.StoreWithDeadline(key, value, deadline)
+	d.w.dirty[key] = value
+}
+
+func (w *writeBehind) Delete(key interface{}) {
+	w.mutate(func() {
+		w.Cache.Delete(key)
+		delete(w.dirty, key)
+	})
+}
+
+func (w *writeBehind) DeleteMany(keys ...interface{}) {
+	w.mutate(func() {
+		w.Cache.DeleteMany(keys...)
+		for _, k := range keys {
+			delete(w.dirty, k)
+		}
+	})
+}
+
+func (w *writeBehind) GetAndDelete(key interface{}) (v interface{}, ok bool) {
+	w.mutate(func() {
+		v, ok = w.Cache.GetAndDelete(key)
+		delete(w.dirty, key)
+	})
+	return
+}
+
+func (w *writeBehind) CompareAndDelete(key, old interface{}) (deleted bool) {
+	w.mutate(func() {
+		if deleted = w.Cache.CompareAndDelete(key, old); deleted {
+			delete(w.dirty, key)
+		}
+	})
+	return
+}
+
+func (w *writeBehind) StoreNegative(key interface{}, ttl time.Duration) {
+	w.mutate(func() {
+		w.Cache.StoreNegative(key, ttl)
+		delete(w.dirty, key)
+	})
+}
+
+func (w *writeBehind) DeleteWithPrefix(prefix string) (n int) {
+	w.mutate(func() {
+		w.dropRemoved(func() {
+			n = w.Cache.DeleteWithPrefix(prefix)
+		})
+	})
+	return
+}
+
+func (w *writeBehind) InvalidateTag(tag string) (n int) {
+	w.mutate(func() {
+		w.dropRemoved(func() {
+			n = w.Cache.InvalidateTag(tag)
+		})
+	})
+	return
+}
+
+func (w *writeBehind) DeleteFunc(pred func(key, value interface{}) bool) (n int) {
+	w.mutate(func() {
+		n = w.Cache.DeleteFunc(func(key, value interface{}) bool {
+			if pred(key, value) {
+				delete(w.dirty, key)
+				return true
+			}
+			return false
+		})
+	})
+	return
+}
+
+func (w *writeBehind) Purge() {
+	w.mutate(func() {
+		w.Cache.Purge()
+		w.dirty = make(map[interface{}]interface{})
+	})
+}
+
+func (w *writeBehind) Reset() {
+	w.mutate(func() {
+		w.Cache.Reset()
+		w.dirty = make(map[interface{}]interface{})
+	})
+}
+
+func (w *writeBehind) Close() error {
+	var err error
+
+	w.once.Do(func() {
+		close(w.done)
+		<-w.exit
+		err = w.flushDirty()
+		if cerr := w.Cache.Close(); err == nil {
+			err = cerr
+		}
+	})
+
+	return err
+}
@@ -0,0 +1,320 @@
+package libcache
+
+import (
+	"sync"
+	"time"
+
+	"github.com/shaj13/libcache/internal"
+)
+
+// WriteThroughCache is a Cache that synchronously persists its writes
+// to a backing store before applying them, so the cache never holds a value
+// the store does not. The E suffixed methods return the backing store error,
+// while their Cache counterparts drop it.
+type WriteThroughCache interface {
+	Cache
+	// StoreE writes the key value to the backing store,
+	// and stores it in the cache only if the write succeeded.
+	StoreE(key, value interface{}) error
+	// StoreWithTTLE writes the key value to the backing store,
+	// and stores it in the cache with the given TTL only if the write succeeded.
+	StoreWithTTLE(key, value interface{}, ttl time.Duration) error
+	// UpdateE writes the key value to the backing store,
+	// and updates it in the cache only if the write succeeded.
+	UpdateE(key, value interface{}) error
+	// DeleteE deletes the key from the backing store,
+	// and deletes it from the cache only if the delete succeeded.
+	DeleteE(key interface{}) error
+}
+
+// WriteThrough returns a cache that delegates to c, and calls writer before
+// the Store variants, StoreMany, Update, CompareAndSwap, Increment, Decrement and Merge,
+// with the value they store, and deleter before Delete, DeleteMany, GetAndDelete,
+// CompareAndDelete, DeleteWithPrefix and DeleteFunc, for each key they delete.
+// The cache left unchanged for a key if writer or deleter fails.
+// A nil deleter means deletes applied to the cache only.
+//
+// Write through operations are serialized, so the cache and the backing store
+// apply them in the same order. The other operations are delegated to c
+// without calling writer or deleter, notably:
+//   - GetOrCompute and LoadCtx, as their values loaded from the backing store.
+//   - Warm and WarmWithTTL, as they populate the cache from the backing store.
+//   - StoreNegative, as it caches the key absence from the backing store.
+//   - InvalidateTag, as the tagged keys are unknown until deleted, and Purge and Reset,
+//     as they clear the cache and not the backing store.
+//   - Touch, SetExpiry, RefreshAllTTL and ClearTTL, as they change the expiry only.
+//
+// Calls made on c directly bypass the writer and deleter as well.
+func WriteThrough(
+	c Cache,
+	writer func(key, value interface{}) error,
+	deleter func(key interface{}) error,
+) WriteThroughCache {
+	return &writeThrough{Cache: c, writer: writer, deleter: deleter}
+}
+
+type writeThrough struct {
+	Cache
+	// mu serializes the write through operations.
+	mu      sync.Mutex
+	writer  func(key, value interface{}) error
+	deleter func(key interface{}) error
+}
+
+// write calls writer and then apply if writer succeeded.
+func (w *writeThrough) write(key, value interface{}, apply func()) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.writer(key, value); err != nil {
+		return err
+	}
+
+	apply()
+	return nil
+}
+
+func (w *writeThrough) StoreE(key, value interface{}) error {
+	return w.write(key, value, func() {
+		w.Cache.Store(key, value)
+	})
+}
+
+func (w *writeThrough) StoreWithTTLE(key, value interface{}, ttl time.Duration) error {
+	return w.write(key, value, func() {
+		w.Cache.StoreWithTTL(key, value, ttl)
+	})
+}
+
+func (w *writeThrough) UpdateE(key, value interface{}) error {
+	return w.write(key, value, func() {
+		w.Cache.Update(key, value)
+	})
+}
+
+func (w *writeThrough) DeleteE(key interface{}) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.remove(key); err != nil {
+		return err
+	}
+
+	w.Cache.Delete(key)
+	return nil
+}
+
+// remove calls deleter if any, while holding w.mu.
+func (w *writeThrough) remove(key interface{}) error {
+	if w.deleter == nil {
+		return nil
+	}
+	return w.deleter(key)
+}
+
+func (w *writeThrough) Store(key, value interface{}) {
+	_ = w.StoreE(key, value)
+}
+
+func (w *writeThrough) StoreWithTTL(key, value interface{}, ttl time.Duration) {
+	_ = w.StoreWithTTLE(key, value, ttl)
+}
+
+func (w *writeThrough) StoreWithTTLJitter(key, value interface{}, ttl, jitter time.Duration) {
+	_ = w.write(key, value, func() {
+		w.Cache.StoreWithTTLJitter(key, value, ttl, jitter)
+	})
+}
+
+func (w *writeThrough) StoreWithDeadline(key, value interface{}, deadline time.Time) {
+	_ = w.write(key, value, func() {
+		w.Cache.StoreWithDeadline(key, value, deadline)
+	})
+}
+
+func (w *writeThrough) StoreWithTags(key, value interface{}, tags ...string) {
+	_ = w.write(key, value, func() {
+		w.Cache.StoreWithTags(key, value, tags...)
+	})
+}
+
+func (w *writeThrough) StoreEvicting(key, value interface{}) (ek, ev interface{}, evicted bool) {
+	_ = w.write(key, value, func() {
+		ek, ev, evicted = w.Cache.StoreEvicting(key, value)
+	})
+	return
+}
+
+func (w *writeThrough) StoreWithTTLEvicting(key, value interface{}, ttl time.Duration) (ek, ev interface{}, evicted bool) {
+	_ = w.write(key, value, func() {
+		ek, ev, evicted = w.Cache.StoreWithTTLEvicting(key, value, ttl)
+	})
+	return
+}
+
+func (w *writeThrough) StoreWithDeadlineEvicting(key, value interface{}, deadline time.Time) (ek, ev interface{}, evicted bool) {
+	_ = w.write(key, value, func() {
+		ek, ev, evicted = w.Cache.StoreWithDeadlineEvicting(key, value, deadline)
+	})
+	return
+}
+
+// CompareAndSwap writes the new value only if the current value equal to old.
+func (w *writeThrough) CompareAndSwap(key, old, new interface{}) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if v, ok := w.Cache.Peek(key); !ok || !internal.Equal(v, old) {
+		return false
+	}
+
+	if err := w.writer(key, new); err != nil {
+		return false
+	}
+
+	return w.Cache.CompareAndSwap(key, old, new)
+}
+
+// Increment writes the incremented value, and returns the writer error if any.
+func (w *writeThrough) Increment(key interface{}, delta int64) (int64, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	n := delta
+	if v, ok := w.Cache.Peek(key); ok {
+		i, ok := v.(int64)
+		if !ok {
+			return 0, ErrNotInt64
+		}
+		n += i
+	}
+
+	if err := w.writer(key, n); err != nil {
+		return 0, err
+	}
+
+	return w.Cache.Increment(key, delta)
+}
+
+// Decrement writes the decremented value, and returns the writer error if any.
+func (w *writeThrough) Decrement(key interface{}, delta int64) (int64, error) {
+	return w.Increment(key, -delta)
+}
+
+// Merge writes the merged entries, with their resolved value,
+// and stores the written ones keeping their absolute expiry.
+// It snapshots the other cache entries before merging them,
+// so the other cache may be the write through cache itself.
+func (w *writeThrough) Merge(other EntrySource, onConflict func(existing, incoming interface{}) interface{}) {
+	entries := internal.LiveEntries(other)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	internal.MergeEntries(throughMerge{w}, entries, onConflict)
+}
+
+// throughMerge is the Merge target of a write through cache,
+// storing the merged entries written successfully, while w.mu held.
+type throughMerge struct {
+	w *writeThrough
+}
+
+func (t throughMerge) Peek(key interface{}) (interface{}, bool) {
+	return t.w.Cache.Peek(key)
+}
+
+func (t throughMerge) StoreWithDeadline(key, value interface{}, deadline time.Time) {
+	if err := t.w.writer(key, value); err == nil {
+		t.w.Cache.StoreWithDeadline(key, value, deadline)
+	}
+}
+
+// StoreMany stores the items written successfully.
+func (w *writeThrough) StoreMany(items map[interface{}]interface{}) {
+	for k, v := range items {
+		w.Store(k, v)
+	}
+}
+
+func (w *writeThrough) Update(key, value interface{}) {
+	_ = w.UpdateE(key, value)
+}
+
+func (w *writeThrough) Delete(key interface{}) {
+	_ = w.DeleteE(key)
+}
+
+// DeleteMany deletes the keys deleted successfully.
+func (w *writeThrough) DeleteMany(keys ...interface{}) {
+	for _, k := range keys {
+		w.Delete(k)
+	}
+}
+
+// GetAndDelete deletes the key value and returns its value,
+// only if deleter succeeded.
+func (w *writeThrough) GetAndDelete(key interface{}) (interface{}, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.remove(key); err != nil {
+		return nil, false
+	}
+
+	return w.Cache.GetAndDelete(key)
+}
+
+// CompareAndDelete deletes the key only if its current value equal to old,
+// and deleter succeeded.
+func (w *writeThrough) CompareAndDelete(key, old interface{}) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if v, ok := w.Cache.Peek(key); !ok || !internal.Equal(v, old) {
+		return false
+	}
+
+	if err := w.remove(key); err != nil {
+		return false
+	}
+
+	return w.Cache.CompareAndDelete(key, old)
+}
+
+// DeleteWithPrefix deletes the keys starting with prefix deleted successfully,
+// and returns their number.
+func (w *writeThrough) DeleteWithPrefix(prefix string) int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.deleteAll(w.Cache.KeysWithPrefix(prefix))
+}
+
+// DeleteFunc deletes the live entries satisfying pred deleted successfully,
+// and returns their number.
+func (w *writeThrough) DeleteFunc(pred func(key, value interface{}) bool) int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	keys := []interface{}{}
+	for it := w.Cache.Iterator(); it.Next(); {
+		if pred(it.Key(), it.Value()) {
+			keys = append(keys, it.Key())
+		}
+	}
+
+	return w.deleteAll(keys)
+}
+
+// deleteAll deletes the keys deleted successfully, while holding w.mu,
+// and returns their number.
+func (w *writeThrough) deleteAll(keys []interface{}) (n int) {
+	for _, k := range keys {
+		if err := w.remove(k); err == nil {
+			w.Cache.Delete(k)
+			n++
+		}
+	}
+	return
+}
@@ -0,0 +1,100 @@
+package libcache
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// deltaer is implemented by the caches that record the duration
+// of the loader calls, used by GetOrCompute to recompute hot keys early.
+type deltaer interface {
+	SetDelta(key interface{}, d time.Duration) bool
+	Delta(key interface{}) (time.Duration, time.Time, bool)
+}
+
+// WithXFetch enables the probabilistic early recomputation (XFetch) of GetOrCompute,
+// to avoid a stampede of loader calls when a hot key expires.
+//
+// On each GetOrCompute hit, the key recomputed early if
+//
+//	now - delta * beta * ln(rand()) >= expiry
+//
+// where delta is the duration of the loader call that computed the key value,
+// and rand() is uniform in (0, 1]. The chance grows as the key approaches its expiry,
+// and beta greater than one favors earlier recomputation, 1 is a sensible default.
+//
+// A single caller starts the recomputation in the background and returns the current value,
+// and the other callers keep getting the current value until the new value stored.
+// Background loader errors and panics are ignored, the current value left to expire.
+//
+// src is the random source, nil means the global source.
+// WithXFetch has effect only on caches returned by the ReplacementPolicy New methods,
+// and zero or negative beta disables it.
+func WithXFetch(beta float64, src rand.Source) Option {
+	return func(c Cache) {
+		sc, ok := c.(*cache)
+		if !ok {
+			return
+		}
+
+		sc.mu.Lock()
+		defer sc.mu.Unlock()
+
+		sc.beta = beta
+		sc.rand = nil
+		if src != nil {
+			sc.rand = rand.New(src)
+		}
+	}
+}
+
+// refreshEarly starts the XFetch background recomputation of the key,
+// refreshEarly must be called while holding the cache lock.
+func (c *cache) refreshEarly(key interface{}, loader Loader) {
+	d, ok := c.unsafe.(deltaer)
+	if !ok {
+		return
+	}
+
+	delta, exp, ok := d.Delta(key)
+	if !ok || delta <= 0 || exp.IsZero() {
+		return
+	}
+
+	id := c.keyFunc.Key(key)
+	if _, ok := c.calls[id]; ok {
+		return
+	}
+
+	// ln of (0, 1] is non-positive, so the lead is non-negative,
+	// and kept as float since it may overflow a duration.
+	lead := -float64(delta) * c.beta * math.Log(1-c.float64())
+	now := c.now()
+	if float64(exp.Sub(now)) > lead {
+		return
+	}
+
+	cl := c.newCall(id)
+
+	go func() {
+		defer func() {
+			_ = recover()
+		}()
+		c.load(key, id, now, cl, loader)
+	}()
+}
+
+func (c *cache) float64() float64 {
+	if c.rand == nil {
+		return rand.Float64()
+	}
+	return c.rand.Float64()
+}
+
+func (c *cache) now() time.Time {
+	if c.clock == nil {
+		return time.Now()
+	}
+	return c.clock.Now()
+}